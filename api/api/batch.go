@@ -0,0 +1,280 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"orders-api/auth"
+	"orders-api/metrics"
+	"orders-api/outbox"
+	"orders-api/store"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Lotes (POST /orders:batch, PUT /orders/status:batch): todas as entradas
+// numa transação, cada uma no seu savepoint, e os eventos numa escrita só
+// no Kafka. Em "atomic" (padrão) qualquer falha desfaz o lote inteiro; em
+// "partial" só a entrada que falhou fica de fora.
+// ──────────────────────────────────────────────────────────────────────────────
+
+const (
+	batchAtomic  = "atomic"
+	batchPartial = "partial"
+)
+
+type batchCreateReq struct {
+	Mode   string      `json:"mode"`
+	Orders []createReq `json:"orders"`
+}
+
+type batchStatusReq struct {
+	Mode    string             `json:"mode"`
+	Updates []batchStatusEntry `json:"updates"`
+}
+
+type batchStatusEntry struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+type batchResponse struct {
+	Mode      string        `json:"mode"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []batchResult `json:"results"`
+}
+
+// batchResult é o desfecho de uma entrada, com o status HTTP que ela teria
+// sozinha (424 quando foi desfeita por causa de outra, no modo atomic).
+type batchResult struct {
+	Index  int         `json:"index"`
+	Status int         `json:"status"`
+	ID     string      `json:"id,omitempty"`
+	Error  *batchError `json:"error,omitempty"`
+}
+
+type batchError struct {
+	Title  string `json:"title"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (s *Server) handleBatchCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	var req batchCreateReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !s.checkBatch(w, r, req.Mode, len(req.Orders), "POST /orders:batch") {
+		return
+	}
+
+	ctx := r.Context()
+	lim := s.limits.For("POST /orders")
+	res, err := s.runBatch(r, req.Mode, len(req.Orders), http.StatusCreated,
+		func(tx *sql.Tx, i int) (string, []store.OutboxEvent, error) {
+			o, err := s.newOrder(ctx, req.Orders[i], lim)
+			if err != nil {
+				return "", nil, err
+			}
+			evs, err := s.insertOrder(ctx, tx, &o)
+			return o.ID, evs, err
+		})
+	if err != nil {
+		writeError(w, r, err, "order batch failed", "entries", len(req.Orders))
+		return
+	}
+	metrics.OrdersCreated.Add(float64(res.applied))
+	writeJSON(w, res.code(http.StatusCreated), res.batchResponse)
+}
+
+func (s *Server) handleBatchStatus(w http.ResponseWriter, r *http.Request) {
+	var req batchStatusReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !s.checkBatch(w, r, req.Mode, len(req.Updates), "PUT /orders/status:batch") {
+		return
+	}
+
+	ctx := r.Context()
+	now := time.Now().UTC()
+	res, err := s.runBatch(r, req.Mode, len(req.Updates), http.StatusOK,
+		func(tx *sql.Tx, i int) (string, []store.OutboxEvent, error) {
+			u := req.Updates[i]
+			if u.Status == "" {
+				return u.ID, nil, &invalidError{"Invalid status", "status is required"}
+			}
+			_, ev, err := s.updateStatusTx(ctx, tx, u.ID, u.Status, now)
+			return u.ID, []store.OutboxEvent{ev}, err
+		})
+	if err != nil {
+		writeError(w, r, err, "status batch failed", "entries", len(req.Updates))
+		return
+	}
+	for _, rr := range res.Results {
+		if rr.Status == http.StatusOK || rr.Status == http.StatusServiceUnavailable {
+			metrics.StatusTransition(req.Updates[rr.Index].Status)
+		}
+	}
+	writeJSON(w, res.code(http.StatusOK), res.batchResponse)
+}
+
+// checkBatch valida modo e quantidade de entradas antes de abrir a transação.
+func (s *Server) checkBatch(w http.ResponseWriter, r *http.Request, mode string, n int, route string) bool {
+	switch mode {
+	case "", batchAtomic, batchPartial:
+	default:
+		writeProblem(w, r, http.StatusBadRequest, "Invalid batch mode",
+			fmt.Sprintf("mode: %q is not atomic or partial", mode))
+		return false
+	}
+	if n == 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Empty batch", "the batch has no entries")
+		return false
+	}
+	if max := s.limits.For(route).MaxBatchEntries; max > 0 && n > max {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, "Batch too large",
+			fmt.Sprintf("%d entries (max %d)", n, max))
+		return false
+	}
+	return true
+}
+
+// batchOutcome é a resposta do lote mais o que o handler precisa para as
+// métricas e o status HTTP.
+type batchOutcome struct {
+	batchResponse
+	applied    int  // entradas gravadas (mesmo com o evento pendente no outbox)
+	rolledBack bool // atomic com falha: nada foi gravado
+}
+
+// code: okCode se tudo deu certo, 422 se o lote atomic foi desfeito e 207
+// (Multi-Status) se só parte dele valeu.
+func (o batchOutcome) code(okCode int) int {
+	switch {
+	case o.rolledBack:
+		return http.StatusUnprocessableEntity
+	case o.Failed > 0:
+		return http.StatusMultiStatus
+	default:
+		return okCode
+	}
+}
+
+// runBatch aplica fn a cada entrada dentro de uma transação, com um
+// savepoint por entrada. Erros de domínio (422/403/404) viram o resultado da
+// entrada; qualquer outro desfaz o lote e é devolvido. Depois do commit, os
+// eventos das entradas gravadas saem num único PublishBatch.
+func (s *Server) runBatch(r *http.Request, mode string, n, okCode int,
+	fn func(tx *sql.Tx, i int) (string, []store.OutboxEvent, error),
+) (batchOutcome, error) {
+	ctx := r.Context()
+	if mode == "" {
+		mode = batchAtomic
+	}
+	out := batchOutcome{batchResponse: batchResponse{Mode: mode, Results: make([]batchResult, n)}}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return out, err
+	}
+	var (
+		evs     []store.OutboxEvent
+		idxs    []int // entrada de cada evento em evs
+		applied []int // entradas gravadas, em ordem
+	)
+	for i := 0; i < n; i++ {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_entry`); err != nil {
+			_ = tx.Rollback()
+			return out, err
+		}
+		id, entryEvs, err := fn(tx, i)
+		if err == nil {
+			out.Results[i] = batchResult{Index: i, Status: okCode, ID: id}
+			applied = append(applied, i)
+			for _, ev := range entryEvs {
+				evs, idxs = append(evs, ev), append(idxs, i)
+			}
+			continue
+		}
+		code, be, ok := batchFailure(r, err)
+		if !ok {
+			_ = tx.Rollback()
+			return out, fmt.Errorf("batch entry %d: %w", i, err)
+		}
+		if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT batch_entry`); err != nil {
+			_ = tx.Rollback()
+			return out, err
+		}
+		out.Results[i] = batchResult{Index: i, Status: code, ID: id, Error: be}
+		out.Failed++
+	}
+
+	if mode == batchAtomic && out.Failed > 0 {
+		if err := tx.Rollback(); err != nil {
+			return out, err
+		}
+		for _, i := range applied {
+			if okCode == http.StatusCreated {
+				out.Results[i].ID = "" // o pedido não chegou a existir
+			}
+			out.Results[i].Status = http.StatusFailedDependency
+			out.Results[i].Error = &batchError{"Not applied", "the atomic batch was rolled back"}
+		}
+		out.Failed = n
+		out.rolledBack = true
+		slog.InfoContext(ctx, "order batch rolled back", "mode", mode, "entries", n)
+		return out, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return out, err
+	}
+	out.applied = len(applied)
+
+	// gravado vale mesmo sem o Kafka: o evento fica no outbox para o relay
+	unpublished := map[int]bool{}
+	for k, perr := range s.relay.PublishBatch(ctx, evs) {
+		if perr == nil || errors.Is(perr, outbox.ErrQueued) {
+			continue
+		}
+		i := idxs[k]
+		slog.WarnContext(ctx, "publish failed; event kept in outbox",
+			"event", evs[k].Type, "order_id", out.Results[i].ID, "outbox_id", evs[k].ID, "err", perr)
+		out.Results[i].Status = http.StatusServiceUnavailable
+		out.Results[i].Error = &batchError{"Kafka unavailable", "saved; the event stays in the outbox"}
+		unpublished[i] = true
+	}
+	out.Failed += len(unpublished)
+	out.Succeeded = out.applied - len(unpublished)
+	slog.InfoContext(ctx, "order batch applied", "mode", mode, "entries", n, "applied", out.applied, "failed", out.Failed)
+	return out, nil
+}
+
+// batchFailure traduz um erro de domínio de uma entrada como writeError faria
+// para o request sozinho; ok=false para erros que abortam o lote.
+func batchFailure(r *http.Request, err error) (int, *batchError, bool) {
+	ctx := r.Context()
+	var (
+		inv *invalidError
+		den *deniedError
+	)
+	switch {
+	case errors.As(err, &inv):
+		return http.StatusUnprocessableEntity, &batchError{inv.title, inv.detail}, true
+	case errors.As(err, &den):
+		p, _ := auth.FromContext(ctx)
+		auditDenied(ctx, p, r.Method, r.URL.Path, den.reason, den.attrs...)
+		return http.StatusForbidden, &batchError{"Forbidden", den.reason}, true
+	case store.IsNotFound(err):
+		return http.StatusNotFound, &batchError{"Not Found", "order not found"}, true
+	default:
+		return 0, nil, false
+	}
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"orders-api/metrics"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Validação contra o OpenAPI (http.validation): "warn" loga requests e
+// respostas fora do contrato; "strict" (dev/testes) recusa o request com 400
+// e troca a resposta por 500, para o drift entre handlers e documento
+// aparecer na hora.
+// ──────────────────────────────────────────────────────────────────────────────
+
+const (
+	ValidationOff    = "off"
+	ValidationWarn   = "warn"
+	ValidationStrict = "strict"
+)
+
+// maxValidatedBody: respostas maiores passam sem validação do corpo.
+const maxValidatedBody = 4 << 20
+
+type contract struct {
+	mode    string
+	doc     []byte // servido em /openapi.json
+	ops     map[string]*operation
+	schemas map[string]*jsonschema.Schema
+}
+
+func newContract(mode, tenantHeader string) *contract {
+	c := &contract{
+		mode:    mode,
+		doc:     OpenAPIDocument(tenantHeader),
+		ops:     map[string]*operation{},
+		schemas: map[string]*jsonschema.Schema{},
+	}
+	for i := range apiOperations {
+		op := &apiOperations[i]
+		c.ops[op.method+" "+op.path] = op
+	}
+	if mode == ValidationOff {
+		return c
+	}
+
+	// o próprio documento é o recurso: os $ref entre schemas resolvem nele
+	raw, err := jsonschema.UnmarshalJSON(bytes.NewReader(c.doc))
+	if err != nil {
+		panic(fmt.Sprintf("openapi: %v", err))
+	}
+	comp := jsonschema.NewCompiler()
+	comp.DefaultDraft(jsonschema.Draft2020)
+	if err := comp.AddResource("openapi.json", raw); err != nil {
+		panic(fmt.Sprintf("openapi: %v", err))
+	}
+	for name := range apiSchemas {
+		sch, err := comp.Compile("openapi.json#/components/schemas/" + name)
+		if err != nil {
+			panic(fmt.Sprintf("openapi: schema %s: %v", name, err))
+		}
+		c.schemas[name] = sch
+	}
+	return c
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(s.contract.doc)
+}
+
+// operation acha a operação do request pelo template da rota.
+func (c *contract) operation(r *http.Request) (*operation, string) {
+	key := r.Method + " " + routeLabel(r.URL.Path)
+	return c.ops[key], key
+}
+
+// violation loga (e conta) um desvio do contrato.
+func (c *contract) violation(r *http.Request, kind, route, detail string) {
+	metrics.ContractViolations.WithLabelValues(kind, route).Inc()
+	slog.WarnContext(r.Context(), "openapi contract violation",
+		"kind", kind, "route", route, "mode", c.mode, "detail", detail)
+}
+
+// validate confere um corpo JSON contra um schema de components; devolve a
+// lista de erros numa linha.
+func (c *contract) validate(name string, body []byte) string {
+	sch, ok := c.schemas[name]
+	if !ok {
+		return ""
+	}
+	v, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return "invalid JSON: " + err.Error()
+	}
+	if err := sch.Validate(v); err != nil {
+		return oneLine(err)
+	}
+	return ""
+}
+
+// oneLine junta os erros do jsonschema numa linha, sem o cabeçalho com a
+// URL do schema.
+func oneLine(err error) string {
+	lines := strings.Split(strings.TrimSpace(err.Error()), "\n")
+	if len(lines) > 1 && strings.HasPrefix(lines[0], "jsonschema validation failed") {
+		lines = lines[1:]
+	}
+	for i := range lines {
+		lines[i] = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), "- "))
+	}
+	return strings.Join(lines, "; ")
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Requests: roda depois de auth/tenant/limites, para um cliente sem
+// credencial continuar recebendo 401 e um corpo grande 413.
+// ──────────────────────────────────────────────────────────────────────────────
+
+func (c *contract) checkRequests(next http.Handler) http.Handler {
+	if c.mode == ValidationOff {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op, route := c.operation(r)
+		if op == nil || op.body == "" || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			// devolve o que foi lido e o erro (ex.: MaxBytesError → 413 no handler)
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{err}))
+			next.ServeHTTP(w, r)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var detail string
+		if media, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); media != "" && media != "application/json" {
+			detail = "unsupported content type " + media
+		} else if json.Valid(body) { // JSON inválido fica com o 400 do handler
+			detail = c.validate(op.body, body)
+		}
+		if detail == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		c.violation(r, "request", route, detail)
+		if c.mode == ValidationStrict {
+			writeProblem(w, r, http.StatusBadRequest, "Request does not match the API contract", detail)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Respostas: roda por fora de auth e limites, para 401/403/429 também serem
+// conferidos. Streams (SSE, WebSocket) passam direto.
+// ──────────────────────────────────────────────────────────────────────────────
+
+func (c *contract) checkResponses(next http.Handler) http.Handler {
+	if c.mode == ValidationOff {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op, route := c.operation(r)
+		if op != nil && op.stream {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rec := &contractRecorder{ResponseWriter: w, status: http.StatusOK, buffer: c.mode == ValidationStrict}
+		next.ServeHTTP(rec, r)
+
+		detail := c.checkResponse(op, rec)
+		if detail != "" {
+			c.violation(r, "response", route, detail)
+		}
+		if !rec.buffer {
+			return // warn: a resposta já foi escrita
+		}
+		if detail != "" {
+			w.Header().Del("Content-Length") // headers de fora (X-Request-Id...) ficam
+			writeProblem(w, r, http.StatusInternalServerError, "Response does not match the API contract",
+				fmt.Sprintf("%d %s: %s", rec.status, route, detail))
+			return
+		}
+		w.WriteHeader(rec.status)
+		_, _ = w.Write(rec.body.Bytes())
+	})
+}
+
+// checkResponse devolve "" se status, content type e corpo estão no contrato.
+// Operações fora do documento só podem responder erro (404/405 do mux).
+func (c *contract) checkResponse(op *operation, rec *contractRecorder) string {
+	if op == nil {
+		if rec.status < 400 {
+			return "operation is not documented"
+		}
+		return ""
+	}
+	resp, ok := op.responses[rec.status]
+	if !ok {
+		return fmt.Sprintf("status %d is not documented", rec.status)
+	}
+	if rec.body.Len() == 0 && len(resp.content) == 0 {
+		return ""
+	}
+	media, _, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	name, ok := resp.content[media]
+	if !ok {
+		return fmt.Sprintf("content type %q is not documented for status %d", media, rec.status)
+	}
+	if name == "" || rec.overflow {
+		return ""
+	}
+	return c.validate(name, rec.body.Bytes())
+}
+
+// contractRecorder guarda status e corpo da resposta. Em strict segura tudo
+// até a validação; em warn só copia o corpo (até maxValidatedBody).
+type contractRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	buffer      bool
+	overflow    bool
+	body        bytes.Buffer
+}
+
+func (cr *contractRecorder) WriteHeader(code int) {
+	if cr.wroteHeader {
+		return
+	}
+	cr.status, cr.wroteHeader = code, true
+	if !cr.buffer {
+		cr.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (cr *contractRecorder) Write(b []byte) (int, error) {
+	if !cr.wroteHeader {
+		cr.WriteHeader(http.StatusOK)
+	}
+	if cr.buffer {
+		return cr.body.Write(b)
+	}
+	if !cr.overflow {
+		if cr.body.Len()+len(b) > maxValidatedBody {
+			cr.overflow = true
+		} else {
+			cr.body.Write(b)
+		}
+	}
+	return cr.ResponseWriter.Write(b)
+}
+
+func (cr *contractRecorder) Unwrap() http.ResponseWriter { return cr.ResponseWriter }
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"orders-api/auth"
+	"orders-api/store"
+	"orders-api/tenant"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Clientes (/customers): o cadastro a que os pedidos apontam por
+// customerId. O nome continua copiado no pedido, e POST /orders só com o
+// nome (clientes antigos) cria o cliente na primeira vez.
+// ──────────────────────────────────────────────────────────────────────────────
+
+// customerReq é o corpo de POST /customers e PUT /customers/{id}; o PUT
+// substitui os dois campos (email ausente apaga o email).
+type customerReq struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func (s *Server) handleGetCustomer(w http.ResponseWriter, r *http.Request, id string) {
+	c, err := s.getCustomer(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err, "get customer failed", "customer_id", id)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (s *Server) handleUpdateCustomer(w http.ResponseWriter, r *http.Request, id string) {
+	var req customerReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	c, err := s.updateCustomer(r.Context(), id, req)
+	if err != nil {
+		writeError(w, r, err, "update customer failed", "customer_id", id)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+func (s *Server) handleDeleteCustomer(w http.ResponseWriter, r *http.Request, id string) {
+	if err := s.deleteCustomer(r.Context(), id); err != nil {
+		writeError(w, r, err, "delete customer failed", "customer_id", id)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleCreateCustomer(w http.ResponseWriter, r *http.Request) {
+	var req customerReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	c, err := s.createCustomer(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err, "insert customer failed")
+		return
+	}
+	w.Header().Set("Location", "/customers/"+c.ID)
+	writeJSON(w, http.StatusCreated, c)
+}
+
+func (s *Server) handleListCustomers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, offset := 50, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid query parameter", fmt.Sprintf("limit: %q is not an integer", v))
+			return
+		}
+		if n > 0 && n <= 500 {
+			limit = n
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid query parameter", fmt.Sprintf("offset: %q is not a non-negative integer", v))
+			return
+		}
+		offset = n
+	}
+
+	ctx := r.Context()
+	// principal amarrado a um cliente só enxerga o próprio cadastro
+	var f store.CustomerFilter
+	if p, ok := auth.FromContext(ctx); ok && p.Bound() {
+		if p.CustomerID != "" {
+			f.ID = p.CustomerID
+		} else {
+			f.Name = p.Customer
+		}
+	}
+	list, err := store.ListCustomers(ctx, s.db, tenant.FromContext(ctx), f, limit, offset)
+	if err != nil {
+		writeError(w, r, err, "list customers failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items":  list,
+		"limit":  limit,
+		"offset": offset,
+		"count":  len(list),
+	})
+}
+
+func (s *Server) handleCustomerOrders(w http.ResponseWriter, r *http.Request, id string) {
+	f, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid query parameter", err.Error())
+		return
+	}
+	ctx := r.Context()
+	if _, err := s.getCustomer(ctx, id); err != nil {
+		writeError(w, r, err, "get customer failed", "customer_id", id)
+		return
+	}
+	f.CustomerID = id
+	list, err := s.listOrders(ctx, f)
+	if err != nil {
+		writeError(w, r, err, "list customer orders failed", "customer_id", id)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"items":  list,
+		"limit":  f.Limit,
+		"offset": f.Offset,
+		"count":  len(list),
+	})
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Operações
+// ──────────────────────────────────────────────────────────────────────────────
+
+func (s *Server) createCustomer(ctx context.Context, req customerReq) (store.Customer, error) {
+	req, err := checkCustomer(req)
+	if err != nil {
+		return store.Customer{}, err
+	}
+	if p, ok := auth.FromContext(ctx); ok && !p.CanAccess(req.Name, "") {
+		return store.Customer{}, denied("cannot create another customer", "customer", req.Name)
+	}
+	now := time.Now().UTC()
+	sub := auth.Subject(ctx)
+	c := store.Customer{
+		ID: newID(), TenantID: tenant.FromContext(ctx), Name: req.Name, Email: req.Email,
+		CreatedAt: now, UpdatedAt: now, CreatedBy: sub, UpdatedBy: sub,
+	}
+	evs, err := s.inTx(ctx, func(tx *sql.Tx) ([]store.OutboxEvent, error) {
+		if err := store.InsertCustomer(ctx, tx, c); err != nil {
+			return nil, customerConflict(err, c.Name)
+		}
+		ev, err := s.enqueueCustomerEvent(ctx, tx, c, "CustomerCreated")
+		return []store.OutboxEvent{ev}, err
+	})
+	if err != nil {
+		return store.Customer{}, err
+	}
+	slog.InfoContext(ctx, "customer created", "customer_id", c.ID, "subject", sub)
+	return c, s.publishAll(ctx, evs, c.ID)
+}
+
+func (s *Server) getCustomer(ctx context.Context, id string) (store.Customer, error) {
+	c, err := store.GetCustomer(ctx, s.db, tenant.FromContext(ctx), id)
+	if err != nil {
+		return store.Customer{}, err
+	}
+	if p, ok := auth.FromContext(ctx); ok && !p.CanAccess(c.Name, c.ID) {
+		return store.Customer{}, denied("customer belongs to another principal", "customer_id", id)
+	}
+	return c, nil
+}
+
+// updateCustomer troca nome e email; um nome novo vai também para os
+// pedidos do cliente, na mesma transação do CustomerUpdated.
+func (s *Server) updateCustomer(ctx context.Context, id string, req customerReq) (store.Customer, error) {
+	req, err := checkCustomer(req)
+	if err != nil {
+		return store.Customer{}, err
+	}
+	var c store.Customer
+	evs, err := s.inTx(ctx, func(tx *sql.Tx) ([]store.OutboxEvent, error) {
+		cur, err := store.LockCustomer(ctx, tx, tenant.FromContext(ctx), id, true)
+		if err != nil {
+			return nil, err
+		}
+		p, ok := auth.FromContext(ctx)
+		if ok && !p.CanAccess(cur.Name, cur.ID) {
+			return nil, denied("customer belongs to another principal", "customer_id", id)
+		}
+		// preso pelo nome, renomear trancaria a credencial fora do cliente;
+		// preso pelo id, o nome novo passa a valer no próximo request
+		if ok && p.CustomerID == "" && !p.CanAccess(req.Name, cur.ID) {
+			return nil, denied("cannot rename the customer bound to the credential", "customer_id", id)
+		}
+		c = cur
+		c.Name, c.Email = req.Name, req.Email
+		c.UpdatedAt, c.UpdatedBy = time.Now().UTC(), auth.Subject(ctx)
+		if err := store.UpdateCustomer(ctx, tx, c); err != nil {
+			return nil, customerConflict(err, c.Name)
+		}
+		ev, err := s.enqueueCustomerEvent(ctx, tx, c, "CustomerUpdated")
+		return []store.OutboxEvent{ev}, err
+	})
+	if err != nil {
+		return store.Customer{}, err
+	}
+	return c, s.publishAll(ctx, evs, id)
+}
+
+// checkBoundCustomer confere que o cliente de um principal preso a
+// customer_id existe no tenant do contexto; apagado, ou de outro tenant,
+// nega tudo em vez de só devolver listas vazias.
+func (s *Server) checkBoundCustomer(ctx context.Context) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.CustomerID == "" || !p.Bound() {
+		return nil
+	}
+	_, err := store.GetCustomer(ctx, s.db, tenant.FromContext(ctx), p.CustomerID)
+	if errors.Is(err, store.ErrNotFound) {
+		return denied("customer bound to the credential does not exist", "customer_id", p.CustomerID)
+	}
+	return err
+}
+
+// deleteCustomer só apaga clientes sem pedidos (a FK recusa os outros).
+func (s *Server) deleteCustomer(ctx context.Context, id string) error {
+	if _, err := s.getCustomer(ctx, id); err != nil {
+		return err
+	}
+	err := store.DeleteCustomer(ctx, s.db, tenant.FromContext(ctx), id)
+	if errors.Is(err, store.ErrConflict) {
+		return &conflictError{"Customer has orders", "delete or move the customer's orders first"}
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "customer deleted", "audit", true, "customer_id", id, "subject", auth.Subject(ctx))
+	return nil
+}
+
+// enqueueCustomerEvent grava CustomerCreated/CustomerUpdated no outbox,
+// no mesmo tópico dos eventos de pedido e com o id do cliente como chave.
+func (s *Server) enqueueCustomerEvent(ctx context.Context, tx *sql.Tx, c store.Customer, eventType string) (store.OutboxEvent, error) {
+	evt := map[string]any{
+		"type":     eventType,
+		"id":       c.ID,
+		"tenantId": c.TenantID,
+		"name":     c.Name,
+		"email":    c.Email,
+		"ts":       c.UpdatedAt.Format(time.RFC3339Nano),
+	}
+	return store.EnqueueEvent(ctx, tx, s.outboxEvent(ctx, c.ID, eventType), evt)
+}
+
+// checkCustomer valida e normaliza o corpo de criação/atualização.
+func checkCustomer(req customerReq) (customerReq, error) {
+	name, err := customerName(req.Name)
+	if err != nil {
+		return req, &invalidError{"Invalid customer", err.Error()}
+	}
+	req.Name, req.Email = name, strings.TrimSpace(req.Email)
+	switch {
+	case len(req.Email) > 255:
+		return req, &invalidError{"Invalid customer", "email is longer than 255 bytes"}
+	}
+	if req.Email != "" {
+		if a, err := mail.ParseAddress(req.Email); err != nil || a.Address != req.Email {
+			return req, &invalidError{"Invalid customer", fmt.Sprintf("email: %q is not an email address", req.Email)}
+		}
+	}
+	return req, nil
+}
+
+// customerName normaliza o nome como ele é gravado em customers.name (e
+// copiado para os pedidos): sem espaços nas pontas e com até 255 bytes.
+func customerName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", errors.New("name is required")
+	case len(name) > 255:
+		return "", errors.New("name is longer than 255 bytes")
+	}
+	return name, nil
+}
+
+func customerConflict(err error, name string) error {
+	if errors.Is(err, store.ErrConflict) {
+		return &conflictError{"Customer already exists", fmt.Sprintf("a customer named %q already exists", name)}
+	}
+	return err
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"orders-api/store"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Exportação (GET /orders/export): os pedidos filtrados como a listagem, sem
+// paginação, lidos de um cursor numa transação com snapshot consistente e
+// escritos conforme chegam, em NDJSON ou CSV (Accept), com gzip opcional.
+// ──────────────────────────────────────────────────────────────────────────────
+
+const (
+	exportNDJSON = "application/x-ndjson"
+	exportCSV    = "text/csv"
+
+	exportFlushRows = 500 // linhas entre flushes, para o cliente ver progresso
+)
+
+// HeaderSnapshot é o instante do snapshot lido: nada gravado depois dele
+// entra na exportação.
+const HeaderSnapshot = "X-Snapshot-At"
+
+var exportCSVHeader = []string{"id", "tenantId", "customer", "customerId", "status", "items", "createdAt", "updatedAt", "createdBy", "updatedBy"}
+
+func (s *Server) handleOrderExport(w http.ResponseWriter, r *http.Request) {
+	q, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid query parameter", err.Error())
+		return
+	}
+	format, ok := exportFormat(r.Header.Get("Accept"))
+	if !ok {
+		writeProblem(w, r, http.StatusNotAcceptable, "Not Acceptable",
+			"export is available as "+exportNDJSON+" or "+exportCSV)
+		return
+	}
+
+	ctx := r.Context()
+	snap, err := s.openSnapshot(ctx)
+	if err != nil {
+		writeError(w, r, err, "export: open snapshot failed")
+		return
+	}
+	defer snap.close(ctx)
+
+	where, args := q.where(ctx)
+	// pedidos e itens num cursor só: a conexão do snapshot não aceita outra
+	// consulta enquanto ele está aberto
+	rows, err := snap.conn.QueryContext(ctx, store.OrdersWithItemsQuery(where, q.orderBy()), args...)
+	if err != nil {
+		writeError(w, r, err, "export: query failed")
+		return
+	}
+	defer rows.Close()
+
+	ext := "ndjson"
+	if format == exportCSV {
+		ext = "csv"
+	}
+	h := w.Header()
+	h.Set("Content-Type", format+"; charset=utf-8")
+	h.Set("Content-Disposition", `attachment; filename="orders-`+snap.at.Format("20060102T150405Z")+`.`+ext+`"`)
+	h.Set(HeaderSnapshot, snap.at.Format(time.RFC3339Nano))
+	h.Set("Cache-Control", "no-store")
+	h.Add("Vary", "Accept, Accept-Encoding")
+
+	rc := http.NewResponseController(w)
+	var out io.Writer = w
+	flush := rc.Flush
+	if acceptsGzip(r.Header.Get("Accept-Encoding")) {
+		h.Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+		flush = func() error {
+			if err := gz.Flush(); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+
+	enc := newExportEncoder(format, out)
+	n := 0
+	start := time.Now()
+	err = store.EachOrderWithItems(rows, func(o store.Order) error {
+		if err := enc.write(o); err != nil {
+			return err
+		}
+		if n++; n%exportFlushRows == 0 {
+			if err := enc.flush(); err != nil {
+				return err
+			}
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = enc.flush()
+	}
+	if err != nil {
+		// o status já foi: cortar a conexão é o único jeito de o cliente não
+		// tomar um arquivo truncado por completo
+		slog.ErrorContext(ctx, "export aborted", "rows", n, "format", format, "err", err)
+		panic(http.ErrAbortHandler)
+	}
+	slog.InfoContext(ctx, "orders exported", "rows", n, "format", format,
+		"snapshot_at", snap.at, "duration_ms", time.Since(start).Milliseconds())
+}
+
+// snapshot é uma conexão dedicada com uma transação só de leitura aberta
+// WITH CONSISTENT SNAPSHOT, para o cursor não ver gravações concorrentes.
+type snapshot struct {
+	conn *sql.Conn
+	at   time.Time
+}
+
+func (s *Server) openSnapshot(ctx context.Context) (*snapshot, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{conn: conn}
+	for _, stmt := range []string{
+		"SET TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY",
+	} {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			snap.close(ctx)
+			return nil, err
+		}
+	}
+	if err := conn.QueryRowContext(ctx, "SELECT UTC_TIMESTAMP(6)").Scan(&snap.at); err != nil {
+		snap.close(ctx)
+		return nil, err
+	}
+	snap.at = snap.at.UTC()
+	return snap, nil
+}
+
+// close encerra a transação antes de devolver a conexão ao pool.
+func (snap *snapshot) close(ctx context.Context) {
+	if _, err := snap.conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK"); err != nil {
+		slog.WarnContext(ctx, "export: end snapshot failed", "err", err)
+	}
+	_ = snap.conn.Close()
+}
+
+// exportFormat escolhe o formato pelo Accept (q > 0, na ordem do header);
+// sem Accept ou com */* o padrão é NDJSON.
+func exportFormat(accept string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return exportNDJSON, true
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(q, 64); err != nil || f <= 0 {
+				continue
+			}
+		}
+		switch mt {
+		case exportNDJSON, "application/ndjson", "application/jsonl", "application/*", "*/*":
+			return exportNDJSON, true
+		case exportCSV, "text/*":
+			return exportCSV, true
+		}
+	}
+	return "", false
+}
+
+// acceptsGzip: gzip listado em Accept-Encoding sem q=0.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, q, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			continue
+		}
+		q = strings.TrimSpace(q)
+		if v, ok := strings.CutPrefix(q, "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			return err == nil && f > 0
+		}
+		return true
+	}
+	return false
+}
+
+// exportEncoder escreve um pedido por linha no formato escolhido.
+type exportEncoder struct {
+	json *json.Encoder
+	csv  *csv.Writer
+}
+
+func newExportEncoder(format string, w io.Writer) *exportEncoder {
+	if format == exportCSV {
+		cw := csv.NewWriter(w)
+		_ = cw.Write(exportCSVHeader) // erro de escrita aparece no flush
+		return &exportEncoder{csv: cw}
+	}
+	return &exportEncoder{json: json.NewEncoder(w)}
+}
+
+func (e *exportEncoder) write(o store.Order) error {
+	if e.json != nil {
+		return e.json.Encode(o) // Encode termina cada objeto com \n
+	}
+	items, err := json.Marshal(o.Items)
+	if err != nil {
+		return err
+	}
+	return e.csv.Write([]string{
+		o.ID, o.TenantID, csvCell(o.Customer), o.CustomerID, csvCell(o.Status), csvCell(string(items)),
+		o.CreatedAt.UTC().Format(time.RFC3339Nano), o.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		csvCell(o.CreatedBy), csvCell(o.UpdatedBy),
+	})
+}
+
+func (e *exportEncoder) flush() error {
+	if e.csv == nil {
+		return nil
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// csvCell neutraliza fórmulas de planilha (=, +, -, @ no início) com um
+// apóstrofo, já que o destino da exportação costuma ser o Excel.
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
@@ -0,0 +1,364 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"orders-api/auth"
+	"orders-api/bus"
+	"orders-api/store"
+
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// GraphQL: POST /graphql (queries e mutations) e WebSocket em /graphql
+// (subscriptions, graphql_ws.go). Os resolvers usam as mesmas operações das
+// rotas REST e do gRPC (orders.go); erros saem com extensions.code.
+// ──────────────────────────────────────────────────────────────────────────────
+
+//go:embed schema.graphql
+var graphqlSchema string
+
+const (
+	graphqlMaxDepth = 8
+	graphqlMaxFirst = 100 // teto de orders(first:); o padrão (20) está no schema
+)
+
+func (s *Server) newGraphQLSchema() *graphql.Schema {
+	return graphql.MustParseSchema(graphqlSchema, &gqlResolver{s: s}, graphql.MaxDepth(graphqlMaxDepth))
+}
+
+// graphqlReq é o corpo do POST e o payload do "subscribe" no WebSocket.
+type graphqlReq struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// /graphql → POST (query/mutation) / GET com Upgrade (subscriptions). As
+// mutations conferem orders:write no resolver.
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		s.serveGraphQLWS(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST (or a WebSocket upgrade for subscriptions)", http.StatusMethodNotAllowed)
+		return
+	}
+	var req graphqlReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Query == "" {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid GraphQL request", "query is required")
+		return
+	}
+	// erros de GraphQL (validação, resolvers) voltam com 200, em "errors"
+	writeJSON(w, http.StatusOK, s.gql.Exec(r.Context(), req.Query, req.OperationName, req.Variables))
+}
+
+// gqlError é um erro de resolver com código em extensions.code.
+type gqlError struct {
+	msg, code string
+}
+
+func (e *gqlError) Error() string { return e.msg }
+
+func (e *gqlError) Extensions() map[string]any { return map[string]any{"code": e.code} }
+
+// gqlFail traduz os erros das operações de pedido, como writeError e
+// grpcError; o que não for erro de domínio é logado com msg.
+func gqlFail(ctx context.Context, field string, err error, msg string, attrs ...any) error {
+	var (
+		inv *invalidError
+		den *deniedError
+	)
+	switch {
+	case errors.As(err, &inv):
+		return &gqlError{inv.detail, "BAD_USER_INPUT"}
+	case errors.As(err, &den):
+		p, _ := auth.FromContext(ctx)
+		auditDenied(ctx, p, "GraphQL", field, den.reason, den.attrs...)
+		return &gqlError{den.reason, "FORBIDDEN"}
+	case store.IsNotFound(err):
+		return &gqlError{"order not found", "NOT_FOUND"}
+	case errors.Is(err, errPublish), errors.Is(err, errWatchClosed), errors.Is(err, errWatchTooSlow):
+		return &gqlError{err.Error(), "UNAVAILABLE"}
+	default:
+		slog.ErrorContext(ctx, msg, append(attrs, "err", err)...)
+		return &gqlError{err.Error(), "INTERNAL"}
+	}
+}
+
+// gqlRequire confere um scope além do da rota (mutations exigem escrita).
+func gqlRequire(ctx context.Context, field, scope string) error {
+	if p, ok := auth.FromContext(ctx); ok && !p.Has(scope) {
+		return gqlFail(ctx, field, denied("missing scope "+scope, "scope", scope), "")
+	}
+	return nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Resolvers
+// ──────────────────────────────────────────────────────────────────────────────
+
+type gqlResolver struct{ s *Server }
+
+func (r *gqlResolver) Order(ctx context.Context, args struct{ ID graphql.ID }) (*orderResolver, error) {
+	o, err := r.s.getOrder(ctx, string(args.ID))
+	if store.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, gqlFail(ctx, "order", err, "get order failed", "order_id", args.ID)
+	}
+	return &orderResolver{o}, nil
+}
+
+type orderFilter struct {
+	Status, Customer *string
+	Since, Until     *graphql.Time
+}
+
+// Orders pagina por cursor (created_at, id) na ordem da listagem REST; pede
+// um pedido a mais para saber se há próxima página.
+func (r *gqlResolver) Orders(ctx context.Context, args struct {
+	Filter *orderFilter
+	First  int32
+	After  *string
+}) (*orderConnection, error) {
+	if args.First < 1 || args.First > graphqlMaxFirst {
+		return nil, &gqlError{"first must be between 1 and " + strconv.Itoa(graphqlMaxFirst), "BAD_USER_INPUT"}
+	}
+	q := orderQuery{Limit: int(args.First) + 1}
+	if f := args.Filter; f != nil {
+		if f.Status != nil && *f.Status != "" {
+			q.Status = []string{*f.Status}
+		}
+		if f.Customer != nil {
+			q.Customer = *f.Customer
+		}
+		if f.Since != nil {
+			q.Since = f.Since.Time
+		}
+		if f.Until != nil {
+			q.Until = f.Until.Time
+		}
+	}
+	if args.After != nil && *args.After != "" {
+		c, err := decodeCursor(*args.After)
+		if err != nil {
+			return nil, &gqlError{"invalid cursor", "BAD_USER_INPUT"}
+		}
+		q.After = &c
+	}
+	list, err := r.s.listOrders(ctx, q)
+	if err != nil {
+		return nil, gqlFail(ctx, "orders", err, "list orders failed")
+	}
+	more := len(list) > int(args.First)
+	if more {
+		list = list[:args.First]
+	}
+	return &orderConnection{orders: list, more: more}, nil
+}
+
+type createOrderInput struct {
+	Customer   *string
+	CustomerID *graphql.ID
+	Items      []string
+}
+
+func (r *gqlResolver) CreateOrder(ctx context.Context, args struct{ Input createOrderInput }) (*orderResolver, error) {
+	if err := gqlRequire(ctx, "createOrder", auth.ScopeWrite); err != nil {
+		return nil, err
+	}
+	req := createReq{Items: args.Input.Items}
+	if in := args.Input; in.Customer != nil {
+		req.Customer = *in.Customer
+	}
+	if in := args.Input; in.CustomerID != nil {
+		req.CustomerID = string(*in.CustomerID)
+	}
+	o, err := r.s.createOrder(ctx, req)
+	if err != nil {
+		return nil, gqlFail(ctx, "createOrder", err, "insert order failed")
+	}
+	return &orderResolver{o}, nil
+}
+
+func (r *gqlResolver) UpdateOrderStatus(ctx context.Context, args struct {
+	ID     graphql.ID
+	Status string
+}) (*orderResolver, error) {
+	if err := gqlRequire(ctx, "updateOrderStatus", auth.ScopeWrite); err != nil {
+		return nil, err
+	}
+	o, err := r.s.updateStatus(ctx, string(args.ID), args.Status)
+	if err != nil {
+		return nil, gqlFail(ctx, "updateOrderStatus", err, "update order status failed", "order_id", args.ID)
+	}
+	return &orderResolver{o}, nil
+}
+
+// OrderEvents assina o bus antes de voltar (o watch chama ready depois de
+// Subscribe), então nada publicado depois do "subscribe" se perde.
+func (r *gqlResolver) OrderEvents(ctx context.Context, args struct {
+	OrderID      *graphql.ID
+	AfterEventID *graphql.ID
+}) (<-chan *orderEventResolver, error) {
+	if r.s.bus == nil {
+		return nil, &gqlError{"the event bus is not configured", "UNAVAILABLE"}
+	}
+	var orderID string
+	if args.OrderID != nil {
+		orderID = string(*args.OrderID)
+	}
+	var after int64
+	if args.AfterEventID != nil {
+		id, err := strconv.ParseInt(string(*args.AfterEventID), 10, 64)
+		if err != nil || id < 0 {
+			return nil, &gqlError{"afterEventId is not an event id", "BAD_USER_INPUT"}
+		}
+		after = id
+	}
+	if err := r.s.checkWatch(ctx, orderID); err != nil {
+		return nil, gqlFail(ctx, "orderEvents", err, "stream: load order failed", "order_id", orderID)
+	}
+
+	out := make(chan *orderEventResolver)
+	subscribed := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		defer close(out)
+		ready := func() error { close(subscribed); return nil }
+		send := func(e bus.Event) error {
+			select {
+			case out <- &orderEventResolver{s: r.s, e: e}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		err := r.s.watch(ctx, orderID, after, ready, send, nil)
+		if errors.Is(err, errWatchTooSlow) {
+			slog.WarnContext(ctx, "stream: subscriber too slow, closing", "order_id", orderID)
+		}
+		done <- err
+	}()
+	select {
+	case <-subscribed:
+		return out, nil
+	case err := <-done:
+		return nil, gqlFail(ctx, "orderEvents", err, "stream failed", "order_id", orderID)
+	}
+}
+
+type orderResolver struct{ o store.Order }
+
+func (r *orderResolver) ID() graphql.ID          { return graphql.ID(r.o.ID) }
+func (r *orderResolver) TenantID() string        { return r.o.TenantID }
+func (r *orderResolver) Customer() string        { return r.o.Customer }
+func (r *orderResolver) CustomerID() graphql.ID  { return graphql.ID(r.o.CustomerID) }
+func (r *orderResolver) Status() string          { return r.o.Status }
+func (r *orderResolver) Items() []string         { return r.o.Items }
+func (r *orderResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.o.CreatedAt} }
+func (r *orderResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.o.UpdatedAt} }
+func (r *orderResolver) CreatedBy() *string      { return optional(r.o.CreatedBy) }
+func (r *orderResolver) UpdatedBy() *string      { return optional(r.o.UpdatedBy) }
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+type orderConnection struct {
+	orders []store.Order
+	more   bool
+}
+
+func (c *orderConnection) Edges() []*orderEdge {
+	edges := make([]*orderEdge, len(c.orders))
+	for i, o := range c.orders {
+		edges[i] = &orderEdge{o}
+	}
+	return edges
+}
+
+func (c *orderConnection) PageInfo() *pageInfo { return &pageInfo{c} }
+
+type orderEdge struct{ o store.Order }
+
+func (e *orderEdge) Cursor() string       { return encodeCursor(e.o) }
+func (e *orderEdge) Node() *orderResolver { return &orderResolver{e.o} }
+
+type pageInfo struct{ c *orderConnection }
+
+func (p *pageInfo) HasNextPage() bool { return p.c.more }
+
+func (p *pageInfo) EndCursor() *string {
+	n := len(p.c.orders)
+	if n == 0 {
+		return nil
+	}
+	end := encodeCursor(p.c.orders[n-1])
+	return &end
+}
+
+type orderEventResolver struct {
+	s *Server
+	e bus.Event
+}
+
+func (r *orderEventResolver) ID() graphql.ID      { return graphql.ID(strconv.FormatInt(r.e.ID, 10)) }
+func (r *orderEventResolver) Type() string        { return r.e.Type }
+func (r *orderEventResolver) OrderID() graphql.ID { return graphql.ID(r.e.OrderID) }
+func (r *orderEventResolver) TenantID() string    { return r.e.Tenant }
+func (r *orderEventResolver) Payload() string     { return string(r.e.Payload) }
+
+// Order lê o pedido na hora da entrega (null se não estiver mais visível).
+func (r *orderEventResolver) Order(ctx context.Context) (*orderResolver, error) {
+	o, err := r.s.getOrder(ctx, r.e.OrderID)
+	if store.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, gqlFail(ctx, "orderEvents.order", err, "get order failed", "order_id", r.e.OrderID)
+	}
+	return &orderResolver{o}, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Cursor: base64url("<created_at RFC3339Nano>|<id>"), opaco para o cliente
+// ──────────────────────────────────────────────────────────────────────────────
+
+func encodeCursor(o store.Order) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(o.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + o.ID))
+}
+
+func decodeCursor(s string) (orderCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return orderCursor{}, err
+	}
+	ts, id, ok := strings.Cut(string(b), "|")
+	if !ok || id == "" {
+		return orderCursor{}, errors.New("malformed cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return orderCursor{}, err
+	}
+	return orderCursor{CreatedAt: t, ID: id}, nil
+}
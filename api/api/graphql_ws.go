@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Subscriptions GraphQL sobre WebSocket, protocolo graphql-transport-ws
+// (https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md).
+// Autenticação, tenant e rate limit são os do request de upgrade.
+// ──────────────────────────────────────────────────────────────────────────────
+
+const (
+	gqlWSProtocol    = "graphql-transport-ws"
+	gqlWSInitTimeout = 10 * time.Second
+)
+
+// Códigos de fechamento do protocolo.
+const (
+	gqlWSBadRequest      websocket.StatusCode = 4400
+	gqlWSInitTimedOut    websocket.StatusCode = 4408
+	gqlWSSubscriberInUse websocket.StatusCode = 4409
+)
+
+type gqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// serveGraphQLWS aceita o upgrade e atende connection_init, ping/pong,
+// subscribe e complete. O resolver da subscription roda no laço de leitura
+// (um pong depois do subscribe garante que a assinatura está ativa); as
+// respostas seguem numa goroutine até o fim, um complete ou o fim da conexão.
+func (s *Server) serveGraphQLWS(w http.ResponseWriter, r *http.Request) {
+	c, err := websocket.Accept(w, r, &websocket.AcceptOptions{Subprotocols: []string{gqlWSProtocol}})
+	if err != nil {
+		return // Accept já respondeu o erro
+	}
+	defer c.CloseNow()
+	if c.Subprotocol() != gqlWSProtocol {
+		c.Close(websocket.StatusPolicyViolation, "unsupported subprotocol; use "+gqlWSProtocol)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	var subs sync.WaitGroup
+	defer func() { cancel(); subs.Wait() }()
+
+	// shutdown: fecha com 1001 para o cliente reconectar em outra réplica
+	go func() {
+		select {
+		case <-s.streamsDone:
+			c.Close(websocket.StatusGoingAway, "server shutting down")
+		case <-ctx.Done():
+		}
+	}()
+
+	var wmu sync.Mutex
+	write := func(m gqlWSMessage) error {
+		wmu.Lock()
+		defer wmu.Unlock()
+		return wsjson.Write(ctx, c, m)
+	}
+
+	initCtx, cancelInit := context.WithTimeout(ctx, gqlWSInitTimeout)
+	var msg gqlWSMessage
+	err = wsjson.Read(initCtx, c, &msg)
+	cancelInit()
+	if err != nil || msg.Type != "connection_init" {
+		c.Close(gqlWSInitTimedOut, "connection initialisation timeout")
+		return
+	}
+	if write(gqlWSMessage{Type: "connection_ack"}) != nil {
+		return
+	}
+
+	var (
+		mu     sync.Mutex
+		active = map[string]*context.CancelFunc{} // ponteiro: identifica a assinatura se o id for reusado
+	)
+	for {
+		var msg gqlWSMessage
+		if err := wsjson.Read(ctx, c, &msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case "ping":
+			_ = write(gqlWSMessage{Type: "pong", Payload: msg.Payload})
+		case "pong":
+		case "subscribe":
+			var req graphqlReq
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil || req.Query == "" {
+				c.Close(gqlWSBadRequest, "invalid subscribe message")
+				return
+			}
+			mu.Lock()
+			if _, dup := active[msg.ID]; dup {
+				mu.Unlock()
+				c.Close(gqlWSSubscriberInUse, "subscriber for "+msg.ID+" already exists")
+				return
+			}
+			subCtx, stop := context.WithCancel(ctx)
+			entry := &stop
+			active[msg.ID] = entry
+			mu.Unlock()
+
+			ch, err := s.gql.Subscribe(subCtx, req.Query, req.OperationName, req.Variables)
+			subs.Add(1)
+			go func(id string) {
+				defer subs.Done()
+				defer func() {
+					mu.Lock()
+					if active[id] == entry {
+						delete(active, id)
+					}
+					mu.Unlock()
+					stop()
+				}()
+				forwardGraphQL(subCtx, id, ch, err, write)
+			}(msg.ID)
+		case "complete":
+			mu.Lock()
+			// o id fica livre na hora, mesmo antes de a goroutine terminar
+			if stop, ok := active[msg.ID]; ok {
+				(*stop)()
+				delete(active, msg.ID)
+			}
+			mu.Unlock()
+		default:
+			c.Close(gqlWSBadRequest, "unexpected message type "+msg.Type)
+			return
+		}
+	}
+}
+
+// forwardGraphQL repassa as respostas da operação (subscription, ou uma
+// query/mutation de resposta única) como "next". Erros antes do primeiro
+// resultado vão como "error", sem "complete".
+func forwardGraphQL(ctx context.Context, id string, ch <-chan any, err error, write func(gqlWSMessage) error) {
+	if err != nil {
+		payload, _ := json.Marshal([]*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)})
+		_ = write(gqlWSMessage{ID: id, Type: "error", Payload: payload})
+		return
+	}
+	for out := range ch {
+		resp, ok := out.(*graphql.Response)
+		if !ok || ctx.Err() != nil {
+			continue // drena o canal da biblioteca depois do cancelamento
+		}
+		withErrorCodes(resp.Errors)
+		if len(resp.Data) == 0 && len(resp.Errors) > 0 {
+			payload, _ := json.Marshal(resp.Errors)
+			_ = write(gqlWSMessage{ID: id, Type: "error", Payload: payload})
+			for range ch {
+			}
+			return
+		}
+		payload, _ := json.Marshal(resp)
+		if err := write(gqlWSMessage{ID: id, Type: "next", Payload: payload}); err != nil {
+			slog.DebugContext(ctx, "graphql: subscription write failed", "id", id, "err", err)
+		}
+	}
+	// cancelado por "complete" do cliente ou fim da conexão: sem complete
+	if ctx.Err() == nil {
+		_ = write(gqlWSMessage{ID: id, Type: "complete"})
+	}
+}
+
+// withErrorCodes copia extensions.code dos erros de resolver: a biblioteca
+// só faz isso em queries e mutations, não no resolver da subscription.
+func withErrorCodes(errs []*gqlerrors.QueryError) {
+	for _, e := range errs {
+		var ge *gqlError
+		if e.Extensions == nil && errors.As(e.ResolverError, &ge) {
+			e.Extensions = ge.Extensions()
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"orders-api/auth"
+	"orders-api/bus"
+	ordersv1 "orders-api/gen/orders/v1"
+	"orders-api/logging"
+	"orders-api/metrics"
+	"orders-api/store"
+	"orders-api/tenant"
+	"orders-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// gRPC: OrdersService (proto/orders/v1/orders.proto)
+// ──────────────────────────────────────────────────────────────────────────────
+
+// grpcRoute liga cada método à rota HTTP equivalente: mesmo scope e mesmos
+// limites (os buckets de rate limit são compartilhados entre os transportes).
+type grpcRoute struct {
+	route string // "MÉTODO /path", como em limits.routes
+	scope string
+}
+
+var grpcRoutes = map[string]grpcRoute{
+	ordersv1.OrdersService_CreateOrder_FullMethodName:       {"POST /orders", auth.ScopeWrite},
+	ordersv1.OrdersService_GetOrder_FullMethodName:          {"GET /orders/{id}", auth.ScopeRead},
+	ordersv1.OrdersService_ListOrders_FullMethodName:        {"GET /orders", auth.ScopeRead},
+	ordersv1.OrdersService_UpdateOrderStatus_FullMethodName: {"PUT /orders/{id}/status", auth.ScopeWrite},
+	ordersv1.OrdersService_WatchOrders_FullMethodName:       {"GET /orders/stream", auth.ScopeRead},
+}
+
+// NewGRPCServer monta o servidor gRPC sobre as mesmas operações do HTTP.
+// Reflection fica ligado para grpcurl/grpcui.
+func (s *Server) NewGRPCServer() *grpc.Server {
+	gs := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	ordersv1.RegisterOrdersServiceServer(gs, &grpcOrders{s: s})
+	reflection.Register(gs)
+	return gs
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var resp any
+	size := 0
+	if m, ok := req.(proto.Message); ok {
+		size = proto.Size(m)
+	}
+	err := s.interceptGRPC(ctx, info.FullMethod, size, func(ctx context.Context) error {
+		var err error
+		resp, err = handler(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (s *Server) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return s.interceptGRPC(ss.Context(), info.FullMethod, 0, func(ctx context.Context) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	})
+}
+
+// contextStream troca o contexto do stream pelo enriquecido no interceptor.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (c *contextStream) Context() context.Context { return c.ctx }
+
+// interceptGRPC é a cadeia de middlewares do HTTP para uma chamada gRPC:
+// request id, tracing, log e métricas; depois auth, limites, tenant e scope.
+func (s *Server) interceptGRPC(ctx context.Context, method string, size int, call func(context.Context) error) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	id := mdValue(md, "x-request-id")
+	if !validRequestID(id) {
+		id = newID()
+	}
+	ctx = logging.WithRequestID(ctx, id)
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", id))
+
+	service, rpc, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	ctx = tracing.Propagator.Extract(ctx, metadataCarrier(md))
+	ctx, span := tracing.Tracer().Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(service),
+			semconv.RPCMethod(rpc),
+			attribute.String("rpc.request_id", id),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	err := s.authorizeGRPC(ctx, md, method, size, call)
+	code := status.Code(err)
+
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if grpcServerFault(code) {
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	labels := []string{method, code.String()}
+	metrics.GRPCRequests.WithLabelValues(labels...).Inc()
+	metrics.GRPCDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	slog.InfoContext(ctx, "grpc response",
+		"method", method,
+		"code", code.String(),
+		"latency_ms", float64(time.Since(start).Microseconds())/1000,
+	)
+	return err
+}
+
+func (s *Server) authorizeGRPC(ctx context.Context, md metadata.MD, method string, size int, call func(context.Context) error) error {
+	rt, ok := grpcRoutes[method]
+	if !ok {
+		return call(ctx) // reflection
+	}
+
+	if s.authn != nil {
+		// credenciais recusadas gastam o bucket do IP, como no withAuth
+		ip := grpcPeerIP(ctx)
+		if wait := s.limiters.authWait(ip, time.Now()); wait > 0 {
+			return grpcRateLimited(ctx, wait)
+		}
+		p, err := s.authn.Credentials(mdValue(md, "x-api-key"), mdValue(md, "authorization"))
+		if err != nil {
+			s.limiters.authFailed(ip, time.Now())
+			slog.InfoContext(ctx, "authentication failed", "path", method, "err", err)
+			detail := "provide x-api-key or authorization: Bearer <jwt> metadata"
+			if !errors.Is(err, auth.ErrNoCredentials) {
+				detail = err.Error()
+			}
+			return status.Error(codes.Unauthenticated, detail)
+		}
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("enduser.id", p.Subject))
+		ctx = auth.WithPrincipal(ctx, p)
+	}
+
+	lim := s.limits.For(rt.route)
+	if lim.RatePerSecond > 0 {
+		if wait := s.limiters.reserve(rt.route, grpcClientKey(ctx), lim, time.Now()); wait > 0 {
+			return grpcRateLimited(ctx, wait)
+		}
+	}
+	if lim.MaxBodyBytes > 0 && int64(size) > lim.MaxBodyBytes {
+		return status.Errorf(codes.ResourceExhausted, "request message exceeds %d bytes", lim.MaxBodyBytes)
+	}
+
+	tnt, err := resolveTenant(ctx, s.tenancy, mdValue(md, strings.ToLower(s.tenancy.Header)))
+	if err != nil {
+		return grpcError(ctx, method, err, "")
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("tenant.id", tnt))
+	ctx = tenant.WithID(ctx, tnt)
+
+	if p, ok := auth.FromContext(ctx); ok && !p.Has(rt.scope) {
+		return grpcError(ctx, method, denied("missing scope "+rt.scope, "scope", rt.scope), "")
+	}
+	if err := s.checkBoundCustomer(ctx); err != nil {
+		return grpcError(ctx, method, err, "")
+	}
+	return call(ctx)
+}
+
+// grpcClientKey é o clientKey do HTTP: subject autenticado ou IP do peer.
+func grpcClientKey(ctx context.Context) string {
+	if p, ok := auth.FromContext(ctx); ok {
+		return "sub:" + p.Subject
+	}
+	return grpcPeerIP(ctx)
+}
+
+// grpcPeerIP é a chave "ip:<host>" do peer da chamada.
+func grpcPeerIP(ctx context.Context) string {
+	if pr, ok := peer.FromContext(ctx); ok && pr.Addr != nil {
+		host, _, err := net.SplitHostPort(pr.Addr.String())
+		if err != nil {
+			host = pr.Addr.String()
+		}
+		return "ip:" + host
+	}
+	return "ip:unknown"
+}
+
+// grpcRateLimited é o 429 do gRPC: ResourceExhausted com retry-after no
+// trailer.
+func grpcRateLimited(ctx context.Context, wait time.Duration) error {
+	secs := strconv.Itoa(int(math.Ceil(wait.Seconds())))
+	_ = grpc.SetTrailer(ctx, metadata.Pairs("retry-after", secs))
+	return status.Error(codes.ResourceExhausted, "rate limit exceeded; retry in "+secs+"s")
+}
+
+// grpcError traduz os erros das operações de pedido (orders.go) para status
+// gRPC, como writeError faz para HTTP.
+func grpcError(ctx context.Context, method string, err error, msg string, attrs ...any) error {
+	var (
+		inv *invalidError
+		den *deniedError
+		bad *tenantError
+	)
+	if _, ok := status.FromError(err); ok {
+		return err // já é status gRPC (ex.: Send com o cliente desconectado)
+	}
+	switch {
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	case errors.As(err, &inv):
+		return status.Error(codes.InvalidArgument, inv.detail)
+	case errors.As(err, &bad):
+		return status.Error(codes.InvalidArgument, bad.detail)
+	case errors.As(err, &den):
+		p, _ := auth.FromContext(ctx)
+		auditDenied(ctx, p, "gRPC", method, den.reason, den.attrs...)
+		return status.Error(codes.PermissionDenied, den.reason)
+	case store.IsNotFound(err):
+		return status.Error(codes.NotFound, "order not found")
+	case errors.Is(err, errPublish), errors.Is(err, errWatchClosed), errors.Is(err, errWatchTooSlow):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		slog.ErrorContext(ctx, msg, append(attrs, "err", err)...)
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// grpcServerFault separa erros do servidor (span com erro) dos do cliente.
+func grpcServerFault(c codes.Code) bool {
+	switch c {
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+func mdValue(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// metadataCarrier adapta a metadata gRPC para o propagator W3C.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string { return mdValue(metadata.MD(c), key) }
+func (c metadataCarrier) Set(key, value string) { metadata.MD(c).Set(key, value) }
+func (c metadataCarrier) Keys() []string {
+	out := make([]string, 0, len(c))
+	for k := range c {
+		out = append(out, k)
+	}
+	return out
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Handlers
+// ──────────────────────────────────────────────────────────────────────────────
+
+type grpcOrders struct {
+	ordersv1.UnimplementedOrdersServiceServer
+	s *Server
+}
+
+func (g *grpcOrders) CreateOrder(ctx context.Context, req *ordersv1.CreateOrderRequest) (*ordersv1.CreateOrderResponse, error) {
+	o, err := g.s.createOrder(ctx, createReq{Customer: req.GetCustomer(), CustomerID: req.GetCustomerId(), Items: req.GetItems()})
+	if err != nil {
+		return nil, grpcError(ctx, ordersv1.OrdersService_CreateOrder_FullMethodName, err, "insert order failed")
+	}
+	return &ordersv1.CreateOrderResponse{Order: orderToProto(o)}, nil
+}
+
+func (g *grpcOrders) GetOrder(ctx context.Context, req *ordersv1.GetOrderRequest) (*ordersv1.GetOrderResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	o, err := g.s.getOrder(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(ctx, ordersv1.OrdersService_GetOrder_FullMethodName, err, "get order failed", "order_id", req.GetId())
+	}
+	return &ordersv1.GetOrderResponse{Order: orderToProto(o)}, nil
+}
+
+func (g *grpcOrders) ListOrders(ctx context.Context, req *ordersv1.ListOrdersRequest) (*ordersv1.ListOrdersResponse, error) {
+	q := orderQuery{
+		Customer: req.GetCustomer(),
+		Limit:    int(req.GetLimit()),
+		Offset:   int(req.GetOffset()),
+	}
+	if st := req.GetStatus(); st != "" {
+		q.Status = []string{st}
+	}
+	if req.GetSince() != nil {
+		q.Since = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		q.Until = req.GetUntil().AsTime()
+	}
+	q.normalize()
+
+	list, err := g.s.listOrders(ctx, q)
+	if err != nil {
+		return nil, grpcError(ctx, ordersv1.OrdersService_ListOrders_FullMethodName, err, "list orders failed")
+	}
+	resp := &ordersv1.ListOrdersResponse{Limit: int32(q.Limit), Offset: int32(q.Offset), Count: int32(len(list))}
+	for _, o := range list {
+		resp.Items = append(resp.Items, orderToProto(o))
+	}
+	return resp, nil
+}
+
+func (g *grpcOrders) UpdateOrderStatus(ctx context.Context, req *ordersv1.UpdateOrderStatusRequest) (*ordersv1.UpdateOrderStatusResponse, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	o, err := g.s.updateStatus(ctx, req.GetId(), req.GetStatus())
+	if err != nil {
+		return nil, grpcError(ctx, ordersv1.OrdersService_UpdateOrderStatus_FullMethodName, err,
+			"update order status failed", "order_id", req.GetId())
+	}
+	return &ordersv1.UpdateOrderStatusResponse{Order: orderToProto(o)}, nil
+}
+
+// WatchOrders é o /orders/stream do gRPC. Os headers da resposta saem assim
+// que a assinatura do bus está feita: a partir daí nada publicado se perde.
+func (g *grpcOrders) WatchOrders(req *ordersv1.WatchOrdersRequest, stream ordersv1.OrdersService_WatchOrdersServer) error {
+	const method = ordersv1.OrdersService_WatchOrders_FullMethodName
+	ctx := stream.Context()
+	if g.s.bus == nil {
+		return status.Error(codes.Unavailable, "the event bus is not configured")
+	}
+	if err := g.s.checkWatch(ctx, req.GetOrderId()); err != nil {
+		return grpcError(ctx, method, err, "stream: load order failed", "order_id", req.GetOrderId())
+	}
+	ready := func() error { return stream.SendHeader(metadata.MD{}) }
+	send := func(e bus.Event) error {
+		return stream.Send(&ordersv1.WatchOrdersResponse{Event: &ordersv1.OrderEvent{
+			Id: e.ID, Type: e.Type, OrderId: e.OrderID, TenantId: e.Tenant, Payload: e.Payload,
+		}})
+	}
+	if err := g.s.watch(ctx, req.GetOrderId(), req.GetAfterEventId(), ready, send, nil); err != nil {
+		return grpcError(ctx, method, err, "stream failed", "order_id", req.GetOrderId())
+	}
+	return nil
+}
+
+func orderToProto(o store.Order) *ordersv1.Order {
+	return &ordersv1.Order{
+		Id:         o.ID,
+		TenantId:   o.TenantID,
+		Customer:   o.Customer,
+		CustomerId: o.CustomerID,
+		Status:     o.Status,
+		Items:      o.Items,
+		CreatedAt:  timestamppb.New(o.CreatedAt),
+		UpdatedAt:  timestamppb.New(o.UpdatedAt),
+		CreatedBy:  o.CreatedBy,
+		UpdatedBy:  o.UpdatedBy,
+	}
+}
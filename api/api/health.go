@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Probes: /livez (processo de pé) e /readyz (dependências OK)
+// ──────────────────────────────────────────────────────────────────────────────
+
+// readyCheckTimeout limita cada checagem de dependência do /readyz.
+const readyCheckTimeout = 2 * time.Second
+
+type checkResult struct {
+	Status    string  `json:"status"` // ok|fail
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type readyResp struct {
+	Status string                 `json:"status"`
+	Reason string                 `json:"reason,omitempty"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// StartDraining faz o /readyz passar a falhar, para o balanceador parar de
+// mandar tráfego antes do shutdown do servidor HTTP, e encerra os streams
+// SSE (senão o Shutdown esperaria por eles até o timeout).
+func (s *Server) StartDraining() {
+	s.draining.Store(true)
+	s.stopStreams.Do(func() { close(s.streamsDone) })
+}
+
+func (s *Server) handleLivez(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, readyResp{Status: "fail", Reason: "shutting down"})
+		return
+	}
+
+	checks := map[string]func(context.Context) error{
+		"mysql": s.db.PingContext,
+		"kafka": s.publisher.CheckTopic,
+	}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		out = readyResp{Status: "ok", Checks: map[string]checkResult{}}
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) error) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(ctx)
+			res := checkResult{Status: "ok", LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				res.Status, res.Error = "fail", err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			out.Checks[name] = res
+			if err != nil {
+				out.Status = "fail"
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	if out.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, out)
+}
@@ -0,0 +1,612 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"orders-api/auth"
+	"orders-api/config"
+	"orders-api/events"
+	"orders-api/logging"
+	"orders-api/metrics"
+	"orders-api/outbox"
+	"orders-api/store"
+	"orders-api/tenant"
+	"orders-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Imports (POST /imports): um arquivo CSV ou NDJSON de pedidos, conferido na
+// chegada e processado depois pelo worker, em lotes de imports.chunkSize
+// linhas, cada lote numa transação com o progresso. Linhas inválidas não
+// param o import: vão para o arquivo de rejeitos (GET /imports/{id}/rejects),
+// no formato original, pronto para corrigir e reenviar.
+// ──────────────────────────────────────────────────────────────────────────────
+
+const (
+	importCSV    = "csv"
+	importNDJSON = "ndjson"
+
+	importPreviewRejects = 20 // recusas que já vêm no GET /imports/{id}
+	maxImportLine        = 1 << 20
+)
+
+// importRow é uma linha de dados do arquivo, já decodificada.
+type importRow struct {
+	line int    // no arquivo, contando o cabeçalho do CSV
+	raw  string // como veio, para o arquivo de rejeitos
+	req  createReq
+	err  string // linha ilegível: vai direto para os rejeitos
+}
+
+// importView é o import com uma amostra das recusas.
+type importView struct {
+	store.Import
+	Rejects    []store.ImportReject `json:"rejects"`
+	RejectsURL string               `json:"rejectsUrl,omitempty"`
+}
+
+func (s *Server) handleCreateImport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filename, format, content, ok := readUpload(w, r)
+	if !ok {
+		return
+	}
+	header, rows, err := parseImport(format, content)
+	if err != nil {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Invalid import file", err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Empty import", "the file has no rows")
+		return
+	}
+	if max := s.imports.MaxRows; len(rows) > max {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, "Import too large",
+			fmt.Sprintf("%d rows (max %d)", len(rows), max))
+		return
+	}
+
+	now := time.Now().UTC()
+	job := store.ImportJob{
+		Import: store.Import{
+			ID: newID(), TenantID: tenant.FromContext(ctx), Status: store.ImportQueued, Format: format,
+			Filename: filename, Header: header, TotalRows: len(rows),
+			CreatedAt: now, UpdatedAt: now, CreatedBy: auth.Subject(ctx),
+		},
+		Content: content,
+		Headers: importHeaders(ctx),
+	}
+	if p, ok := auth.FromContext(ctx); ok {
+		if job.Principal, err = json.Marshal(p); err != nil {
+			writeError(w, r, err, "import: encode principal failed")
+			return
+		}
+	}
+	if err := store.InsertImport(ctx, s.db, job); err != nil {
+		writeError(w, r, err, "insert import failed")
+		return
+	}
+	slog.InfoContext(ctx, "import queued", "audit", true, "import_id", job.ID, "format", format,
+		"rows", len(rows), "bytes", len(content), "subject", job.CreatedBy)
+
+	w.Header().Set("Location", "/imports/"+job.ID)
+	writeJSON(w, http.StatusAccepted, importView{Import: job.Import, Rejects: []store.ImportReject{}})
+}
+
+// handleGetImport: progresso do import e os primeiros rejeitos.
+func (s *Server) handleGetImport(w http.ResponseWriter, r *http.Request, id string) {
+	ctx := r.Context()
+	im, err := s.getImport(ctx, id)
+	if err != nil {
+		writeError(w, r, err, "get import failed", "import_id", id)
+		return
+	}
+	view := importView{Import: im}
+	if view.Rejects, err = store.ListImportRejects(ctx, s.db, id, importPreviewRejects); err != nil {
+		writeError(w, r, err, "list import rejects failed", "import_id", id)
+		return
+	}
+	if im.RejectedRows > 0 {
+		view.RejectsURL = "/imports/" + id + "/rejects"
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// handleImportRejects: o arquivo de rejeitos do import.
+func (s *Server) handleImportRejects(w http.ResponseWriter, r *http.Request, id string) {
+	im, err := s.getImport(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err, "get import failed", "import_id", id)
+		return
+	}
+	s.writeRejects(w, r, im)
+}
+
+// getImport: o import do tenant; principal preso a um cliente só vê os que
+// ele mesmo enviou.
+func (s *Server) getImport(ctx context.Context, id string) (store.Import, error) {
+	im, err := store.GetImport(ctx, s.db, tenant.FromContext(ctx), id)
+	if err != nil {
+		return store.Import{}, err
+	}
+	if p, ok := auth.FromContext(ctx); ok && p.Bound() && im.CreatedBy != p.Subject {
+		return store.Import{}, denied("import belongs to another subject", "import_id", id)
+	}
+	return im, nil
+}
+
+// writeRejects devolve as linhas recusadas no formato do arquivo enviado,
+// com a linha e o motivo: colunas "line" e "error" na frente no CSV, campos
+// "line" e "error" no NDJSON. O import ignora campos além de customer e
+// items, então o arquivo pode ser corrigido e reenviado como está.
+func (s *Server) writeRejects(w http.ResponseWriter, r *http.Request, im store.Import) {
+	ctx := r.Context()
+	media, ext := exportNDJSON, "ndjson"
+	if im.Format == importCSV {
+		media, ext = exportCSV, "csv"
+	}
+	h := w.Header()
+	h.Set("Content-Type", media+"; charset=utf-8")
+	h.Set("Content-Disposition", `attachment; filename="import-`+im.ID+`-rejects.`+ext+`"`)
+	h.Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	var (
+		cw  *csv.Writer
+		enc = json.NewEncoder(w)
+	)
+	if im.Format == importCSV {
+		cw = csv.NewWriter(w)
+		head, _ := csv.NewReader(strings.NewReader(im.Header)).Read()
+		_ = cw.Write(append([]string{"line", "error"}, head...)) // erro de escrita aparece no flush
+	}
+	err := store.EachImportReject(ctx, s.db, im.ID, 0, func(rj store.ImportReject) error {
+		if cw != nil {
+			rec, _ := csv.NewReader(strings.NewReader(rj.Content)).Read()
+			return cw.Write(append([]string{strconv.Itoa(rj.Line), csvCell(rj.Error)}, rec...))
+		}
+		obj := map[string]any{}
+		if err := json.Unmarshal([]byte(rj.Content), &obj); err != nil {
+			obj = map[string]any{"raw": rj.Content}
+		}
+		obj["line"], obj["error"] = rj.Line, rj.Error
+		return enc.Encode(obj)
+	})
+	if err == nil && cw != nil {
+		cw.Flush()
+		err = cw.Error()
+	}
+	if err != nil {
+		// como na exportação: cortar a conexão em vez de entregar pela metade
+		slog.ErrorContext(ctx, "import rejects aborted", "import_id", im.ID, "err", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// readUpload lê o arquivo de um multipart/form-data (campo "file") ou do
+// corpo cru (text/csv, application/x-ndjson). O formato vem do Content-Type
+// da parte e, se ele for genérico, da extensão do arquivo.
+func readUpload(w http.ResponseWriter, r *http.Request) (filename, format string, content []byte, ok bool) {
+	media, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var (
+		body io.Reader = r.Body
+		err  error
+	)
+	if media == "multipart/form-data" {
+		var part io.Reader
+		filename, media, part, err = uploadPart(r)
+		if err == nil && part == nil {
+			writeProblem(w, r, http.StatusBadRequest, "Missing file", `the form has no "file" field`)
+			return "", "", nil, false
+		}
+		body = part
+	}
+	if err == nil {
+		if format = importFormat(media, filename); format == "" {
+			writeProblem(w, r, http.StatusUnsupportedMediaType, "Unsupported Media Type",
+				"upload a CSV (text/csv) or NDJSON (application/x-ndjson) file")
+			return "", "", nil, false
+		}
+		content, err = io.ReadAll(body)
+	}
+	var tooBig *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooBig):
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, "Request Entity Too Large",
+			"request body exceeds "+strconv.FormatInt(tooBig.Limit, 10)+" bytes")
+		return "", "", nil, false
+	case err != nil:
+		writeProblem(w, r, http.StatusBadRequest, "Invalid upload", err.Error())
+		return "", "", nil, false
+	}
+	return filename, format, content, true
+}
+
+// uploadPart acha a parte "file" do formulário (part nil se não houver).
+func uploadPart(r *http.Request) (filename, media string, part io.Reader, err error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return "", "", nil, err
+	}
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return "", "", nil, nil
+		}
+		if err != nil {
+			return "", "", nil, err
+		}
+		if p.FormName() != "file" {
+			continue
+		}
+		media, _, _ = mime.ParseMediaType(p.Header.Get("Content-Type"))
+		if filename = p.FileName(); filename != "" {
+			filename = path.Base(filename)
+		}
+		return filename, media, p, nil
+	}
+}
+
+func importFormat(media, filename string) string {
+	switch media {
+	case exportCSV:
+		return importCSV
+	case exportNDJSON, "application/ndjson", "application/jsonl":
+		return importNDJSON
+	case "", "application/octet-stream", "text/plain":
+		switch strings.ToLower(path.Ext(filename)) {
+		case ".csv":
+			return importCSV
+		case ".ndjson", ".jsonl":
+			return importNDJSON
+		}
+	}
+	return ""
+}
+
+// importHeaders guarda o X-Request-Id e o traceparent do upload, para o
+// processamento (e os eventos dos pedidos) continuar o mesmo trace.
+func importHeaders(ctx context.Context) map[string]string {
+	h := map[string]string{}
+	if id := logging.RequestID(ctx); id != "" {
+		h[events.HeaderRequestID] = id
+	}
+	tracing.Propagator.Inject(ctx, tracing.MapCarrier(h))
+	return h
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Formato dos arquivos: o mesmo da exportação. CSV com cabeçalho e as
+// colunas customer e items (array JSON); NDJSON com um objeto por linha.
+// Outras colunas/campos são ignorados, então um export pode ser reimportado.
+// ──────────────────────────────────────────────────────────────────────────────
+
+// parseImport decodifica o arquivo inteiro. Erro só para o que impede de
+// ler as linhas (CSV sem as colunas, aspas quebradas, linha longa demais);
+// problemas de uma linha ficam em importRow.err.
+func parseImport(format string, content []byte) (string, []importRow, error) {
+	content = bytes.TrimPrefix(content, []byte("\uFEFF")) // BOM do Excel
+	if format == importCSV {
+		return parseCSVImport(content)
+	}
+	rows, err := parseNDJSONImport(content)
+	return "", rows, err
+}
+
+func parseCSVImport(content []byte) (string, []importRow, error) {
+	cr := csv.NewReader(bytes.NewReader(content))
+	cr.FieldsPerRecord = -1 // conferido por linha, para virar rejeito
+	head, err := cr.Read()
+	if err == io.EOF {
+		return "", nil, errors.New("the CSV has no header line")
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	col := map[string]int{}
+	for i, name := range head {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	ci, okC := col["customer"]
+	ii, okI := col["items"]
+	if !okC || !okI {
+		return "", nil, errors.New(`the CSV header must have "customer" and "items" columns`)
+	}
+
+	var rows []importRow
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		row := importRow{line: line, raw: csvLine(rec)}
+		if len(rec) != len(head) {
+			row.err = fmt.Sprintf("%d fields, the header has %d", len(rec), len(head))
+		} else {
+			row.req.Customer = uncell(rec[ci])
+			if items := strings.TrimSpace(uncell(rec[ii])); items != "" {
+				if err := json.Unmarshal([]byte(items), &row.req.Items); err != nil {
+					row.err = "items: not a JSON array of strings"
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return csvLine(head), rows, nil
+}
+
+func parseNDJSONImport(content []byte) ([]importRow, error) {
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Buffer(make([]byte, 64<<10), maxImportLine)
+	var rows []importRow
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		row := importRow{line: line, raw: text}
+		if err := json.Unmarshal([]byte(text), &row.req); err != nil {
+			row.err = "invalid JSON: " + err.Error()
+		}
+		rows = append(rows, row)
+	}
+	if errors.Is(sc.Err(), bufio.ErrTooLong) {
+		return nil, fmt.Errorf("line %d is longer than %d bytes", len(rows)+1, maxImportLine)
+	}
+	return rows, sc.Err()
+}
+
+// csvLine serializa um registro como uma linha CSV, sem o \n.
+func csvLine(rec []string) string {
+	var b bytes.Buffer
+	cw := csv.NewWriter(&b)
+	_ = cw.Write(rec)
+	cw.Flush()
+	return strings.TrimRight(b.String(), "\r\n")
+}
+
+// uncell desfaz o apóstrofo que o csvCell põe na frente de fórmulas.
+func uncell(v string) string {
+	if len(v) > 1 && v[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(v[1])) {
+		return v[1:]
+	}
+	return v
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Worker: reivindica um import por vez (várias réplicas podem rodar juntas,
+// ver store.ClaimImport) e grava lote a lote. Um import interrompido fica
+// "running" até o lease vencer e então é retomado de processed_rows.
+// ──────────────────────────────────────────────────────────────────────────────
+
+// RunImports processa a fila a cada imports.workerInterval até ctx acabar.
+func (s *Server) RunImports(ctx context.Context) {
+	t := time.NewTicker(s.imports.WorkerInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, err := s.ProcessImports(ctx); err != nil && ctx.Err() == nil {
+				slog.WarnContext(ctx, "import round failed", "err", err)
+			}
+		}
+	}
+}
+
+// ProcessImports processa imports até a fila esvaziar e devolve quantos
+// reivindicou.
+func (s *Server) ProcessImports(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		job, err := store.ClaimImport(ctx, s.db, time.Now().UTC(), s.imports.Lease)
+		if err != nil || job == nil {
+			return total, err
+		}
+		total++
+		if err := s.processImport(ctx, job); err != nil {
+			// fica running: outra rodada retoma quando o lease vencer
+			if ctx.Err() != nil {
+				return total, ctx.Err()
+			}
+			slog.WarnContext(ctx, "import interrupted", "import_id", job.ID, "attempt", job.Attempts, "err", err)
+		}
+	}
+}
+
+func (s *Server) processImport(ctx context.Context, job *store.ImportJob) (err error) {
+	ctx, err = importContext(ctx, job)
+	if err != nil {
+		return s.failImport(ctx, job, "invalid stored principal: "+err.Error())
+	}
+	// o cliente pode ter sido apagado desde o upload
+	if err = s.checkBoundCustomer(ctx); err != nil {
+		return s.failImport(ctx, job, err.Error())
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "import process",
+		trace.WithAttributes(
+			attribute.String("import.id", job.ID),
+			attribute.String("import.format", job.Format),
+			attribute.Int("import.attempt", job.Attempts),
+			attribute.Int("import.resume_at", job.ProcessedRows),
+		),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if job.Attempts > s.imports.MaxAttempts {
+		return s.failImport(ctx, job, fmt.Sprintf("gave up after %d attempts", job.Attempts-1))
+	}
+	_, rows, err := parseImport(job.Format, job.Content)
+	if err != nil {
+		return s.failImport(ctx, job, err.Error()) // conferido no upload; só se o formato mudar
+	}
+
+	start := time.Now()
+	lim := s.limits.For("POST /orders")
+	for job.ProcessedRows < len(rows) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := min(job.ProcessedRows+s.imports.ChunkSize, len(rows))
+		if err := s.importChunk(ctx, job, rows[job.ProcessedRows:end], lim); err != nil {
+			return err
+		}
+	}
+	if err := store.FinishImport(ctx, s.db, job.ID, store.ImportCompleted, "", time.Now().UTC()); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "import completed", "import_id", job.ID, "rows", len(rows),
+		"created", job.CreatedRows, "rejected", job.RejectedRows, "duration_ms", time.Since(start).Milliseconds())
+	return nil
+}
+
+// importChunk grava os pedidos válidos de um lote, as recusas e o progresso
+// numa transação e depois publica os eventos numa escrita só.
+func (s *Server) importChunk(ctx context.Context, job *store.ImportJob, rows []importRow, lim config.RouteLimits) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	next := job.Import
+	var (
+		evs     []store.OutboxEvent
+		rejects []store.ImportReject
+	)
+	for _, row := range rows {
+		next.ProcessedRows++
+		o, err := s.importOrder(ctx, row, lim)
+		if err != nil {
+			cause, ok := importFailure(ctx, job.ID, err)
+			if !ok {
+				return err
+			}
+			rejects = append(rejects, store.ImportReject{Line: row.line, Content: row.raw, Error: cause})
+			next.RejectedRows++
+			continue
+		}
+		orderEvs, err := s.insertOrder(ctx, tx, &o)
+		if err != nil {
+			cause, ok := importFailure(ctx, job.ID, err)
+			if !ok {
+				return err
+			}
+			// linkCustomer recusa antes de gravar qualquer coisa
+			rejects = append(rejects, store.ImportReject{Line: row.line, Content: row.raw, Error: cause})
+			next.RejectedRows++
+			continue
+		}
+		evs = append(evs, orderEvs...)
+		next.CreatedRows++
+	}
+	now := time.Now().UTC()
+	if err := store.RecordImportChunk(ctx, tx, next, rejects, now.Add(s.imports.Lease), now); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	metrics.OrdersCreated.Add(float64(next.CreatedRows - job.CreatedRows))
+	job.Import = next
+
+	// gravado vale mesmo sem o Kafka: o evento fica no outbox para o relay
+	failed := 0
+	for _, perr := range s.relay.PublishBatch(ctx, evs) {
+		if perr != nil && !errors.Is(perr, outbox.ErrQueued) {
+			failed++
+		}
+	}
+	if failed > 0 {
+		slog.WarnContext(ctx, "publish failed; events kept in outbox", "import_id", job.ID, "events", failed)
+	}
+	return nil
+}
+
+// importOrder valida uma linha como o POST /orders validaria o corpo.
+func (s *Server) importOrder(ctx context.Context, row importRow, lim config.RouteLimits) (store.Order, error) {
+	switch {
+	case row.err != "":
+		return store.Order{}, &invalidError{"Invalid row", row.err}
+	case strings.TrimSpace(row.req.Customer) == "":
+		return store.Order{}, &invalidError{"Invalid order", "customer is required"}
+	case len(row.req.Customer) > 255:
+		return store.Order{}, &invalidError{"Invalid order", "customer is longer than 255 bytes"}
+	}
+	if row.req.Items == nil {
+		row.req.Items = []string{}
+	}
+	return s.newOrder(ctx, row.req, lim)
+}
+
+// importFailure devolve o motivo da recusa de uma linha; ok=false para erros
+// que interrompem o import (banco, contexto).
+func importFailure(ctx context.Context, id string, err error) (string, bool) {
+	var (
+		inv *invalidError
+		den *deniedError
+	)
+	switch {
+	case errors.As(err, &inv):
+		return inv.detail, true
+	case errors.As(err, &den):
+		p, _ := auth.FromContext(ctx)
+		auditDenied(ctx, p, http.MethodPost, "/imports/"+id, den.reason, den.attrs...)
+		return "forbidden: " + den.reason, true
+	default:
+		return "", false
+	}
+}
+
+func (s *Server) failImport(ctx context.Context, job *store.ImportJob, cause string) error {
+	slog.WarnContext(ctx, "import failed", "import_id", job.ID, "processed", job.ProcessedRows, "err", cause)
+	return store.FinishImport(ctx, s.db, job.ID, store.ImportFailed, cause, time.Now().UTC())
+}
+
+// importContext refaz o contexto do upload: tenant, principal, request id
+// e o trace, para autoria, autorização e headers dos eventos saírem iguais
+// aos de um POST /orders.
+func importContext(ctx context.Context, job *store.ImportJob) (context.Context, error) {
+	ctx = tracing.Propagator.Extract(ctx, tracing.MapCarrier(job.Headers))
+	ctx = tenant.WithID(ctx, job.TenantID)
+	if id := job.Headers[events.HeaderRequestID]; id != "" {
+		ctx = logging.WithRequestID(ctx, id)
+	}
+	if job.Principal != nil {
+		var p auth.Principal
+		if err := json.Unmarshal(job.Principal, &p); err != nil {
+			return ctx, err
+		}
+		ctx = auth.WithPrincipal(ctx, p)
+	}
+	return ctx, nil
+}
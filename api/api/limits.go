@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"orders-api/auth"
+	"orders-api/config"
+	"orders-api/metrics"
+
+	"golang.org/x/time/rate"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Rate limiting e tamanho de request
+// ──────────────────────────────────────────────────────────────────────────────
+
+// limiterIdleTTL: buckets sem uso por esse tempo são descartados (um bucket
+// ocioso estaria cheio de qualquer forma).
+const limiterIdleTTL = 10 * time.Minute
+
+type bucket struct {
+	lim  *rate.Limiter
+	seen time.Time
+}
+
+// limiters guarda um token bucket por (rota, cliente).
+type limiters struct {
+	cfg config.Limits
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newLimiters(cfg config.Limits) *limiters {
+	return &limiters{cfg: cfg, buckets: map[string]*bucket{}, lastSweep: time.Now()}
+}
+
+// reserve consome um token do cliente na rota; devolve quanto ele deve
+// esperar se o bucket estiver vazio (0 = liberado).
+func (l *limiters) reserve(route, client string, lim config.RouteLimits, now time.Time) time.Duration {
+	b := l.bucket(route, client, lim, now)
+	res := b.lim.ReserveN(now, 1)
+	if d := res.DelayFrom(now); d > 0 {
+		res.CancelAt(now) // não consome: quem levou 429 não deve piorar a própria espera
+		return d
+	}
+	return 0
+}
+
+// authRoute é a "rota" dos buckets de credenciais recusadas (limits.auth).
+const authRoute = "auth"
+
+// authWait diz quanto o IP ainda deve esperar antes de apresentar outra
+// credencial (0 = liberado), sem consumir token: quem autentica certo não
+// gasta o bucket.
+func (l *limiters) authWait(ip string, now time.Time) time.Duration {
+	lim := l.cfg.Auth
+	if lim.RatePerSecond <= 0 {
+		return 0
+	}
+	tokens := l.bucket(authRoute, ip, lim, now).lim.TokensAt(now)
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - tokens) / lim.RatePerSecond * float64(time.Second))
+}
+
+// authFailed consome um token do IP a cada credencial recusada.
+func (l *limiters) authFailed(ip string, now time.Time) {
+	if lim := l.cfg.Auth; lim.RatePerSecond > 0 {
+		l.bucket(authRoute, ip, lim, now).lim.AllowN(now, 1)
+	}
+}
+
+// bucket devolve (criando se preciso) o bucket de (rota, cliente) e, de
+// minuto em minuto, descarta os ociosos.
+func (l *limiters) bucket(route, client string, lim config.RouteLimits, now time.Time) *bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > time.Minute {
+		for k, b := range l.buckets {
+			if now.Sub(b.seen) > limiterIdleTTL {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	key := route + "|" + client
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{lim: rate.NewLimiter(rate.Limit(lim.RatePerSecond), lim.Burst)}
+		l.buckets[key] = b
+	}
+	b.seen = now
+	return b
+}
+
+// clientKey identifica o cliente para o bucket: o subject autenticado (uma
+// API key/JWT) ou, sem auth, o IP da conexão.
+func clientKey(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return "sub:" + p.Subject
+	}
+	return remoteIP(r)
+}
+
+// remoteIP é a chave "ip:<host>" da conexão do request.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// writeRateLimited responde 429 com Retry-After e conta na métrica da rota.
+func writeRateLimited(w http.ResponseWriter, r *http.Request, wait time.Duration) {
+	secs := int(math.Ceil(wait.Seconds()))
+	metrics.RateLimited.WithLabelValues(routeLabel(r.URL.Path)).Inc()
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	writeProblem(w, r, http.StatusTooManyRequests, "Too Many Requests",
+		"rate limit exceeded; retry in "+strconv.Itoa(secs)+"s")
+}
+
+// withLimits aplica o token bucket e o teto de corpo da rota. Roda depois do
+// withAuth para contar por API key (credenciais recusadas já passaram pelo
+// bucket por IP do withAuth); probes e /metrics ficam de fora.
+func withLimits(l *limiters, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		route := r.Method + " " + routeLabel(r.URL.Path)
+		lim := l.cfg.For(route)
+
+		if lim.RatePerSecond > 0 {
+			if wait := l.reserve(route, clientKey(r), lim, time.Now()); wait > 0 {
+				writeRateLimited(w, r, wait)
+				return
+			}
+		}
+		if lim.MaxBodyBytes > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, lim.MaxBodyBytes)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CheckLimitRoutes confere as chaves de limits.routes contra as rotas que o
+// servidor registra (as do OpenAPI, fora probes e /metrics): uma chave que
+// não casa com nenhuma ("POST /order", "GET /orders/{ID}") seria ignorada em
+// silêncio pelo Limits.For.
+func CheckLimitRoutes(l config.Limits) error {
+	known := map[string]bool{}
+	for _, op := range apiOperations {
+		if !publicPaths[op.path] {
+			known[op.method+" "+op.path] = true
+		}
+	}
+	var errs []error
+	for route := range l.Routes {
+		if known[route] {
+			continue
+		}
+		hint := ""
+		for k := range known {
+			if strings.EqualFold(k, route) {
+				hint = fmt.Sprintf(" (did you mean %q?)", k)
+			}
+		}
+		errs = append(errs, fmt.Errorf("limits.routes[%q]: unknown route%s", route, hint))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return fmt.Errorf("invalid config:\n%w", errors.Join(errs...))
+}
+
+// decodeJSON lê o corpo em dst; responde 413 se passou do MaxBytesReader e
+// 400 para JSON inválido. Devolve false se já respondeu.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	err := json.NewDecoder(r.Body).Decode(dst)
+	if err == nil {
+		return true
+	}
+	var tooBig *http.MaxBytesError
+	if errors.As(err, &tooBig) {
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, "Request Entity Too Large",
+			"request body exceeds "+strconv.FormatInt(tooBig.Limit, 10)+" bytes")
+		return false
+	}
+	http.Error(w, "invalid json", http.StatusBadRequest)
+	return false
+}
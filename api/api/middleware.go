@@ -0,0 +1,481 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"orders-api/auth"
+	"orders-api/config"
+	"orders-api/logging"
+	"orders-api/metrics"
+	"orders-api/tenant"
+	"orders-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Middlewares
+// ──────────────────────────────────────────────────────────────────────────────
+
+const headerRequestID = "X-Request-Id"
+
+// withRequestID reaproveita o X-Request-Id do cliente (se for razoável) ou
+// gera um ULID, devolve no response e coloca no contexto para logs e eventos.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(headerRequestID)
+		if !validRequestID(id) {
+			id = newID()
+		}
+		w.Header().Set(headerRequestID, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID aceita até 128 caracteres ASCII visíveis (sem espaço),
+// para não deixar o cliente injetar lixo nos logs e headers Kafka.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Log de requests (HTTP_DEBUG)
+// ──────────────────────────────────────────────────────────────────────────────
+
+// maxLoggedBody limita quanto do corpo vai para o log em modo debug.
+const maxLoggedBody = 4 << 10
+
+// headers cujo valor nunca vai para o log
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+	"X-Api-Key":     true,
+}
+
+// campos JSON mascarados nos corpos logados
+var redactedFields = map[string]bool{
+	"password": true,
+	"secret":   true,
+	"token":    true,
+	"apiKey":   true,
+}
+
+// withRequestLog registra método, path, status e latência de cada request.
+// Com debug=true também loga headers e corpos (redigidos), espelhando o que
+// domain.ApiCtx.LogReq/LogResp imprime do lado dos testes.
+func withRequestLog(debug bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := r.Context()
+
+		if debug {
+			body := peekBody(r)
+			slog.InfoContext(ctx, "http request",
+				"method", r.Method,
+				"url", r.URL.RequestURI(),
+				"headers", redactHeaders(r.Header),
+				"body", redactBody(body),
+			)
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK, capture: debug}
+		next.ServeHTTP(rec, r)
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", rec.bytes,
+		}
+		if debug {
+			attrs = append(attrs,
+				"headers", redactHeaders(rec.Header()),
+				"body", redactBody(rec.body.Bytes()),
+			)
+		}
+		level := slog.LevelInfo
+		switch r.URL.Path {
+		case "/health", "/livez", "/readyz":
+			level = slog.LevelDebug // probes a cada poucos segundos
+		}
+		slog.Log(ctx, level, "http response", attrs...)
+	})
+}
+
+// peekBody lê até maxLoggedBody bytes do corpo e o recoloca intacto no request.
+func peekBody(r *http.Request) []byte {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	buf, _ := io.ReadAll(io.LimitReader(r.Body, maxLoggedBody))
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+	return buf
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, vals := range h {
+		if redactedHeaders[http.CanonicalHeaderKey(k)] {
+			out[k] = "REDACTED"
+			continue
+		}
+		out[k] = strings.Join(vals, ", ")
+	}
+	return out
+}
+
+// redactBody devolve JSON com campos sensíveis mascarados; corpos que não são
+// JSON vão como texto (truncado).
+func redactBody(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err == nil {
+		return redactValue(v)
+	}
+	if len(b) > maxLoggedBody {
+		b = append(b[:maxLoggedBody:maxLoggedBody], "…"...)
+	}
+	return string(b)
+}
+
+func redactValue(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, vv := range x {
+			if redactedFields[k] {
+				x[k] = "REDACTED"
+				continue
+			}
+			x[k] = redactValue(vv)
+		}
+	case []any:
+		for i := range x {
+			x[i] = redactValue(x[i])
+		}
+	}
+	return v
+}
+
+// responseRecorder guarda status e tamanho (e o corpo, em modo debug).
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+	capture     bool
+	body        bytes.Buffer
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	if !rr.wroteHeader {
+		rr.status = code
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	n, err := rr.ResponseWriter.Write(b)
+	rr.bytes += n
+	if rr.capture && rr.body.Len() < maxLoggedBody {
+		rr.body.Write(b[:min(n, maxLoggedBody-rr.body.Len())])
+	}
+	return n, err
+}
+
+// Flush mantém streaming funcionando através do wrapper.
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack permite o upgrade para WebSocket (/graphql) através do wrapper.
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	rr.wroteHeader = true
+	return http.NewResponseController(rr.ResponseWriter).Hijack()
+}
+
+func (rr *responseRecorder) Unwrap() http.ResponseWriter { return rr.ResponseWriter }
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Métricas HTTP
+// ──────────────────────────────────────────────────────────────────────────────
+
+// withMetrics conta requests e latência por rota (template, não o path cru,
+// para não explodir a cardinalidade com ids).
+func withMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		labels := []string{routeLabel(r.URL.Path), r.Method, strconv.Itoa(rec.status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routeLabel mapeia o path para o template da rota em apiOperations
+// ("other" fora dela), para não explodir a cardinalidade com ids.
+func routeLabel(path string) string {
+	_, pattern := routeIndex.Handler(&http.Request{Method: http.MethodGet, URL: &url.URL{Path: path}})
+	if pattern == "" {
+		return "other"
+	}
+	return pattern
+}
+
+// routeIndex casa paths com os templates de apiOperations, sem método.
+var routeIndex = func() *http.ServeMux {
+	m := http.NewServeMux()
+	seen := map[string]bool{}
+	for _, op := range apiOperations {
+		if !seen[op.path] {
+			seen[op.path] = true
+			m.Handle(op.path, http.NotFoundHandler())
+		}
+	}
+	return m
+}()
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Tracing
+// ──────────────────────────────────────────────────────────────────────────────
+
+// withTracing abre o span de servidor, continuando o traceparent do cliente
+// quando houver. Spans de DB e Kafka ficam pendurados nele via contexto.
+func withTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		route := routeLabel(r.URL.Path)
+		ctx, span := tracing.Tracer().Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+		if id := logging.RequestID(ctx); id != "" {
+			span.SetAttributes(attribute.String("http.request_id", id))
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.status))
+		if rec.status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Autenticação
+// ──────────────────────────────────────────────────────────────────────────────
+
+// publicPaths não exigem credencial nem tenant: as rotas de apiOperations
+// sem scope (probes, scrape do Prometheus e o documento OpenAPI).
+var publicPaths = func() map[string]bool {
+	out := map[string]bool{}
+	for _, op := range apiOperations {
+		if op.scope == "" {
+			out[op.path] = true
+		}
+	}
+	return out
+}()
+
+// withAuth exige API key ou bearer JWT válidos e coloca o Principal no
+// contexto; falhas viram 401 problem+json. Cada falha gasta o bucket do IP
+// (limits.auth), e um IP sem token leva 429 antes de a credencial ser
+// checada, para chaves e tokens inválidos não escaparem do rate limit.
+func withAuth(authn *auth.Authenticator, l *limiters, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ip := remoteIP(r)
+		if wait := l.authWait(ip, time.Now()); wait > 0 {
+			writeRateLimited(w, r, wait)
+			return
+		}
+		p, err := authn.Authenticate(r)
+		if err != nil {
+			l.authFailed(ip, time.Now())
+			slog.InfoContext(r.Context(), "authentication failed", "path", r.URL.Path, "err", err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="orders-api", ApiKey realm="orders-api"`)
+			detail := "provide X-Api-Key or Authorization: Bearer <jwt>"
+			if !errors.Is(err, auth.ErrNoCredentials) {
+				detail = err.Error()
+			}
+			writeProblem(w, r, http.StatusUnauthorized, "Unauthorized", detail)
+			return
+		}
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("enduser.id", p.Subject))
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+	})
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Autorização por rota
+// ──────────────────────────────────────────────────────────────────────────────
+
+// requireScope confere o scope do principal antes do handler. Sem principal
+// no contexto (auth.enabled=false) a rota fica aberta, como antes.
+func requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, authenticated := auth.FromContext(r.Context())
+		if authenticated && !p.Has(scope) {
+			forbid(w, r, p, "missing scope "+scope, "scope", scope)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// forbid responde 403 problem+json e deixa a linha de auditoria da negação.
+func forbid(w http.ResponseWriter, r *http.Request, p auth.Principal, reason string, attrs ...any) {
+	auditDenied(r.Context(), p, r.Method, r.URL.Path, reason, attrs...)
+	writeProblem(w, r, http.StatusForbidden, "Forbidden", reason)
+}
+
+// auditDenied é a linha de auditoria de uma negação, igual para HTTP e gRPC
+// (no gRPC, method é "gRPC" e path o método completo).
+func auditDenied(ctx context.Context, p auth.Principal, method, path, reason string, attrs ...any) {
+	attrs = append([]any{
+		"audit", true,
+		"subject", p.Subject,
+		"auth_method", p.Method,
+		"method", method,
+		"path", path,
+		"reason", reason,
+	}, attrs...)
+	slog.WarnContext(ctx, "access denied", attrs...)
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Tenant
+// ──────────────────────────────────────────────────────────────────────────────
+
+// withTenant resolve o tenant do request (claim/API key → header → padrão) e
+// coloca no contexto. Roda depois do withAuth: um principal preso a um tenant
+// não pode pedir outro pelo header.
+func withTenant(cfg config.Tenancy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		id, err := resolveTenant(r.Context(), cfg, r.Header.Get(cfg.Header))
+		var (
+			den *deniedError
+			bad *tenantError
+		)
+		switch {
+		case errors.As(err, &den):
+			p, _ := auth.FromContext(r.Context())
+			forbid(w, r, p, den.reason, den.attrs...)
+			return
+		case errors.As(err, &bad):
+			writeProblem(w, r, http.StatusBadRequest, bad.title, bad.detail)
+			return
+		}
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("tenant.id", id))
+		next.ServeHTTP(w, r.WithContext(tenant.WithID(r.Context(), id)))
+	})
+}
+
+// withCustomer recusa um principal preso a um customer_id que não existe no
+// tenant (ver checkBoundCustomer). Roda depois do withTenant.
+func (s *Server) withCustomer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		err := s.checkBoundCustomer(r.Context())
+		var den *deniedError
+		switch {
+		case errors.As(err, &den):
+			p, _ := auth.FromContext(r.Context())
+			forbid(w, r, p, den.reason, den.attrs...)
+			return
+		case err != nil:
+			writeError(w, r, err, "check bound customer failed")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tenantError é tenant ausente ou malformado (400 / InvalidArgument).
+type tenantError struct {
+	title, detail string
+}
+
+func (e *tenantError) Error() string { return e.title + ": " + e.detail }
+
+// resolveTenant decide o tenant a partir do pedido explícito (header ou
+// metadata), do principal e do padrão da config. Com auth, um principal sem
+// tenant só chega ao padrão e aos seus Tenants; outro tenant é 403.
+func resolveTenant(ctx context.Context, cfg config.Tenancy, requested string) (string, error) {
+	id := requested
+	p, authenticated := auth.FromContext(ctx)
+	if authenticated && p.Tenant != "" {
+		if id != "" && id != p.Tenant {
+			return "", denied("principal is bound to another tenant", "tenant", id)
+		}
+		id = p.Tenant
+	}
+	if id == "" {
+		id = cfg.Default
+	}
+	if id == "" {
+		return "", &tenantError{"Missing tenant", "set the " + cfg.Header + " header"}
+	}
+	if !tenant.Valid(id) {
+		return "", &tenantError{"Invalid tenant", "tenant must match [A-Za-z0-9_-]{1,64}"}
+	}
+	if authenticated && id != cfg.Default && !p.AllowsTenant(id) {
+		return "", denied("principal is not allowed in this tenant", "tenant", id)
+	}
+	return id, nil
+}
@@ -0,0 +1,702 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"orders-api/auth"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// OpenAPI 3.1: apiOperations é o registro único das rotas HTTP. Dela saem o
+// mux (registerRoutes), o documento servido em /openapi.json, os templates
+// de rota das métricas e do tracing (routeLabel) e as chaves aceitas em
+// limits.routes; uma rota nova entra só aqui.
+// ──────────────────────────────────────────────────────────────────────────────
+
+// operation descreve uma rota+método da API e o handler que a atende.
+type operation struct {
+	method, path string
+	handle       func(*Server, http.ResponseWriter, *http.Request)
+	summary      string
+	tag          string
+	scope        string // "" = pública (sem credencial nem tenant)
+	params       []param
+	body         string // schema do corpo JSON em components, "" sem corpo
+	upload       bool   // corpo é um arquivo CSV/NDJSON (cru ou multipart), não validado
+	stream       bool   // SSE/WebSocket: a resposta não é validada
+	responses    map[int]response
+}
+
+// byID adapta um handler que recebe o {id} do path.
+func byID(h func(*Server, http.ResponseWriter, *http.Request, string)) func(*Server, http.ResponseWriter, *http.Request) {
+	return func(s *Server, w http.ResponseWriter, r *http.Request) { h(s, w, r, r.PathValue("id")) }
+}
+
+type param struct {
+	name, in, desc string
+	schema         schema
+	required       bool
+}
+
+// response mapeia content type → schema em components ("" = texto livre).
+type response struct {
+	desc    string
+	content map[string]string
+}
+
+type schema = map[string]any
+
+func ref(name string) schema { return schema{"$ref": "#/components/schemas/" + name} }
+
+// Respostas comuns. Erros antigos (http.Error) ainda saem em text/plain.
+func jsonResp(desc, name string) response {
+	return response{desc, map[string]string{"application/json": name}}
+}
+
+func problemResp(desc string) response {
+	return response{desc, map[string]string{"application/problem+json": "Problem"}}
+}
+
+func textResp(desc string) response {
+	return response{desc, map[string]string{"text/plain": ""}}
+}
+
+func problemOrText(desc string) response {
+	return response{desc, map[string]string{"application/problem+json": "Problem", "text/plain": ""}}
+}
+
+func problemOrBatch(desc string) response {
+	return response{desc, map[string]string{"application/problem+json": "Problem", "application/json": "BatchResult"}}
+}
+
+// withErrors acrescenta as respostas que o pipeline de middlewares pode dar
+// em qualquer rota autenticada.
+func withErrors(rs map[int]response) map[int]response {
+	common := map[int]response{
+		http.StatusBadRequest:          problemOrText("Missing or invalid tenant, or malformed request"),
+		http.StatusUnauthorized:        problemResp("Missing or invalid credentials"),
+		http.StatusForbidden:           problemResp("Missing scope or resource of another customer/tenant"),
+		http.StatusTooManyRequests:     problemResp("Rate limit exceeded (see Retry-After)"),
+		http.StatusInternalServerError: textResp("Unexpected error"),
+	}
+	for code, r := range common {
+		if _, ok := rs[code]; !ok {
+			rs[code] = r
+		}
+	}
+	return rs
+}
+
+var (
+	idParam    = param{name: "id", in: "path", required: true, schema: schema{"type": "string"}}
+	limitParam = func(def, max int) param {
+		return param{name: "limit", in: "query", desc: "values outside 1.." + strconv.Itoa(max) + " use the default",
+			schema: schema{"type": "integer", "minimum": 1, "maximum": max, "default": def}}
+	}
+	lastEventParam = param{name: "Last-Event-ID", in: "header", desc: "resume after this event id (or ?lastEventId=)",
+		schema: schema{"type": "string", "pattern": "^[0-9]+$"}}
+)
+
+// orderFilters são os filtros comuns de GET /orders e /orders/stats.
+var orderFilters = []param{
+	{name: "status", in: "query", desc: "comma-separated, any of them (OPEN,PAID)", schema: schema{"type": "string"}},
+	{name: "customer", in: "query", desc: "partial match", schema: schema{"type": "string"}},
+	{name: "q", in: "query", desc: "full-text search on customer; every word must match, by prefix", schema: schema{"type": "string"}},
+	{name: "item", in: "query", desc: "some item contains this text", schema: schema{"type": "string"}},
+	{name: "sku", in: "query", desc: "comma-separated, some item is exactly one of them", schema: schema{"type": "string"}},
+	{name: "since", in: "query", desc: "created at or after", schema: schema{"type": "string", "format": "date-time"}},
+	{name: "until", in: "query", desc: "created at or before", schema: schema{"type": "string", "format": "date-time"}},
+	{name: "updated_since", in: "query", desc: "updated at or after", schema: schema{"type": "string", "format": "date-time"}},
+}
+
+var sortParam = param{name: "sort", in: "query", desc: "comma-separated createdAt, updatedAt, customer, status; - for descending (default -createdAt)",
+	schema: schema{"type": "string", "pattern": "^-?(createdAt|updatedAt|customer|status)(,-?(createdAt|updatedAt|customer|status))*$"}}
+
+func withFilters(extra ...param) []param {
+	return append(append([]param{}, orderFilters...), extra...)
+}
+
+var apiOperations = []operation{
+	{method: "GET", path: "/health", handle: (*Server).handleHealth, tag: "probes", summary: "Legacy health check (always ok)",
+		responses: map[int]response{200: jsonResp("OK", "Health")}},
+	{method: "GET", path: "/livez", handle: (*Server).handleLivez, tag: "probes", summary: "Liveness probe",
+		responses: map[int]response{200: jsonResp("Process is up", "Status")}},
+	{method: "GET", path: "/readyz", handle: (*Server).handleReadyz, tag: "probes", summary: "Readiness probe (MySQL and Kafka)",
+		responses: map[int]response{
+			200: jsonResp("Ready", "Ready"),
+			503: jsonResp("Not ready or shutting down", "Ready"),
+		}},
+	{method: "GET", path: "/metrics", handle: (*Server).handleMetrics, tag: "probes", summary: "Prometheus metrics",
+		responses: map[int]response{200: {"Prometheus exposition format (or OpenMetrics, by Accept)",
+			map[string]string{"text/plain": "", "application/openmetrics-text": ""}}}},
+	{method: "GET", path: "/openapi.json", handle: (*Server).handleOpenAPI, tag: "probes", summary: "This document",
+		responses: map[int]response{200: jsonResp("OpenAPI 3.1 document", "")}},
+
+	{method: "POST", path: "/orders", handle: (*Server).handleCreateOrder, tag: "orders", scope: auth.ScopeWrite, summary: "Create an order",
+		body: "CreateOrderRequest",
+		responses: withErrors(map[int]response{
+			201: jsonResp("Created; OrderCreated published", "OrderCreated"),
+			413: problemResp("Body larger than the route limit"),
+			422: problemResp("Too many or too long items"),
+			503: textResp("Saved, but Kafka is unavailable; the event stays in the outbox"),
+		})},
+	{method: "GET", path: "/orders", handle: (*Server).handleListOrders, tag: "orders", scope: auth.ScopeRead, summary: "List, filter, search and sort orders",
+		params: withFilters(
+			sortParam,
+			limitParam(50, 500),
+			param{name: "offset", in: "query", schema: schema{"type": "integer", "minimum": 0, "default": 0}},
+		),
+		responses: withErrors(map[int]response{200: jsonResp("A page of orders", "OrderList")})},
+	{method: "GET", path: "/orders/stats", handle: (*Server).handleOrderStats, tag: "orders", scope: auth.ScopeRead,
+		summary: "Counts by status, orders per day/hour, top customers and average time in each status",
+		params: withFilters(
+			param{name: "interval", in: "query", desc: "series buckets (at most 1000 between since and until)",
+				schema: schema{"enum": []string{"day", "hour"}, "default": "day"}},
+			param{name: "top", in: "query", desc: "how many top customers",
+				schema: schema{"type": "integer", "minimum": 1, "maximum": 100, "default": 10}},
+		),
+		responses: withErrors(map[int]response{200: jsonResp("Aggregates of the filtered orders", "OrderStats")})},
+	{method: "GET", path: "/orders/export", handle: (*Server).handleOrderExport, tag: "orders", scope: auth.ScopeRead, stream: true,
+		summary: "Every filtered order from one consistent snapshot, streamed as NDJSON or CSV (by Accept, gzip by Accept-Encoding)",
+		params:  withFilters(sortParam),
+		responses: withErrors(map[int]response{
+			200: {"One order per line; X-Snapshot-At is the instant of the snapshot",
+				map[string]string{"application/x-ndjson": "", "text/csv": ""}},
+			406: problemResp("Accept allows neither NDJSON nor CSV"),
+		})},
+	{method: "GET", path: "/orders/{id}", handle: byID((*Server).handleGetOrder), tag: "orders", scope: auth.ScopeRead, summary: "Get an order",
+		params: []param{idParam},
+		responses: withErrors(map[int]response{
+			200: jsonResp("The order", "Order"),
+			404: textResp("Unknown order"),
+		})},
+	{method: "PUT", path: "/orders/{id}/status", handle: byID((*Server).handleUpdateStatus), tag: "orders", scope: auth.ScopeWrite, summary: "Change the status of an order",
+		params: []param{idParam}, body: "UpdateStatusRequest",
+		responses: withErrors(map[int]response{
+			200: jsonResp("Updated; OrderStatusUpdated published", "StatusUpdated"),
+			404: textResp("Unknown order"),
+			413: problemResp("Body larger than the route limit"),
+			503: textResp("Saved, but Kafka is unavailable; the event stays in the outbox"),
+		})},
+	{method: "POST", path: "/orders:batch", handle: (*Server).handleBatchCreate, tag: "orders", scope: auth.ScopeWrite,
+		summary: "Create many orders in one transaction (atomic) or with per-entry results (partial)",
+		body:    "BatchCreateRequest",
+		responses: withErrors(map[int]response{
+			201: jsonResp("All created; events published in one batch", "BatchResult"),
+			207: jsonResp("Partial mode with failures, or saved entries whose event stays in the outbox", "BatchResult"),
+			413: problemResp("Body or number of entries larger than the route limit"),
+			422: problemOrBatch("Empty batch, or atomic batch rolled back (per-entry results)"),
+		})},
+	{method: "PUT", path: "/orders/status:batch", handle: (*Server).handleBatchStatus, tag: "orders", scope: auth.ScopeWrite,
+		summary: "Change the status of many orders in one transaction (atomic) or with per-entry results (partial)",
+		body:    "BatchStatusRequest",
+		responses: withErrors(map[int]response{
+			200: jsonResp("All updated; events published in one batch", "BatchResult"),
+			207: jsonResp("Partial mode with failures, or saved entries whose event stays in the outbox", "BatchResult"),
+			413: problemResp("Body or number of entries larger than the route limit"),
+			422: problemOrBatch("Empty batch, or atomic batch rolled back (per-entry results)"),
+		})},
+
+	{method: "POST", path: "/imports", handle: (*Server).handleCreateImport, tag: "imports", scope: auth.ScopeWrite,
+		summary: "Upload a CSV or NDJSON file of orders, processed in the background",
+		upload:  true,
+		responses: withErrors(map[int]response{
+			202: jsonResp("Queued; follow the Location header", "Import"),
+			413: problemResp("Body or number of rows larger than the limit"),
+			415: problemResp("Not a CSV or NDJSON file"),
+			422: problemResp("Empty file, CSV without customer/items columns or unreadable file"),
+		})},
+	{method: "GET", path: "/imports/{id}", handle: byID((*Server).handleGetImport), tag: "imports", scope: auth.ScopeRead,
+		summary: "Progress of an import and its first rejected rows",
+		params:  []param{idParam},
+		responses: withErrors(map[int]response{
+			200: jsonResp("The import", "Import"),
+			404: textResp("Unknown import"),
+		})},
+	{method: "GET", path: "/imports/{id}/rejects", handle: byID((*Server).handleImportRejects), tag: "imports", scope: auth.ScopeRead, stream: true,
+		summary: "Rejected rows in the format of the upload, with line and error",
+		params:  []param{idParam},
+		responses: withErrors(map[int]response{
+			200: {"Rejected rows, ready to fix and upload again", map[string]string{exportNDJSON: "", exportCSV: ""}},
+			404: textResp("Unknown import"),
+		})},
+	{method: "POST", path: "/customers", handle: (*Server).handleCreateCustomer, tag: "customers", scope: auth.ScopeWrite, summary: "Create a customer",
+		body: "CustomerRequest",
+		responses: withErrors(map[int]response{
+			201: jsonResp("Created; CustomerCreated published", "Customer"),
+			409: problemResp("A customer with this name already exists"),
+			413: problemResp("Body larger than the route limit"),
+			422: problemResp("Missing or too long name, or invalid email"),
+			503: textResp("Saved, but Kafka is unavailable; the event stays in the outbox"),
+		})},
+	{method: "GET", path: "/customers", handle: (*Server).handleListCustomers, tag: "customers", scope: auth.ScopeRead, summary: "List customers by name",
+		params: []param{
+			limitParam(50, 500),
+			{name: "offset", in: "query", schema: schema{"type": "integer", "minimum": 0, "default": 0}},
+		},
+		responses: withErrors(map[int]response{200: jsonResp("A page of customers", "CustomerList")})},
+	{method: "GET", path: "/customers/{id}", handle: byID((*Server).handleGetCustomer), tag: "customers", scope: auth.ScopeRead, summary: "Get a customer",
+		params: []param{idParam},
+		responses: withErrors(map[int]response{
+			200: jsonResp("The customer", "Customer"),
+			404: textResp("Unknown customer"),
+		})},
+	{method: "PUT", path: "/customers/{id}", handle: byID((*Server).handleUpdateCustomer), tag: "customers", scope: auth.ScopeWrite,
+		summary: "Replace name and email; a new name is copied to the customer's orders",
+		params:  []param{idParam}, body: "CustomerRequest",
+		responses: withErrors(map[int]response{
+			200: jsonResp("Updated; CustomerUpdated published", "Customer"),
+			404: textResp("Unknown customer"),
+			409: problemResp("Another customer already has this name"),
+			413: problemResp("Body larger than the route limit"),
+			422: problemResp("Missing or too long name, or invalid email"),
+			503: textResp("Saved, but Kafka is unavailable; the event stays in the outbox"),
+		})},
+	{method: "DELETE", path: "/customers/{id}", handle: byID((*Server).handleDeleteCustomer), tag: "customers", scope: auth.ScopeWrite, summary: "Delete a customer without orders",
+		params: []param{idParam},
+		responses: withErrors(map[int]response{
+			204: {desc: "Deleted"},
+			404: textResp("Unknown customer"),
+			409: problemResp("The customer has orders"),
+		})},
+	{method: "GET", path: "/customers/{id}/orders", handle: byID((*Server).handleCustomerOrders), tag: "customers", scope: auth.ScopeRead,
+		summary: "Orders of a customer, with the filters and sort of GET /orders",
+		params: withFilters(
+			idParam,
+			sortParam,
+			limitParam(50, 500),
+			param{name: "offset", in: "query", schema: schema{"type": "integer", "minimum": 0, "default": 0}},
+		),
+		responses: withErrors(map[int]response{
+			200: jsonResp("A page of the customer's orders", "OrderList"),
+			404: textResp("Unknown customer"),
+		})},
+
+	{method: "GET", path: "/orders/stream", handle: byID((*Server).handleStream), tag: "streams", scope: auth.ScopeRead, stream: true,
+		summary: "Server-Sent Events of every order of the tenant",
+		params:  []param{lastEventParam},
+		responses: withErrors(map[int]response{
+			200: {"Event stream (id = outbox id, event = event type)", map[string]string{"text/event-stream": ""}},
+			503: problemResp("Event bus not configured"),
+		})},
+	{method: "GET", path: "/orders/{id}/stream", handle: byID((*Server).handleStream), tag: "streams", scope: auth.ScopeRead, stream: true,
+		summary: "Server-Sent Events of one order",
+		params:  []param{idParam, lastEventParam},
+		responses: withErrors(map[int]response{
+			200: {"Event stream (id = outbox id, event = event type)", map[string]string{"text/event-stream": ""}},
+			404: textResp("Unknown order"),
+			503: problemResp("Event bus not configured"),
+		})},
+
+	{method: "POST", path: "/graphql", handle: (*Server).handleGraphQL, tag: "graphql", scope: auth.ScopeRead,
+		summary: "GraphQL queries and mutations (mutations also require orders:write)",
+		body:    "GraphQLRequest",
+		responses: withErrors(map[int]response{
+			200: jsonResp("GraphQL result; errors carry extensions.code", "GraphQLResponse"),
+			413: problemResp("Body larger than the route limit"),
+		})},
+	{method: "GET", path: "/graphql", handle: (*Server).handleGraphQL, tag: "graphql", scope: auth.ScopeRead, stream: true,
+		summary: "WebSocket upgrade for subscriptions (subprotocol graphql-transport-ws)",
+		responses: withErrors(map[int]response{
+			101: {desc: "Switching Protocols"},
+			405: textResp("Not a WebSocket upgrade"),
+		})},
+
+	{method: "POST", path: "/webhooks", handle: (*Server).handleCreateWebhook, tag: "webhooks", scope: auth.ScopeAdmin, summary: "Subscribe a webhook",
+		body: "CreateWebhookRequest",
+		responses: withErrors(map[int]response{
+			201: jsonResp("Created; the secret is only shown here", "CreatedWebhook"),
+			413: problemResp("Body larger than the route limit"),
+			422: problemResp("Invalid URL, event type or secret"),
+		})},
+	{method: "GET", path: "/webhooks", handle: (*Server).handleListWebhooks, tag: "webhooks", scope: auth.ScopeAdmin, summary: "List the webhooks of the tenant",
+		responses: withErrors(map[int]response{200: jsonResp("Webhooks", "WebhookList")})},
+	{method: "GET", path: "/webhooks/{id}", handle: byID((*Server).handleGetWebhook), tag: "webhooks", scope: auth.ScopeAdmin, summary: "Get a webhook",
+		params: []param{idParam},
+		responses: withErrors(map[int]response{
+			200: jsonResp("The webhook", "Webhook"),
+			404: textResp("Unknown webhook"),
+		})},
+	{method: "DELETE", path: "/webhooks/{id}", handle: byID((*Server).handleDeleteWebhook), tag: "webhooks", scope: auth.ScopeAdmin, summary: "Delete a webhook",
+		params: []param{idParam},
+		responses: withErrors(map[int]response{
+			204: {desc: "Deleted"},
+			404: textResp("Unknown webhook"),
+		})},
+	{method: "GET", path: "/webhooks/{id}/deliveries", handle: byID((*Server).handleWebhookDeliveries), tag: "webhooks", scope: auth.ScopeAdmin,
+		summary: "Delivery log of a webhook, newest first",
+		params:  []param{idParam, limitParam(50, 500)},
+		responses: withErrors(map[int]response{
+			200: jsonResp("Deliveries", "DeliveryList"),
+			404: textResp("Unknown webhook"),
+		})},
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Schemas. Respostas são fechadas (additionalProperties: false) para um
+// campo novo no handler aparecer como drift. Requests só descrevem a forma
+// (aceitam campos extras, como o decoder JSON): regras de domínio continuam
+// nos handlers, com 422.
+// ──────────────────────────────────────────────────────────────────────────────
+
+func closed(required []string, props schema) schema {
+	s := loose(required, props)
+	s["additionalProperties"] = false
+	return s
+}
+
+// withAnyOf exige ao menos um dos conjuntos de campos.
+func withAnyOf(s schema, alternatives ...[]string) schema {
+	var alts []schema
+	for _, req := range alternatives {
+		alts = append(alts, schema{"required": req})
+	}
+	s["anyOf"] = alts
+	return s
+}
+
+func loose(required []string, props schema) schema {
+	s := schema{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+var (
+	str      = schema{"type": "string"}
+	integer  = schema{"type": "integer"}
+	dateTime = schema{"type": "string", "format": "date-time"}
+	strList  = schema{"type": "array", "items": str}
+
+	batchMode    = schema{"enum": []string{"atomic", "partial"}}
+	batchModeReq = schema{"type": "string", "description": "atomic (default) or partial"}
+)
+
+var apiSchemas = map[string]schema{
+	"Problem": closed([]string{"type", "title", "status"}, schema{
+		"type":      str,
+		"title":     str,
+		"status":    integer,
+		"detail":    str,
+		"instance":  str,
+		"requestId": str,
+	}),
+	"Health": closed([]string{"ok"}, schema{"ok": schema{"type": "boolean"}}),
+	"Status": closed([]string{"status"}, schema{"status": str}),
+	"Ready": closed([]string{"status"}, schema{
+		"status": schema{"enum": []string{"ok", "fail"}},
+		"reason": str,
+		"checks": schema{"type": "object", "additionalProperties": closed([]string{"status", "latencyMs"}, schema{
+			"status":    schema{"enum": []string{"ok", "fail"}},
+			"latencyMs": schema{"type": "number"},
+			"error":     str,
+		})},
+	}),
+
+	"CreateOrderRequest": withAnyOf(loose([]string{"items"}, schema{
+		"customer":   schema{"type": "string", "description": "customer name; created on first use when customerId is absent"},
+		"customerId": str,
+		"items":      strList,
+	}), []string{"customer"}, []string{"customerId"}),
+	"OrderCreated": closed([]string{"id", "tenantId", "customer", "customerId", "items", "status"}, schema{
+		"id":         str,
+		"tenantId":   str,
+		"customer":   str,
+		"customerId": str,
+		"items":      strList,
+		"status":     str,
+	}),
+	"Order": closed([]string{"id", "tenantId", "customer", "customerId", "status", "items", "createdAt", "updatedAt"}, schema{
+		"id":         str,
+		"tenantId":   str,
+		"customer":   str,
+		"customerId": str,
+		"status":     str,
+		"items":      strList,
+		"createdAt":  dateTime,
+		"updatedAt":  dateTime,
+		"createdBy":  schema{"type": "string", "description": "authenticated subject"},
+		"updatedBy":  str,
+	}),
+	"OrderList": closed([]string{"items", "limit", "offset", "count"}, schema{
+		"items":  schema{"type": "array", "items": ref("Order")},
+		"limit":  integer,
+		"offset": integer,
+		"count":  integer,
+	}),
+	"OrderStats": closed([]string{"total", "byStatus", "interval", "from", "to", "series", "topCustomers", "timeInStatus"}, schema{
+		"total":    integer,
+		"byStatus": schema{"type": "object", "additionalProperties": integer},
+		"interval": schema{"enum": []string{"day", "hour"}},
+		"from":     dateTime,
+		"to":       dateTime,
+		"series": schema{"type": "array", "items": closed([]string{"start", "count"}, schema{
+			"start": dateTime, "count": integer,
+		})},
+		"topCustomers": schema{"type": "array", "items": closed([]string{"customer", "count"}, schema{
+			"customer": str, "count": integer,
+		})},
+		"timeInStatus": schema{"type": "array", "items": closed([]string{"status", "avgSeconds", "samples"}, schema{
+			"status": str, "avgSeconds": schema{"type": "number"}, "samples": integer,
+		})},
+	}),
+	"UpdateStatusRequest": loose([]string{"status"}, schema{"status": str}),
+	"StatusUpdated":       closed([]string{"id", "status"}, schema{"id": str, "status": str}),
+
+	"BatchCreateRequest": loose([]string{"orders"}, schema{
+		"mode":   batchModeReq,
+		"orders": schema{"type": "array", "items": ref("CreateOrderRequest")},
+	}),
+	"BatchStatusRequest": loose([]string{"updates"}, schema{
+		"mode": batchModeReq,
+		"updates": schema{"type": "array", "items": loose([]string{"id", "status"}, schema{
+			"id": str, "status": str,
+		})},
+	}),
+	"BatchResult": closed([]string{"mode", "succeeded", "failed", "results"}, schema{
+		"mode":      batchMode,
+		"succeeded": integer,
+		"failed":    integer,
+		"results": schema{"type": "array", "items": closed([]string{"index", "status"}, schema{
+			"index":  integer,
+			"status": schema{"type": "integer", "description": "what the entry alone would have answered; 424 when rolled back because of another"},
+			"id":     str,
+			"error":  closed([]string{"title"}, schema{"title": str, "detail": str}),
+		})},
+	}),
+
+	"CustomerRequest": loose([]string{"name"}, schema{
+		"name":  str,
+		"email": str,
+	}),
+	"Customer": closed([]string{"id", "tenantId", "name", "createdAt", "updatedAt"}, schema{
+		"id":        str,
+		"tenantId":  str,
+		"name":      schema{"type": "string", "description": "unique in the tenant, case-insensitive"},
+		"email":     str,
+		"createdAt": dateTime,
+		"updatedAt": dateTime,
+		"createdBy": str,
+		"updatedBy": str,
+	}),
+	"CustomerList": closed([]string{"items", "limit", "offset", "count"}, schema{
+		"items":  schema{"type": "array", "items": ref("Customer")},
+		"limit":  integer,
+		"offset": integer,
+		"count":  integer,
+	}),
+
+	"Import": closed([]string{"id", "tenantId", "status", "format", "totalRows", "processedRows", "createdRows",
+		"rejectedRows", "createdAt", "updatedAt", "rejects"}, schema{
+		"id":            str,
+		"tenantId":      str,
+		"status":        schema{"enum": []string{"queued", "running", "completed", "failed"}},
+		"format":        schema{"enum": []string{"csv", "ndjson"}},
+		"filename":      str,
+		"totalRows":     integer,
+		"processedRows": integer,
+		"createdRows":   integer,
+		"rejectedRows":  integer,
+		"error":         schema{"type": "string", "description": "why a failed import stopped"},
+		"createdAt":     dateTime,
+		"updatedAt":     dateTime,
+		"startedAt":     dateTime,
+		"finishedAt":    dateTime,
+		"createdBy":     str,
+		"rejects": schema{"type": "array", "items": closed([]string{"line", "error"}, schema{
+			"line": integer, "error": str,
+		})},
+		"rejectsUrl": str,
+	}),
+
+	"GraphQLRequest": loose([]string{"query"}, schema{
+		"query":         str,
+		"operationName": schema{"type": []string{"string", "null"}},
+		"variables":     schema{"type": []string{"object", "null"}},
+	}),
+	"GraphQLResponse": closed(nil, schema{
+		"data":       schema{"type": []string{"object", "null"}},
+		"errors":     schema{"type": "array", "items": schema{"type": "object", "required": []string{"message"}}},
+		"extensions": schema{"type": "object"},
+	}),
+
+	"CreateWebhookRequest": loose([]string{"url", "eventTypes"}, schema{
+		"url":        schema{"type": "string", "description": "http or https URL"},
+		"eventTypes": schema{"type": "array", "items": str, "description": "OrderCreated, OrderStatusUpdated, CustomerCreated and/or CustomerUpdated"},
+		"secret":     schema{"type": "string", "description": "at least 16 characters; generated when absent"},
+	}),
+	"Webhook":        closed(webhookRequired, webhookProps(false)),
+	"CreatedWebhook": closed(append([]string{"secret"}, webhookRequired...), webhookProps(true)),
+	"WebhookList":    schema{"type": "array", "items": ref("Webhook")},
+	"Delivery": closed([]string{"id", "webhookId", "eventId", "eventType", "status", "attempts", "createdAt", "updatedAt"}, schema{
+		"id":             integer,
+		"webhookId":      str,
+		"eventId":        integer,
+		"eventType":      str,
+		"status":         schema{"enum": []string{"pending", "succeeded", "failed"}},
+		"attempts":       integer,
+		"nextAttemptAt":  dateTime,
+		"lastStatusCode": integer,
+		"lastError":      str,
+		"createdAt":      dateTime,
+		"updatedAt":      dateTime,
+		"deliveredAt":    dateTime,
+	}),
+	"DeliveryList": schema{"type": "array", "items": ref("Delivery")},
+}
+
+var webhookRequired = []string{"id", "tenantId", "url", "eventTypes", "active", "createdAt"}
+
+func webhookProps(withSecret bool) schema {
+	p := schema{
+		"id":         str,
+		"tenantId":   str,
+		"url":        str,
+		"eventTypes": strList,
+		"active":     schema{"type": "boolean"},
+		"createdAt":  dateTime,
+		"createdBy":  str,
+	}
+	if withSecret {
+		p["secret"] = str
+	}
+	return p
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Documento
+// ──────────────────────────────────────────────────────────────────────────────
+
+// OpenAPIDocument gera o documento (JSON) da API; tenantHeader é o header de
+// tenant configurado (tenancy.header).
+func OpenAPIDocument(tenantHeader string) []byte {
+	b, err := json.MarshalIndent(openAPIDoc(tenantHeader), "", "  ")
+	if err != nil {
+		panic(err) // só mapas e tipos básicos
+	}
+	return b
+}
+
+func openAPIDoc(tenantHeader string) schema {
+	paths := schema{}
+	for _, op := range apiOperations {
+		item, _ := paths[op.path].(schema)
+		if item == nil {
+			item = schema{}
+			paths[op.path] = item
+		}
+		item[strings.ToLower(op.method)] = op.document(tenantHeader)
+	}
+	return schema{
+		"openapi": "3.1.0",
+		"info": schema{
+			"title":       "orders-api",
+			"version":     "1.0.0",
+			"description": "Orders, their event streams and webhooks. Generated from the route table of the server.",
+		},
+		"tags": []schema{
+			{"name": "orders"}, {"name": "customers"}, {"name": "imports"}, {"name": "streams"}, {"name": "graphql"}, {"name": "webhooks"}, {"name": "probes"},
+		},
+		"paths": paths,
+		"components": schema{
+			"schemas": apiSchemas,
+			"securitySchemes": schema{
+				"apiKey": schema{"type": "apiKey", "in": "header", "name": "X-Api-Key"},
+				"bearer": schema{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+func (op operation) document(tenantHeader string) schema {
+	out := schema{
+		"operationId": op.id(),
+		"summary":     op.summary,
+		"tags":        []string{op.tag},
+	}
+
+	params := op.params
+	if op.scope != "" {
+		params = append([]param{{name: tenantHeader, in: "header", desc: "tenant; optional when a default is configured",
+			schema: schema{"type": "string"}}}, params...)
+		out["security"] = []schema{{"apiKey": []string{op.scope}}, {"bearer": []string{op.scope}}}
+	} else {
+		out["security"] = []schema{}
+	}
+	if len(params) > 0 {
+		ps := make([]schema, 0, len(params))
+		for _, p := range params {
+			d := schema{"name": p.name, "in": p.in, "schema": p.schema}
+			if p.required {
+				d["required"] = true
+			}
+			if p.desc != "" {
+				d["description"] = p.desc
+			}
+			ps = append(ps, d)
+		}
+		out["parameters"] = ps
+	}
+	if op.body != "" {
+		out["requestBody"] = schema{
+			"required": true,
+			"content":  schema{"application/json": schema{"schema": ref(op.body)}},
+		}
+	}
+	if op.upload {
+		out["requestBody"] = schema{
+			"required": true,
+			"content": schema{
+				"multipart/form-data": schema{"schema": loose([]string{"file"}, schema{
+					"file": schema{"type": "string", "description": "CSV with customer and items columns, or NDJSON"},
+				})},
+				exportCSV:    schema{"schema": str},
+				exportNDJSON: schema{"schema": str},
+			},
+		}
+	}
+
+	codes := make([]int, 0, len(op.responses))
+	for code := range op.responses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	responses := schema{}
+	for _, code := range codes {
+		r := op.responses[code]
+		d := schema{"description": r.desc}
+		if len(r.content) > 0 {
+			content := schema{}
+			for media, name := range r.content {
+				switch {
+				case name != "":
+					content[media] = schema{"schema": ref(name)}
+				case media == "application/json":
+					content[media] = schema{"schema": schema{"type": "object"}}
+				default:
+					content[media] = schema{"schema": str}
+				}
+			}
+			d["content"] = content
+		}
+		responses[strconv.Itoa(code)] = d
+	}
+	out["responses"] = responses
+	return out
+}
+
+// id: "GET /orders/{id}/status" → "getOrdersIdStatus", "POST /orders:batch"
+// → "postOrdersBatch".
+func (op operation) id() string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(op.method))
+	for _, part := range strings.FieldsFunc(op.path, func(r rune) bool { return r == '/' || r == '{' || r == '}' || r == '.' || r == ':' }) {
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
@@ -0,0 +1,465 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+
+	"orders-api/auth"
+	"orders-api/config"
+	"orders-api/metrics"
+	"orders-api/outbox"
+	"orders-api/store"
+	"orders-api/tenant"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Operações de pedido, compartilhadas pelas APIs HTTP e gRPC. Identidade,
+// tenant e request id vêm do contexto; cada transporte traduz os erros
+// abaixo para o seu código de status.
+// ──────────────────────────────────────────────────────────────────────────────
+
+// invalidError é entrada recusada pelas regras do domínio (422 / InvalidArgument).
+type invalidError struct {
+	title, detail string
+}
+
+func (e *invalidError) Error() string { return e.title + ": " + e.detail }
+
+// deniedError é acesso negado a um pedido (403 / PermissionDenied); attrs
+// vão para a linha de auditoria.
+type deniedError struct {
+	reason string
+	attrs  []any
+}
+
+func (e *deniedError) Error() string { return "forbidden: " + e.reason }
+
+// conflictError é escrita recusada pelo estado atual (409).
+type conflictError struct {
+	title, detail string
+}
+
+func (e *conflictError) Error() string { return e.title + ": " + e.detail }
+
+func denied(reason string, attrs ...any) error {
+	return &deniedError{reason: reason, attrs: attrs}
+}
+
+// errPublish: a gravação foi feita, mas o Kafka falhou e o evento ficou no
+// outbox para o relay (503 / Unavailable).
+var errPublish = errors.New("kafka unavailable")
+
+// orderQuery são os filtros da listagem (GET /orders, ListOrders).
+type orderQuery struct {
+	Status       []string // qualquer um deles
+	Customer     string   // busca parcial
+	CustomerID   string   // GET /customers/{id}/orders
+	Search       string   // full-text em customer
+	Item         string   // algum item contém o texto
+	SKU          []string // algum item é exatamente um deles
+	Since, Until time.Time
+	UpdatedSince time.Time
+	Sort         []orderSort // vazio: created_at DESC
+	Limit        int
+	Offset       int
+	After        *orderCursor // keyset (GraphQL): só pedidos depois deste, na ordem padrão
+}
+
+// orderSort é uma chave de ordenação já validada contra orderSortColumns.
+type orderSort struct {
+	Column string
+	Desc   bool
+}
+
+// orderSortColumns: campos aceitos em ?sort= → coluna.
+var orderSortColumns = map[string]string{
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"customer":  "customer",
+	"status":    "status",
+}
+
+// orderCursor é a posição de um pedido na ordem da listagem.
+type orderCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// normalize aplica o padrão e os tetos de paginação.
+func (q *orderQuery) normalize() {
+	if q.Limit <= 0 || q.Limit > 500 {
+		q.Limit = 50
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+}
+
+func (s *Server) createOrder(ctx context.Context, req createReq) (store.Order, error) {
+	o, err := s.newOrder(ctx, req, s.limits.For("POST /orders"))
+	if err != nil {
+		return store.Order{}, err
+	}
+	// pedido + eventos na mesma transação (outbox)
+	evs, err := s.inTx(ctx, func(tx *sql.Tx) ([]store.OutboxEvent, error) {
+		return s.insertOrder(ctx, tx, &o)
+	})
+	if err != nil {
+		return store.Order{}, err
+	}
+	metrics.OrdersCreated.Inc()
+	return o, s.publishAll(ctx, evs, o.ID)
+}
+
+// newOrder valida o pedido contra os limites da rota e o principal, e
+// monta o registro ainda não gravado.
+func (s *Server) newOrder(ctx context.Context, req createReq, lim config.RouteLimits) (store.Order, error) {
+	if err := checkItems(req.Items, lim); err != nil {
+		return store.Order{}, &invalidError{"Invalid order", err.Error()}
+	}
+	// o nome vira (ou encontra) um cliente: mesma normalização de /customers
+	req.Customer = strings.TrimSpace(req.Customer)
+	if req.Customer == "" && req.CustomerID == "" {
+		return store.Order{}, &invalidError{"Invalid order", "customer or customerId is required"}
+	}
+	if req.Customer != "" {
+		name, err := customerName(req.Customer)
+		if err != nil {
+			return store.Order{}, &invalidError{"Invalid order", "customer: " + err.Error()}
+		}
+		req.Customer = name
+	}
+	// com customerId (ou principal preso pelo id) quem confere é o
+	// linkCustomer, que já sabe o cliente
+	if p, ok := auth.FromContext(ctx); ok && req.Customer != "" && p.CustomerID == "" && !p.CanAccess(req.Customer, "") {
+		return store.Order{}, denied("cannot create orders for another customer", "customer", req.Customer)
+	}
+	now := time.Now().UTC()
+	sub := auth.Subject(ctx)
+	return store.Order{
+		ID: newID(), TenantID: tenant.FromContext(ctx), Customer: req.Customer, CustomerID: req.CustomerID,
+		Status: "OPEN", Items: req.Items,
+		CreatedAt: now, UpdatedAt: now, CreatedBy: sub, UpdatedBy: sub,
+	}, nil
+}
+
+// insertOrder liga o pedido ao cliente, grava o pedido, a primeira entrada
+// do histórico e o evento OrderCreated dentro de tx. Um nome ainda sem
+// cliente cria o cliente, e o CustomerCreated vem antes do OrderCreated.
+func (s *Server) insertOrder(ctx context.Context, tx *sql.Tx, o *store.Order) ([]store.OutboxEvent, error) {
+	evs, err := s.linkCustomer(ctx, tx, o)
+	if err != nil {
+		return nil, err
+	}
+	evt := map[string]any{
+		"type":       "OrderCreated",
+		"id":         o.ID,
+		"tenantId":   o.TenantID,
+		"customer":   o.Customer,
+		"customerId": o.CustomerID,
+		"status":     o.Status,
+		"items":      o.Items,
+		"ts":         o.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if err := store.InsertOrder(ctx, tx, *o); err != nil {
+		return nil, err
+	}
+	if err := store.RecordStatus(ctx, tx, o.TenantID, o.ID, o.Status, o.CreatedBy, o.CreatedAt); err != nil {
+		return nil, err
+	}
+	ev, err := store.EnqueueEvent(ctx, tx, s.outboxEvent(ctx, o.ID, "OrderCreated"), evt)
+	if err != nil {
+		return nil, err
+	}
+	return append(evs, ev), nil
+}
+
+// linkCustomer resolve o cliente do pedido: pelo customerId (que precisa
+// existir e, com nome junto, bater com ele) ou pelo nome, para os clientes
+// antigos, criando o cliente na primeira vez. o.Customer fica com o nome
+// cadastrado. Nada é gravado antes de um erro de domínio.
+func (s *Server) linkCustomer(ctx context.Context, tx *sql.Tx, o *store.Order) ([]store.OutboxEvent, error) {
+	var evs []store.OutboxEvent
+	if o.CustomerID != "" {
+		c, err := store.LockCustomer(ctx, tx, o.TenantID, o.CustomerID, false)
+		if store.IsNotFound(err) {
+			return nil, &invalidError{"Unknown customer", "customer " + o.CustomerID + " does not exist"}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if o.Customer != "" && !strings.EqualFold(o.Customer, c.Name) {
+			return nil, &invalidError{"Invalid order", "customer does not match the name of customerId"}
+		}
+		o.Customer = c.Name
+	} else {
+		c, created, err := store.EnsureCustomer(ctx, tx, store.Customer{
+			ID: newID(), TenantID: o.TenantID, Name: o.Customer,
+			CreatedAt: o.CreatedAt, UpdatedAt: o.CreatedAt, CreatedBy: o.CreatedBy, UpdatedBy: o.CreatedBy,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			ev, err := s.enqueueCustomerEvent(ctx, tx, c, "CustomerCreated")
+			if err != nil {
+				return nil, err
+			}
+			evs = append(evs, ev)
+		}
+		o.CustomerID, o.Customer = c.ID, c.Name
+	}
+	if p, ok := auth.FromContext(ctx); ok && !p.CanAccess(o.Customer, o.CustomerID) {
+		return nil, denied("cannot create orders for another customer", "customer", o.Customer)
+	}
+	return evs, nil
+}
+
+func (s *Server) getOrder(ctx context.Context, id string) (store.Order, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+store.OrderColumns+` FROM orders WHERE id=? AND tenant_id=?`,
+		id, tenant.FromContext(ctx))
+	o, err := store.ScanOrder(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Order{}, store.ErrNotFound
+	}
+	if err != nil {
+		return store.Order{}, err
+	}
+	if p, ok := auth.FromContext(ctx); ok && !p.CanAccess(o.Customer, o.CustomerID) {
+		return store.Order{}, denied("order belongs to another customer", "order_id", id)
+	}
+	one := []store.Order{*o}
+	if err := store.LoadOrderItems(ctx, s.db, one); err != nil {
+		return store.Order{}, err
+	}
+	return one[0], nil
+}
+
+func (s *Server) listOrders(ctx context.Context, q orderQuery) ([]store.Order, error) {
+	q.normalize()
+	where, args := q.where(ctx)
+
+	query := "SELECT " + store.OrderColumns + " FROM orders WHERE " + where + " ORDER BY " + q.orderBy() + " LIMIT ? OFFSET ?"
+	args = append(args, q.Limit, q.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	list, err := store.ScanOrders(rows)
+	rows.Close() // libera a conexão antes da consulta dos itens
+	if err != nil {
+		return nil, err
+	}
+	return list, store.LoadOrderItems(ctx, s.db, list)
+}
+
+// orderBy são as chaves de ?sort= (padrão created_at DESC) mais o id, que
+// desempata de forma estável para a paginação.
+func (q orderQuery) orderBy() string {
+	var sb strings.Builder
+	for _, o := range q.Sort {
+		sb.WriteString(o.Column)
+		if o.Desc {
+			sb.WriteString(" DESC")
+		}
+		sb.WriteString(", ")
+	}
+	if len(q.Sort) == 0 {
+		sb.WriteString("created_at DESC, ")
+	}
+	sb.WriteString("id DESC")
+	return sb.String()
+}
+
+// where monta os filtros da consulta sobre a tabela orders (listagem e
+// estatísticas), sempre a partir do tenant e do cliente do principal.
+func (q orderQuery) where(ctx context.Context) (string, []any) {
+	// isolamento: toda consulta começa pelo tenant do request
+	conds := []string{"tenant_id = ?"}
+	args := []any{tenant.FromContext(ctx)}
+	if len(q.Status) > 0 {
+		conds = append(conds, "status IN (?"+strings.Repeat(", ?", len(q.Status)-1)+")")
+		for _, st := range q.Status {
+			args = append(args, st)
+		}
+	}
+	if q.CustomerID != "" {
+		conds = append(conds, "customer_id = ?")
+		args = append(args, q.CustomerID)
+	}
+	if q.Customer != "" {
+		conds = append(conds, "customer LIKE ?")
+		args = append(args, "%"+likeEscape(q.Customer)+"%")
+	}
+	if terms := fulltextTerms(q.Search); terms != "" {
+		conds = append(conds, "MATCH(customer) AGAINST (? IN BOOLEAN MODE)")
+		args = append(args, terms)
+	}
+	// itens via idx_order_items_sku (tenant_id, sku); sem prefixo, tenant_id
+	// dentro da subconsulta é o de order_items
+	if len(q.SKU) > 0 {
+		conds = append(conds, "id IN (SELECT order_id FROM order_items WHERE tenant_id = ? AND sku IN (?"+strings.Repeat(", ?", len(q.SKU)-1)+"))")
+		args = append(args, tenant.FromContext(ctx))
+		for _, sku := range q.SKU {
+			args = append(args, sku)
+		}
+	}
+	if q.Item != "" {
+		conds = append(conds, "id IN (SELECT order_id FROM order_items WHERE tenant_id = ? AND sku LIKE ?)")
+		args = append(args, tenant.FromContext(ctx), "%"+likeEscape(q.Item)+"%")
+	}
+	// principal amarrado a um cliente só enxerga os próprios pedidos
+	if p, ok := auth.FromContext(ctx); ok && p.Bound() {
+		if p.CustomerID != "" {
+			conds = append(conds, "customer_id = ?")
+			args = append(args, p.CustomerID)
+		} else {
+			conds = append(conds, "customer = ?")
+			args = append(args, p.Customer)
+		}
+	}
+	if !q.Since.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, q.Since)
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, "created_at <= ?")
+		args = append(args, q.Until)
+	}
+	if !q.UpdatedSince.IsZero() {
+		conds = append(conds, "updated_at >= ?")
+		args = append(args, q.UpdatedSince)
+	}
+	if c := q.After; c != nil {
+		conds = append(conds, "(created_at < ? OR (created_at = ? AND id < ?))")
+		args = append(args, c.CreatedAt, c.CreatedAt, c.ID)
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// likeEscape protege % e _ do texto do cliente num LIKE (ou JSON_SEARCH).
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// fulltextTerms monta a busca em modo booleano: toda palavra é exigida e
+// casa por prefixo ("ac corp" → "+ac* +corp*"). Operadores do cliente são
+// descartados.
+func fulltextTerms(q string) string {
+	words := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = "+" + w + "*"
+	}
+	return strings.Join(words, " ")
+}
+
+// updateStatus troca o status e devolve o pedido já atualizado.
+func (s *Server) updateStatus(ctx context.Context, id, status string) (store.Order, error) {
+	var o store.Order
+	evs, err := s.inTx(ctx, func(tx *sql.Tx) ([]store.OutboxEvent, error) {
+		var err error
+		var ev store.OutboxEvent
+		o, ev, err = s.updateStatusTx(ctx, tx, id, status, time.Now().UTC())
+		return []store.OutboxEvent{ev}, err
+	})
+	if err != nil {
+		return store.Order{}, err
+	}
+	metrics.StatusTransition(status)
+	return o, s.publishAll(ctx, evs, id)
+}
+
+// updateStatusTx trava o pedido, confere o acesso e grava o novo status, o
+// histórico e o evento OrderStatusUpdated dentro de tx.
+func (s *Server) updateStatusTx(ctx context.Context, tx *sql.Tx, id, status string, now time.Time) (store.Order, store.OutboxEvent, error) {
+	tnt := tenant.FromContext(ctx)
+	sub := auth.Subject(ctx)
+
+	cur, err := store.ScanOrder(tx.QueryRowContext(ctx,
+		`SELECT `+store.OrderColumns+` FROM orders WHERE id=? AND tenant_id=? FOR UPDATE`, id, tnt))
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Order{}, store.OutboxEvent{}, store.ErrNotFound
+	}
+	if err != nil {
+		return store.Order{}, store.OutboxEvent{}, err
+	}
+	if p, ok := auth.FromContext(ctx); ok && !p.CanAccess(cur.Customer, cur.CustomerID) {
+		return store.Order{}, store.OutboxEvent{}, denied("order belongs to another customer", "order_id", id)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE orders SET status=?, updated_at=?, updated_by=? WHERE id=? AND tenant_id=?`,
+		status, now, sql.NullString{String: sub, Valid: sub != ""}, id, tnt); err != nil {
+		return store.Order{}, store.OutboxEvent{}, err
+	}
+	if cur.Status != status {
+		if err := store.RecordStatus(ctx, tx, tnt, id, status, sub, now); err != nil {
+			return store.Order{}, store.OutboxEvent{}, err
+		}
+	}
+	one := []store.Order{*cur}
+	if err := store.LoadOrderItems(ctx, tx, one); err != nil {
+		return store.Order{}, store.OutboxEvent{}, err
+	}
+	o := one[0]
+	o.Status, o.UpdatedAt, o.UpdatedBy = status, now, sub
+
+	evt := map[string]any{
+		"type":     "OrderStatusUpdated",
+		"id":       id,
+		"tenantId": tnt,
+		"status":   status,
+		"ts":       now.Format(time.RFC3339Nano),
+	}
+	ev, err := store.EnqueueEvent(ctx, tx, s.outboxEvent(ctx, id, "OrderStatusUpdated"), evt)
+	return o, ev, err
+}
+
+// publish tenta publicar o evento já gravado; se o Kafka falhar ele fica no
+// outbox e o relay tenta de novo depois. Atrás de um evento anterior da mesma
+// chave ainda pendente ele fica para o relay, que respeita a ordem.
+func (s *Server) publish(ctx context.Context, ev store.OutboxEvent, orderID string) error {
+	err := s.relay.Publish(ctx, ev)
+	if errors.Is(err, outbox.ErrQueued) {
+		slog.InfoContext(ctx, "event queued behind an earlier one; left to the outbox relay",
+			"event", ev.Type, "order_id", orderID, "outbox_id", ev.ID)
+		return nil
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "publish failed; event kept in outbox",
+			"event", ev.Type, "order_id", orderID, "outbox_id", ev.ID, "err", err)
+		return errPublish
+	}
+	return nil
+}
+
+// publishAll publica os eventos de uma transação em ordem; o primeiro que
+// falhar deixa os seguintes para o relay, que mantém a ordem do outbox.
+func (s *Server) publishAll(ctx context.Context, evs []store.OutboxEvent, id string) error {
+	for _, ev := range evs {
+		if err := s.publish(ctx, ev, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inTx roda fn numa transação e faz commit/rollback conforme o erro.
+func (s *Server) inTx(ctx context.Context, fn func(tx *sql.Tx) ([]store.OutboxEvent, error)) ([]store.OutboxEvent, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	evs, err := fn(tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return evs, tx.Commit()
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"orders-api/auth"
+	"orders-api/logging"
+	"orders-api/store"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Respostas JSON
+// ──────────────────────────────────────────────────────────────────────────────
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// problem é o corpo RFC 9457 (application/problem+json) dos erros novos.
+type problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, code int, title, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(problem{
+		Type:      "about:blank",
+		Title:     title,
+		Status:    code,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: logging.RequestID(r.Context()),
+	})
+}
+
+// writeError traduz os erros das operações de pedido (orders.go) para HTTP;
+// o que não for erro de domínio é logado com msg e vira 500.
+func writeError(w http.ResponseWriter, r *http.Request, err error, msg string, attrs ...any) {
+	var (
+		inv *invalidError
+		den *deniedError
+		cfl *conflictError
+	)
+	switch {
+	case errors.As(err, &inv):
+		writeProblem(w, r, http.StatusUnprocessableEntity, inv.title, inv.detail)
+	case errors.As(err, &cfl):
+		writeProblem(w, r, http.StatusConflict, cfl.title, cfl.detail)
+	case errors.As(err, &den):
+		p, _ := auth.FromContext(r.Context())
+		forbid(w, r, p, den.reason, den.attrs...)
+	case store.IsNotFound(err):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, errPublish):
+		http.Error(w, "kafka unavailable", http.StatusServiceUnavailable)
+	default:
+		slog.ErrorContext(r.Context(), msg, append(attrs, "err", err)...)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
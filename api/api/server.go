@@ -1,19 +1,32 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
-	"errors"
-	"log"
+	"fmt"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"orders-api/auth"
+	"orders-api/bus"
+	"orders-api/config"
 	"orders-api/events" // ajuste para o nome do seu módulo
+	"orders-api/logging"
+	"orders-api/metrics"
+	"orders-api/outbox"
 	"orders-api/store"
+	"orders-api/tenant"
+	"orders-api/tracing"
+	"orders-api/webhook"
 
+	graphql "github.com/graph-gophers/graphql-go"
 	ulid "github.com/oklog/ulid/v2"
 )
 
@@ -22,8 +35,9 @@ import (
 // ──────────────────────────────────────────────────────────────────────────────
 
 type createReq struct {
-	Customer string   `json:"customer"`
-	Items    []string `json:"items"`
+	Customer   string   `json:"customer"`   // nome; clientes antigos só mandam este
+	CustomerID string   `json:"customerId"` // cliente já cadastrado
+	Items      []string `json:"items"`
 }
 
 type updateStatusReq struct {
@@ -47,33 +61,92 @@ func newID() string {
 type Server struct {
 	db        *sql.DB
 	publisher *events.Publisher
+	relay     *outbox.Relay
 	mux       *http.ServeMux
+	handler   http.Handler
+	tenancy   config.Tenancy
+	limits    config.Limits
+	limiters  *limiters
+	authn     *auth.Authenticator // nil: sem autenticação
+	bus       *bus.Bus
+	gql       *graphql.Schema
+	contract  *contract
+	imports   config.Imports
+	hookGuard *webhook.Guard
+	draining  atomic.Bool
+
+	// streamsDone fecha no início do shutdown e encerra os streams SSE
+	streamsDone chan struct{}
+	stopStreams sync.Once
+}
+
+// Options são os ajustes de comportamento do Server vindos da config.
+type Options struct {
+	Debug   bool                // loga headers e corpos de request/response (HTTP_DEBUG)
+	Auth    *auth.Authenticator // nil: API aberta (auth.enabled=false)
+	Tenancy config.Tenancy
+	Limits  config.Limits
+	Bus     *bus.Bus // eventos publicados, para o SSE; nil desliga /stream
+	Imports config.Imports
+	Outbox  config.Outbox
+	// Webhooks.AllowedHosts libera hosts internos no cadastro de webhooks
+	Webhooks config.Webhooks
+
+	// Validation confere requests/respostas contra o OpenAPI: off|warn|strict
+	Validation string
 }
 
 // NewServer recebe as dependências (DB e Kafka publisher) e monta as rotas.
-func NewServer(db *sql.DB, publisher *events.Publisher) *Server {
+func NewServer(db *sql.DB, publisher *events.Publisher, opts Options) *Server {
 	s := &Server{
 		db:        db,
 		publisher: publisher,
+		relay:     outbox.NewRelay(db, publisher, opts.Outbox),
 		mux:       http.NewServeMux(),
+		tenancy:   opts.Tenancy,
+		limits:    opts.Limits,
+		limiters:  newLimiters(opts.Limits),
+		authn:     opts.Auth,
+		bus:       opts.Bus,
+		imports:   opts.Imports,
+		hookGuard: webhook.NewGuard(opts.Webhooks.AllowedHosts),
+
+		streamsDone: make(chan struct{}),
+	}
+	s.gql = s.newGraphQLSchema()
+	if opts.Validation == "" {
+		opts.Validation = ValidationOff
 	}
+	s.contract = newContract(opts.Validation, opts.Tenancy.Header)
 	s.registerRoutes()
+	var h http.Handler = withLimits(s.limiters, withTenant(opts.Tenancy, s.withCustomer(s.contract.checkRequests(s.mux))))
+	if opts.Auth != nil {
+		h = withAuth(opts.Auth, s.limiters, h)
+	}
+	s.handler = withRequestID(withTracing(withRequestLog(opts.Debug, withMetrics(s.contract.checkResponses(h)))))
 	return s
 }
 
-// ServeHTTP implementa http.Handler e delega para o mux interno.
+// ServeHTTP implementa http.Handler e delega para o mux (com middlewares).
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.mux.ServeHTTP(w, r)
+	s.handler.ServeHTTP(w, r)
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
 // Rotas
 // ──────────────────────────────────────────────────────────────────────────────
 
+// registerRoutes monta o mux a partir de apiOperations (openapi.go), a
+// mesma tabela do /openapi.json, do routeLabel e do limits.routes: rota nova
+// entra só lá. O mux responde 404/405 para path ou método fora dela.
 func (s *Server) registerRoutes() {
-	s.mux.HandleFunc("/health", s.handleHealth)
-	s.mux.HandleFunc("/orders", s.handleOrders)
-	s.mux.HandleFunc("/orders/", s.handleOrderByID)
+	for _, op := range apiOperations {
+		h := func(w http.ResponseWriter, r *http.Request) { op.handle(s, w, r) }
+		if op.scope != "" {
+			h = requireScope(op.scope, h)
+		}
+		s.mux.HandleFunc(op.method+" "+op.path, h)
+	}
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -85,49 +158,12 @@ func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
 	_, _ = w.Write([]byte(`{"ok":true}`))
 }
 
-// /orders → POST (criar) / GET (listar)
-func (s *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodPost:
-		s.handleCreateOrder(w, r)
-	case http.MethodGet:
-		s.handleListOrders(w, r)
-	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
-// /orders/{id}           → GET
-// /orders/{id}/status    → PUT
-func (s *Server) handleOrderByID(w http.ResponseWriter, r *http.Request) {
-	path := strings.TrimPrefix(r.URL.Path, "/orders/")
-	if path == "" {
-		http.NotFound(w, r)
-		return
-	}
-
-	// PUT /orders/{id}/status
-	if strings.HasSuffix(path, "/status") {
-		if r.Method != http.MethodPut {
-			http.Error(w, "use PUT", http.StatusMethodNotAllowed)
-			return
-		}
-		id := strings.TrimSuffix(path, "/status")
-		id = strings.TrimSuffix(id, "/")
-		s.handleUpdateStatus(w, r, id)
-		return
-	}
+// metricsHandler expõe o registry do pacote metrics (criado uma vez: o
+// promhttp registra nele o próprio contador de erros).
+var metricsHandler = metrics.Handler()
 
-	// GET /orders/{id}
-	if r.Method != http.MethodGet {
-		http.Error(w, "use GET", http.StatusMethodNotAllowed)
-		return
-	}
-	if strings.Contains(path, "/") {
-		http.Error(w, "invalid path; expected /orders/{id}", http.StatusBadRequest)
-		return
-	}
-	s.handleGetOrder(w, r, path)
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	metricsHandler.ServeHTTP(w, r)
 }
 
 // ──────────────────────────────────────────────────────────────────────────────
@@ -136,176 +172,192 @@ func (s *Server) handleOrderByID(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleCreateOrder(w http.ResponseWriter, r *http.Request) {
 	var req createReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid json", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
-	now := time.Now().UTC()
-	itemsJSON, _ := json.Marshal(req.Items)
-
-	id := newID()
-
-	if _, err := s.db.Exec(`INSERT INTO orders (id, customer, status, items_json, created_at, updated_at)
-		VALUES (?,?,?,?,?,?)`,
-		id, req.Customer, "OPEN", string(itemsJSON), now, now); err != nil {
-		log.Printf("ERROR insert order: %v", err)
-		http.Error(w, err.Error(), 500)
+	o, err := s.createOrder(r.Context(), req)
+	if err != nil {
+		writeError(w, r, err, "insert order failed")
 		return
 	}
 
-	evt := map[string]any{
-		"type":     "OrderCreated",
-		"id":       id,
-		"customer": req.Customer,
-		"status":   "OPEN",
-		"items":    req.Items,
-		"ts":       now.Format(time.RFC3339Nano),
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"id":         o.ID,
+		"tenantId":   o.TenantID,
+		"customer":   o.Customer,
+		"customerId": o.CustomerID,
+		"items":      o.Items,
+		"status":     o.Status,
+	})
+}
+
+func (s *Server) handleListOrders(w http.ResponseWriter, r *http.Request) {
+	f, err := parseListQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid query parameter", err.Error())
+		return
 	}
 
-	if _, err := s.publisher.PublishWithDigest(r.Context(), id, evt,
-		map[string]string{"x-event": "OrderCreated"}); err != nil {
-		log.Printf("WARN publish OrderCreated failed: %v", err)
-		http.Error(w, "kafka unavailable", http.StatusServiceUnavailable)
+	list, err := s.listOrders(r.Context(), f)
+	if err != nil {
+		writeError(w, r, err, "list orders failed")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(map[string]any{
-		"id":       id,
-		"customer": req.Customer,
-		"items":    req.Items,
-		"status":   "OPEN",
+		"items":  list,
+		"limit":  f.Limit,
+		"offset": f.Offset,
+		"count":  len(list),
 	})
 }
 
-func (s *Server) handleListOrders(w http.ResponseWriter, r *http.Request) {
-	q := r.URL.Query()
-
-	status := q.Get("status")
-	customer := q.Get("customer")
-	since := q.Get("since")
-	until := q.Get("until")
-
-	limit, offset := 50, 0
-	if v := q.Get("limit"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 500 {
-			limit = n
+// parseListQuery lê os filtros de GET /orders. Valores malformados são
+// 400 (antes eram ignorados); limit fora de 1..500 continua virando o padrão.
+func parseListQuery(q url.Values) (orderQuery, error) {
+	f := orderQuery{Customer: q.Get("customer"), Search: q.Get("q"), Item: q.Get("item")}
+	if v := q.Get("status"); v != "" {
+		for _, st := range strings.Split(v, ",") {
+			st = strings.TrimSpace(st)
+			if st == "" || len(st) > 32 {
+				return f, fmt.Errorf("status: invalid value %q", v)
+			}
+			f.Status = append(f.Status, st)
 		}
 	}
-	if v := q.Get("offset"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-			offset = n
+	if v := q.Get("sku"); v != "" {
+		for _, sku := range strings.Split(v, ",") {
+			sku = strings.TrimSpace(sku)
+			if sku == "" || len(sku) > store.MaxItemLength {
+				return f, fmt.Errorf("sku: invalid value %q", v)
+			}
+			f.SKU = append(f.SKU, sku)
 		}
 	}
-
-	var (
-		conds []string
-		args  []any
-	)
-	if status != "" {
-		conds = append(conds, "status = ?")
-		args = append(args, status)
+	if f.Search != "" && fulltextTerms(f.Search) == "" {
+		return f, fmt.Errorf("q: no searchable words in %q", f.Search)
 	}
-	if customer != "" {
-		conds = append(conds, "customer LIKE ?")
-		args = append(args, "%"+customer+"%")
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil {
+			return f, fmt.Errorf("limit: %q is not an integer", v)
+		}
 	}
-	if since != "" {
-		if t, err := time.Parse(time.RFC3339, since); err == nil {
-			conds = append(conds, "created_at >= ?")
-			args = append(args, t)
+	if v := q.Get("offset"); v != "" {
+		if f.Offset, err = strconv.Atoi(v); err != nil || f.Offset < 0 {
+			return f, fmt.Errorf("offset: %q is not a non-negative integer", v)
 		}
 	}
-	if until != "" {
-		if t, err := time.Parse(time.RFC3339, until); err == nil {
-			conds = append(conds, "created_at <= ?")
-			args = append(args, t)
+	for _, d := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}, {"updated_since", &f.UpdatedSince}} {
+		if v := q.Get(d.name); v != "" {
+			if *d.dst, err = time.Parse(time.RFC3339, v); err != nil {
+				return f, fmt.Errorf("%s: %q is not an RFC 3339 timestamp", d.name, v)
+			}
 		}
 	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && f.Since.After(f.Until) {
+		return f, fmt.Errorf("since: must not be after until")
+	}
 
-	var sb strings.Builder
-	sb.WriteString("SELECT id, customer, status, items_json, created_at, updated_at FROM orders")
-	if len(conds) > 0 {
-		sb.WriteString(" WHERE ")
-		sb.WriteString(strings.Join(conds, " AND "))
+	if v := q.Get("sort"); v != "" {
+		seen := map[string]bool{}
+		for _, key := range strings.Split(v, ",") {
+			field, desc := strings.CutPrefix(strings.TrimSpace(key), "-")
+			col, ok := orderSortColumns[field]
+			if !ok {
+				return f, fmt.Errorf("sort: unknown field %q (use createdAt, updatedAt, customer or status, - for descending)", field)
+			}
+			if seen[col] {
+				return f, fmt.Errorf("sort: field %q repeated", field)
+			}
+			seen[col] = true
+			f.Sort = append(f.Sort, orderSort{Column: col, Desc: desc})
+		}
 	}
-	sb.WriteString(" ORDER BY created_at DESC, id DESC")
-	sb.WriteString(" LIMIT ? OFFSET ?")
-	args = append(args, limit, offset)
+	f.normalize()
+	return f, nil
+}
 
-	rows, err := s.db.Query(sb.String(), args...)
-	if err != nil {
-		http.Error(w, err.Error(), 500)
+func (s *Server) handleUpdateStatus(w http.ResponseWriter, r *http.Request, id string) {
+	var req updateStatusReq
+	if !decodeJSON(w, r, &req) {
 		return
 	}
-	defer rows.Close()
-
-	list, err := store.ScanOrders(rows)
+	o, err := s.updateStatus(r.Context(), id, req.Status)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		writeError(w, r, err, "update order status failed", "order_id", id)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]any{
-		"items":  list,
-		"limit":  limit,
-		"offset": offset,
-		"count":  len(list),
+		"id":     o.ID,
+		"status": o.Status,
 	})
 }
 
-func (s *Server) handleUpdateStatus(w http.ResponseWriter, r *http.Request, id string) {
-	var req updateStatusReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid json", http.StatusBadRequest)
-		return
-	}
-	now := time.Now().UTC()
-
-	res, err := s.db.Exec(`UPDATE orders SET status=?, updated_at=? WHERE id=?`, req.Status, now, id)
+func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request, id string) {
+	o, err := s.getOrder(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		writeError(w, r, err, "get order failed", "order_id", id)
 		return
 	}
-	aff, _ := res.RowsAffected()
-	if aff == 0 {
-		http.Error(w, "not found", http.StatusNotFound)
-		return
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(o)
+}
+
+// outboxEvent monta a linha do outbox de um evento do pedido (ou do
+// cliente): chave e tópico do tenant, mais os headers.
+func (s *Server) outboxEvent(ctx context.Context, id, eventType string) store.OutboxEvent {
+	tnt := tenant.FromContext(ctx)
+	return store.OutboxEvent{
+		Tenant:  tnt,
+		Topic:   tenant.Topic(s.tenancy.TopicTemplate, "", tnt),
+		Key:     tenant.EventKey(tnt, id),
+		Type:    eventType,
+		Headers: eventHeaders(ctx, eventType),
 	}
+}
 
-	evt := map[string]any{
-		"type":   "OrderStatusUpdated",
-		"id":     id,
-		"status": req.Status,
-		"ts":     now.Format(time.RFC3339Nano),
+// eventHeaders monta os headers do evento, levando o X-Request-Id, o
+// subject autenticado, o tenant e o traceparent junto (ficam gravados no
+// outbox, então sobrevivem a um `outbox drain` posterior).
+func eventHeaders(ctx context.Context, eventType string) map[string]string {
+	h := map[string]string{events.HeaderEvent: eventType}
+	if tnt := tenant.FromContext(ctx); tnt != "" {
+		h[events.HeaderTenant] = tnt
 	}
-	if _, err := s.publisher.PublishWithDigest(r.Context(), id, evt,
-		map[string]string{"x-event": "OrderStatusUpdated"}); err != nil {
-		log.Printf("WARN publish OrderStatusUpdated failed: %v", err)
-		http.Error(w, "kafka unavailable", http.StatusServiceUnavailable)
-		return
+	if id := logging.RequestID(ctx); id != "" {
+		h[events.HeaderRequestID] = id
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]any{
-		"id":     id,
-		"status": req.Status,
-	})
+	if sub := auth.Subject(ctx); sub != "" {
+		h[events.HeaderSubject] = sub
+	}
+	tracing.Propagator.Inject(ctx, tracing.MapCarrier(h))
+	return h
 }
 
-func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request, id string) {
-	row := s.db.QueryRow(`SELECT id, customer, status, items_json, created_at, updated_at FROM orders WHERE id=?`, id)
-	o, err := store.ScanOrder(row)
-	if errors.Is(err, sql.ErrNoRows) {
-		http.Error(w, "not found", http.StatusNotFound)
-		return
+// checkItems aplica os tetos de quantidade e tamanho de itens da rota.
+func checkItems(items []string, lim config.RouteLimits) error {
+	if lim.MaxItems > 0 && len(items) > lim.MaxItems {
+		return fmt.Errorf("too many items: %d (max %d)", len(items), lim.MaxItems)
 	}
-	if err != nil {
-		http.Error(w, err.Error(), 500)
-		return
+	// order_items.sku limita acima da config
+	max := lim.MaxItemLength
+	if max <= 0 || max > store.MaxItemLength {
+		max = store.MaxItemLength
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(o)
+	for i, it := range items {
+		if len(it) > max {
+			return fmt.Errorf("items[%d] is %d bytes long (max %d)", i, len(it), max)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"orders-api/tenant"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Estatísticas (GET /orders/stats): os mesmos filtros da listagem, agregados
+// no MySQL para os dashboards não precisarem puxar todos os pedidos.
+// ──────────────────────────────────────────────────────────────────────────────
+
+const (
+	statsMaxBuckets = 1000
+	statsDefaultTop = 10
+	statsMaxTop     = 100
+)
+
+// statsIntervals: intervalo da série → formato do DATE_FORMAT, layout para
+// ler o início do bucket de volta e janela padrão sem since.
+var statsIntervals = map[string]struct {
+	sqlFormat, layout string
+	step, window      time.Duration
+}{
+	"day":  {"%Y-%m-%d", "2006-01-02", 24 * time.Hour, 30 * 24 * time.Hour},
+	"hour": {"%Y-%m-%d %H:00:00", "2006-01-02 15:04:05", time.Hour, 48 * time.Hour},
+}
+
+type statsQuery struct {
+	orderQuery
+	Interval string
+	From, To time.Time // janela da série, já alinhada ao intervalo
+	Top      int
+}
+
+type orderStats struct {
+	Total        int              `json:"total"`
+	ByStatus     map[string]int   `json:"byStatus"`
+	Interval     string           `json:"interval"`
+	From         time.Time        `json:"from"`
+	To           time.Time        `json:"to"`
+	Series       []statsBucket    `json:"series"`
+	TopCustomers []customerCount  `json:"topCustomers"`
+	TimeInStatus []statusDuration `json:"timeInStatus"`
+}
+
+type statsBucket struct {
+	Start time.Time `json:"start"`
+	Count int       `json:"count"`
+}
+
+type customerCount struct {
+	Customer string `json:"customer"`
+	Count    int    `json:"count"`
+}
+
+// statusDuration é a média das passagens já encerradas por um status (o
+// status atual de cada pedido não entra).
+type statusDuration struct {
+	Status     string  `json:"status"`
+	AvgSeconds float64 `json:"avgSeconds"`
+	Samples    int     `json:"samples"`
+}
+
+// statsListOnly são parâmetros de GET /orders que não se aplicam aos
+// agregados: recusados em vez de ignorados.
+var statsListOnly = []string{"sort", "limit", "offset"}
+
+// parseStatsQuery aceita os filtros de GET /orders mais interval=day|hour e
+// top=1..100; sort e paginação dão erro.
+func parseStatsQuery(q url.Values) (statsQuery, error) {
+	for _, name := range statsListOnly {
+		if q.Has(name) {
+			return statsQuery{}, fmt.Errorf("%s: not supported by /orders/stats", name)
+		}
+	}
+	f, err := parseListQuery(q)
+	if err != nil {
+		return statsQuery{}, err
+	}
+	sq := statsQuery{orderQuery: f, Interval: "day", Top: statsDefaultTop}
+	if v := q.Get("interval"); v != "" {
+		sq.Interval = v
+	}
+	iv, ok := statsIntervals[sq.Interval]
+	if !ok {
+		return sq, fmt.Errorf("interval: %q is not day or hour", sq.Interval)
+	}
+	if v := q.Get("top"); v != "" {
+		if sq.Top, err = strconv.Atoi(v); err != nil || sq.Top < 1 || sq.Top > statsMaxTop {
+			return sq, fmt.Errorf("top: %q is not an integer in 1..%d", v, statsMaxTop)
+		}
+	}
+
+	// a série vai do bucket de since ao de until (padrão: agora e a janela
+	// do intervalo para trás)
+	sq.To = f.Until
+	if sq.To.IsZero() {
+		sq.To = time.Now().UTC()
+	}
+	sq.From = f.Since
+	if sq.From.IsZero() {
+		sq.From = sq.To.Add(-iv.window)
+	}
+	sq.From, sq.To = sq.From.UTC().Truncate(iv.step), sq.To.UTC().Truncate(iv.step)
+	if n := int(sq.To.Sub(sq.From)/iv.step) + 1; n > statsMaxBuckets {
+		return sq, fmt.Errorf("interval: %d %s buckets requested, at most %d", n, sq.Interval, statsMaxBuckets)
+	}
+	return sq, nil
+}
+
+func (s *Server) orderStats(ctx context.Context, q statsQuery) (orderStats, error) {
+	where, args := q.where(ctx)
+	out := orderStats{
+		ByStatus:     map[string]int{},
+		Interval:     q.Interval,
+		From:         q.From,
+		To:           q.To,
+		Series:       []statsBucket{},
+		TopCustomers: []customerCount{},
+		TimeInStatus: []statusDuration{},
+	}
+
+	// contagem por status
+	rows, err := s.db.QueryContext(ctx, `SELECT status, COUNT(*) FROM orders WHERE `+where+` GROUP BY status`, args...)
+	if err != nil {
+		return out, err
+	}
+	for rows.Next() {
+		var st string
+		var n int
+		if err := rows.Scan(&st, &n); err != nil {
+			rows.Close()
+			return out, err
+		}
+		out.ByStatus[st] = n
+		out.Total += n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return out, err
+	}
+
+	// série: buckets sem pedido entram com zero
+	iv := statsIntervals[q.Interval]
+	counts := map[time.Time]int{}
+	rows, err = s.db.QueryContext(ctx,
+		`SELECT DATE_FORMAT(created_at, '`+iv.sqlFormat+`') AS bucket, COUNT(*) FROM orders
+		WHERE `+where+` AND created_at >= ? AND created_at < ? GROUP BY bucket`,
+		append(args, q.From, q.To.Add(iv.step))...)
+	if err != nil {
+		return out, err
+	}
+	for rows.Next() {
+		var b string
+		var n int
+		if err := rows.Scan(&b, &n); err != nil {
+			rows.Close()
+			return out, err
+		}
+		start, err := time.ParseInLocation(iv.layout, b, time.UTC)
+		if err != nil {
+			rows.Close()
+			return out, fmt.Errorf("stats bucket %q: %w", b, err)
+		}
+		counts[start] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return out, err
+	}
+	for t := q.From; !t.After(q.To); t = t.Add(iv.step) {
+		out.Series = append(out.Series, statsBucket{Start: t, Count: counts[t]})
+	}
+
+	// maiores clientes
+	rows, err = s.db.QueryContext(ctx,
+		`SELECT customer, COUNT(*) AS n FROM orders WHERE `+where+` GROUP BY customer ORDER BY n DESC, customer LIMIT ?`,
+		append(args, q.Top)...)
+	if err != nil {
+		return out, err
+	}
+	for rows.Next() {
+		var c customerCount
+		if err := rows.Scan(&c.Customer, &c.Count); err != nil {
+			rows.Close()
+			return out, err
+		}
+		out.TopCustomers = append(out.TopCustomers, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return out, err
+	}
+
+	// tempo médio em cada status, do histórico dos pedidos filtrados
+	rows, err = s.db.QueryContext(ctx,
+		`SELECT status, AVG(TIMESTAMPDIFF(MICROSECOND, entered_at, left_at)) / 1000000, COUNT(*)
+		FROM order_status_history
+		WHERE tenant_id = ? AND left_at IS NOT NULL AND order_id IN (SELECT id FROM orders WHERE `+where+`)
+		GROUP BY status ORDER BY status`,
+		append([]any{tenant.FromContext(ctx)}, args...)...)
+	if err != nil {
+		return out, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d statusDuration
+		if err := rows.Scan(&d.Status, &d.AvgSeconds, &d.Samples); err != nil {
+			return out, err
+		}
+		out.TimeInStatus = append(out.TimeInStatus, d)
+	}
+	return out, rows.Err()
+}
+
+func (s *Server) handleOrderStats(w http.ResponseWriter, r *http.Request) {
+	q, err := parseStatsQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid query parameter", err.Error())
+		return
+	}
+	st, err := s.orderStats(r.Context(), q)
+	if err != nil {
+		writeError(w, r, err, "order stats failed")
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
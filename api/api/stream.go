@@ -0,0 +1,265 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"orders-api/auth"
+	"orders-api/bus"
+	"orders-api/store"
+	"orders-api/tenant"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Server-Sent Events: GET /orders/stream e GET /orders/{id}/stream
+// ──────────────────────────────────────────────────────────────────────────────
+
+const (
+	streamBuffer       = 256              // eventos enfileirados por cliente antes de desligá-lo
+	streamHeartbeat    = 15 * time.Second // comentário periódico para proxies não fecharem a conexão
+	streamBackfillPage = 1000             // eventos lidos do outbox por vez numa retomada por Last-Event-ID
+	streamRetryMs      = 2000             // sugestão de reconexão para o EventSource
+	streamSeenMax      = 4096             // ids lembrados para descartar duplicatas
+)
+
+// handleStream envia os eventos do tenant (ou de um pedido, se orderID != "")
+// conforme são publicados. O id de cada evento é o id do outbox, então uma
+// reconexão com Last-Event-ID recebe primeiro o que perdeu, lido do banco.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request, orderID string) {
+	if s.bus == nil {
+		writeProblem(w, r, http.StatusServiceUnavailable, "Streaming unavailable", "the event bus is not configured")
+		return
+	}
+	ctx := r.Context()
+	if err := s.checkWatch(ctx, orderID); err != nil {
+		writeError(w, r, err, "stream: load order failed", "order_id", orderID)
+		return
+	}
+	lastID, err := lastEventID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid Last-Event-ID", err.Error())
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	ready := func() error {
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Connection", "keep-alive")
+		h.Set("X-Accel-Buffering", "no") // nginx: não bufferizar
+		w.WriteHeader(http.StatusOK)
+		if _, err := fmt.Fprintf(w, "retry: %d\n\n", streamRetryMs); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	send := func(e bus.Event) error {
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Payload); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	ping := func() error {
+		if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	if err := s.watch(ctx, orderID, lastID, ready, send, ping); errors.Is(err, errWatchTooSlow) {
+		slog.WarnContext(ctx, "stream: subscriber too slow, closing", "order_id", orderID)
+	}
+}
+
+// Fim de um watch que o cliente deve resolver reconectando (com o último id).
+var (
+	errWatchClosed  = errors.New("server shutting down")
+	errWatchTooSlow = errors.New("subscriber too slow")
+)
+
+// checkWatch confere se o pedido existe e é visível para o principal antes
+// de abrir o stream (vazio: stream do tenant, sempre permitido).
+func (s *Server) checkWatch(ctx context.Context, orderID string) error {
+	if orderID == "" {
+		return nil
+	}
+	name, id, err := s.orderCustomer(ctx, tenant.FromContext(ctx), orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if p, _ := auth.FromContext(ctx); !p.CanAccess(name, id) {
+		return denied("order belongs to another customer", "order_id", orderID)
+	}
+	return nil
+}
+
+// watch é o laço comum ao SSE e ao WatchOrders: assina o bus, chama ready,
+// reenvia do outbox o que veio depois de lastID e segue com os eventos ao
+// vivo até ctx acabar (nil), o shutdown (errWatchClosed) ou o bus desligar
+// um assinante lento (errWatchTooSlow). ping nil desliga o heartbeat.
+func (s *Server) watch(ctx context.Context, orderID string, lastID int64,
+	ready func() error, send func(bus.Event) error, ping func() error) error {
+	tnt := tenant.FromContext(ctx)
+	p, _ := auth.FromContext(ctx)
+	restricted := p.Bound()
+
+	// assina antes do backfill para não perder o que for publicado no meio;
+	// duplicatas são descartadas pelo id
+	seen := newSeenIDs(lastID)
+	sub := s.bus.Subscribe(streamBuffer)
+	defer sub.Close()
+
+	// principal preso a um cliente: filtra por dono do pedido (com cache)
+	owners := map[string]bool{}
+	match := func(e bus.Event) bool {
+		// o tópico também leva os eventos de cliente, que não são de pedido
+		if !strings.HasPrefix(e.Type, "Order") {
+			return false
+		}
+		if e.Tenant != tnt || (orderID != "" && e.OrderID != orderID) {
+			return false
+		}
+		if !restricted || orderID != "" {
+			return true
+		}
+		ok, cached := owners[e.OrderID]
+		if !cached {
+			name, id, err := s.orderCustomer(ctx, tnt, e.OrderID)
+			ok = err == nil && p.CanAccess(name, id)
+			owners[e.OrderID] = ok
+		}
+		return ok
+	}
+	emit := func(e bus.Event) error {
+		if !seen.add(e.ID) {
+			return nil
+		}
+		return send(e)
+	}
+
+	if err := ready(); err != nil {
+		return err
+	}
+
+	// backfill em páginas até alcançar o fim do outbox; um atraso grande
+	// demais para o buffer cai em errWatchTooSlow e o cliente retoma dali
+	for after := lastID; after > 0; {
+		missed, err := store.ListEvents(ctx, s.db, store.OutboxFilter{
+			Tenant: tnt, OrderID: orderID, AfterID: after, Published: true, Limit: streamBackfillPage,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "stream: backfill failed", "last_event_id", lastID, "after", after, "err", err)
+			return err
+		}
+		for _, ev := range missed {
+			after = ev.ID
+			_, id := tenant.SplitEventKey(ev.Key)
+			e := bus.Event{ID: ev.ID, Type: ev.Type, Tenant: ev.Tenant, OrderID: id, Payload: ev.Payload}
+			if !match(e) {
+				continue
+			}
+			if err := emit(e); err != nil {
+				return err
+			}
+		}
+		if len(missed) < streamBackfillPage {
+			break
+		}
+	}
+
+	var tick <-chan time.Time
+	if ping != nil {
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+		tick = heartbeat.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.streamsDone:
+			return errWatchClosed // shutdown: o cliente reconecta em outra réplica
+		case <-tick:
+			if err := ping(); err != nil {
+				return err
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				return errWatchTooSlow
+			}
+			if seen.has(e.ID) || !match(e) {
+				continue
+			}
+			if err := emit(e); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// seenIDs lembra os ids já enviados acima do Last-Event-ID. Os ids do outbox
+// não chegam em ordem (duas transações podem publicar N+1 antes de N), então
+// não basta guardar o maior. Passando de streamSeenMax, o mais antigo é
+// esquecido e vira o novo piso.
+type seenIDs struct {
+	floor int64 // este e os anteriores contam como enviados
+	ids   map[int64]bool
+	order []int64 // ordem de envio, para esquecer os mais antigos
+}
+
+func newSeenIDs(floor int64) *seenIDs {
+	return &seenIDs{floor: floor, ids: map[int64]bool{}}
+}
+
+func (s *seenIDs) has(id int64) bool {
+	return id <= s.floor || s.ids[id]
+}
+
+// add registra id; false se ele já tinha sido enviado.
+func (s *seenIDs) add(id int64) bool {
+	if s.has(id) {
+		return false
+	}
+	s.ids[id] = true
+	s.order = append(s.order, id)
+	if len(s.order) > streamSeenMax {
+		old := s.order[0]
+		s.order = s.order[1:]
+		delete(s.ids, old)
+		s.floor = max(s.floor, old)
+	}
+	return true
+}
+
+// lastEventID lê o header Last-Event-ID (ou ?lastEventId=, para clientes que
+// não conseguem mandar header na primeira conexão).
+func lastEventID(r *http.Request) (int64, error) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("lastEventId")
+	}
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("%q is not an event id", v)
+	}
+	return id, nil
+}
+
+// orderCustomer devolve nome e id do cliente do pedido.
+func (s *Server) orderCustomer(ctx context.Context, tnt, id string) (name, customerID string, err error) {
+	err = s.db.QueryRowContext(ctx, `SELECT customer, customer_id FROM orders WHERE id=? AND tenant_id=?`, id, tnt).Scan(&name, &customerID)
+	return name, customerID, err
+}
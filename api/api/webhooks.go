@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"orders-api/auth"
+	"orders-api/store"
+	"orders-api/tenant"
+)
+
+// Tipos de evento que um webhook pode assinar.
+var webhookEventTypes = map[string]bool{
+	"OrderCreated":       true,
+	"OrderStatusUpdated": true,
+	"CustomerCreated":    true,
+	"CustomerUpdated":    true,
+}
+
+type createWebhookReq struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	Secret     string   `json:"secret"` // opcional: gerado se vazio
+}
+
+// createdWebhook é a resposta do POST: a única vez em que o secret aparece.
+type createdWebhook struct {
+	store.Webhook
+	Secret string `json:"secret"`
+}
+
+func (s *Server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := store.ListWebhooks(r.Context(), s.db, tenant.FromContext(r.Context()))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "list webhooks failed", "err", err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	writeJSON(w, http.StatusOK, hooks)
+}
+
+func (s *Server) handleGetWebhook(w http.ResponseWriter, r *http.Request, id string) {
+	hook, err := store.GetWebhook(r.Context(), s.db, tenant.FromContext(r.Context()), id)
+	if err != nil {
+		s.webhookError(w, r, id, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, hook)
+}
+
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request, id string) {
+	if err := store.DeleteWebhook(r.Context(), s.db, tenant.FromContext(r.Context()), id); err != nil {
+		s.webhookError(w, r, id, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "webhook deleted", "audit", true, "webhook_id", id, "subject", auth.Subject(r.Context()))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleWebhookDeliveries: log de entregas do webhook, mais recentes primeiro.
+func (s *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request, id string) {
+	if _, err := store.GetWebhook(r.Context(), s.db, tenant.FromContext(r.Context()), id); err != nil {
+		s.webhookError(w, r, id, err)
+		return
+	}
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 500 {
+			limit = n
+		}
+	}
+	log, err := store.ListDeliveries(r.Context(), s.db, id, limit)
+	if err != nil {
+		s.webhookError(w, r, id, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, log)
+}
+
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req createWebhookReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if err := s.validateWebhook(r.Context(), req); err != nil {
+		writeProblem(w, r, http.StatusUnprocessableEntity, "Invalid webhook", err.Error())
+		return
+	}
+	if req.Secret == "" {
+		req.Secret = newWebhookSecret()
+	}
+
+	hook := store.Webhook{
+		ID:         newID(),
+		TenantID:   tenant.FromContext(r.Context()),
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     req.Secret,
+		Active:     true,
+		CreatedAt:  time.Now().UTC().Truncate(time.Millisecond),
+		CreatedBy:  auth.Subject(r.Context()),
+	}
+	if err := store.InsertWebhook(r.Context(), s.db, hook); err != nil {
+		slog.ErrorContext(r.Context(), "insert webhook failed", "err", err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	slog.InfoContext(r.Context(), "webhook created", "audit", true,
+		"webhook_id", hook.ID, "url", hook.URL, "event_types", hook.EventTypes, "subject", hook.CreatedBy)
+	w.Header().Set("Location", "/webhooks/"+hook.ID)
+	writeJSON(w, http.StatusCreated, createdWebhook{Webhook: hook, Secret: hook.Secret})
+}
+
+// validateWebhook confere o corpo; a URL é resolvida por último e recusada
+// se apontar para a rede interna (ver webhook.Guard).
+func (s *Server) validateWebhook(ctx context.Context, req createWebhookReq) error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	if len(req.EventTypes) == 0 {
+		return errors.New("eventTypes must not be empty")
+	}
+	for _, t := range req.EventTypes {
+		if !webhookEventTypes[t] {
+			return errors.New("unknown event type " + strconv.Quote(t))
+		}
+	}
+	if req.Secret != "" && len(req.Secret) < 16 {
+		return errors.New("secret must be at least 16 characters long")
+	}
+	if err := s.hookGuard.CheckURL(ctx, req.URL); err != nil {
+		return fmt.Errorf("url: %w", err)
+	}
+	return nil
+}
+
+func (s *Server) webhookError(w http.ResponseWriter, r *http.Request, id string, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	slog.ErrorContext(r.Context(), "webhook request failed", "webhook_id", id, "err", err)
+	http.Error(w, err.Error(), 500)
+}
+
+func newWebhookSecret() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"orders-api/config"
+)
+
+// Métodos de autenticação registrados no Principal.
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+var (
+	// ErrNoCredentials: o request não trouxe nem API key nem bearer token.
+	ErrNoCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials: credencial presente mas recusada.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Scopes checados por rota. ScopeAdmin implica os outros e ignora a
+// restrição por cliente.
+const (
+	ScopeRead  = "orders:read"
+	ScopeWrite = "orders:write"
+	ScopeAdmin = "orders:admin"
+)
+
+// Principal é quem fez o request, já autenticado.
+type Principal struct {
+	Subject  string
+	Method   string
+	Scopes   []string
+	Customer string         // != "": só pode ver/alterar pedidos desse cliente
+	Tenant   string         // != "": preso a esse tenant
+	Claims   map[string]any // só para JWT
+
+	// CustomerID != "": preso a esse cliente pelo id (orders.customer_id),
+	// então renomear o cliente não muda o que a credencial enxerga.
+	CustomerID string
+	// Tenants: outros tenants que um principal sem Tenant pode pedir pelo
+	// header, além do tenancy.default; "*" libera todos.
+	Tenants []string
+}
+
+// Has diz se o principal tem o scope (orders:admin vale por todos).
+func (p Principal) Has(scope string) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+// Bound diz se o principal está preso a um cliente (por nome ou id).
+func (p Principal) Bound() bool {
+	return (p.Customer != "" || p.CustomerID != "") && !slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+// CanAccess diz se o principal pode mexer em pedidos do cliente informado
+// (nome e id). Preso pelo id, compara só o id; preso pelo nome, compara o
+// nome sem caixa, como a collation de customers.name.
+func (p Principal) CanAccess(name, id string) bool {
+	switch {
+	case !p.Bound():
+		return true
+	case p.CustomerID != "":
+		return id != "" && id == p.CustomerID
+	default:
+		return strings.EqualFold(p.Customer, name)
+	}
+}
+
+// AllowsTenant diz se o principal pode pedir o tenant id pelo header.
+func (p Principal) AllowsTenant(id string) bool {
+	if p.Tenant != "" {
+		return p.Tenant == id
+	}
+	return slices.Contains(p.Tenants, id) || slices.Contains(p.Tenants, AnyTenant)
+}
+
+// AnyTenant em Principal.Tenants libera qualquer tenant.
+const AnyTenant = "*"
+
+type ctxKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext devolve o Principal do request (ok=false se anônimo).
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
+
+// Subject é o atalho usado para gravar autoria ("" se anônimo).
+func Subject(ctx context.Context) string {
+	p, _ := FromContext(ctx)
+	return p.Subject
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Authenticator
+// ──────────────────────────────────────────────────────────────────────────────
+
+type apiKey struct {
+	subject    string
+	hash       []byte
+	scopes     []string
+	customer   string
+	customerID string
+	tenant     string
+	tenants    []string
+}
+
+type Authenticator struct {
+	keys []apiKey
+	jwt  *jwtVerifier // nil se não houver JWKS configurado
+}
+
+// New monta o Authenticator a partir da config (lê o JWKS do disco).
+func New(cfg config.Auth) (*Authenticator, error) {
+	a := &Authenticator{}
+	for _, k := range cfg.APIKeys {
+		h, err := hex.DecodeString(k.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("auth: api key for %q: %w", k.Subject, err)
+		}
+		a.keys = append(a.keys, apiKey{
+			subject: k.Subject, hash: h, scopes: k.Scopes,
+			customer: k.Customer, customerID: k.CustomerID,
+			tenant: k.Tenant, tenants: k.Tenants,
+		})
+	}
+	if cfg.JWT.JWKSFile != "" {
+		v, err := newJWTVerifier(cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = v
+	}
+	return a, nil
+}
+
+// Authenticate aceita `X-Api-Key: <key>`, `Authorization: ApiKey <key>` ou
+// `Authorization: Bearer <jwt>`.
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	return a.Credentials(r.Header.Get("X-Api-Key"), r.Header.Get("Authorization"))
+}
+
+// Credentials valida os valores crus de X-Api-Key e Authorization (vindos de
+// headers HTTP ou de metadata gRPC).
+func (a *Authenticator) Credentials(apiKey, authorization string) (Principal, error) {
+	if apiKey != "" {
+		return a.checkAPIKey(apiKey)
+	}
+	scheme, cred, ok := strings.Cut(authorization, " ")
+	if !ok {
+		return Principal{}, ErrNoCredentials
+	}
+	cred = strings.TrimSpace(cred)
+	switch strings.ToLower(scheme) {
+	case "apikey":
+		return a.checkAPIKey(cred)
+	case "bearer":
+		if a.jwt == nil {
+			return Principal{}, fmt.Errorf("%w: bearer tokens are not enabled", ErrInvalidCredentials)
+		}
+		return a.jwt.verify(cred)
+	default:
+		return Principal{}, fmt.Errorf("%w: unsupported authorization scheme %q", ErrInvalidCredentials, scheme)
+	}
+}
+
+// checkAPIKey compara o hash da chave com todos os cadastrados em tempo
+// constante (não para no primeiro match).
+func (a *Authenticator) checkAPIKey(key string) (Principal, error) {
+	sum := sha256.Sum256([]byte(key))
+	var match *apiKey
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare(sum[:], a.keys[i].hash) == 1 {
+			match = &a.keys[i]
+		}
+	}
+	if match == nil {
+		return Principal{}, fmt.Errorf("%w: unknown api key", ErrInvalidCredentials)
+	}
+	return Principal{
+		Subject: match.subject, Method: MethodAPIKey, Scopes: match.scopes,
+		Customer: match.customer, CustomerID: match.customerID,
+		Tenant: match.tenant, Tenants: match.tenants,
+	}, nil
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"orders-api/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// JWKS local + validação de bearer tokens
+// ──────────────────────────────────────────────────────────────────────────────
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwtVerifier struct {
+	keys   map[string]crypto.PublicKey // por kid
+	parser *jwt.Parser
+}
+
+func newJWTVerifier(cfg config.JWT) (*jwtVerifier, error) {
+	b, err := os.ReadFile(cfg.JWKSFile)
+	if err != nil {
+		return nil, fmt.Errorf("auth: read jwks: %w", err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("auth: parse jwks %s: %w", cfg.JWKSFile, err)
+	}
+
+	v := &jwtVerifier{keys: map[string]crypto.PublicKey{}}
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("auth: jwks key %d (kid %q): %w", i, k.Kid, err)
+		}
+		v.keys[k.Kid] = pub
+	}
+	if len(v.keys) == 0 {
+		return nil, fmt.Errorf("auth: jwks %s has no signing keys", cfg.JWKSFile)
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+	return v, nil
+}
+
+func (v *jwtVerifier) verify(raw string) (Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := v.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		return key, nil
+	})
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	sub, err := claims.GetSubject()
+	if err != nil || sub == "" {
+		return Principal{}, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+	customer, _ := claims["customer"].(string)
+	customerID, _ := claims["customer_id"].(string)
+	tenant, _ := claims["tenant"].(string)
+	if customerID != "" {
+		customer = "" // o nome vem do cadastro (ver Principal.CustomerID)
+	}
+	return Principal{
+		Subject:    sub,
+		Method:     MethodJWT,
+		Scopes:     claimScopes(claims),
+		Customer:   customer,
+		CustomerID: customerID,
+		Tenant:     tenant,
+		Tenants:    claimList(claims, "tenants"),
+		Claims:     claims,
+	}, nil
+}
+
+// claimScopes lê "scope" (string separada por espaço, RFC 8693) ou "scp"
+// (lista ou string, como alguns IdPs emitem).
+func claimScopes(claims jwt.MapClaims) []string {
+	return claimList(claims, "scope", "scp")
+}
+
+// claimList lê o primeiro dos claims presente, como lista ou string
+// separada por espaço.
+func claimList(claims jwt.MapClaims, names ...string) []string {
+	for _, name := range names {
+		switch v := claims[name].(type) {
+		case string:
+			return strings.Fields(v)
+		case []any:
+			out := make([]string, 0, len(v))
+			for _, s := range v {
+				if s, ok := s.(string); ok {
+					out = append(out, s)
+				}
+			}
+			return out
+		}
+	}
+	return nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := b64int(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("n: %w", err)
+		}
+		e, err := b64int(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("e: %w", err)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64int(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := b64int(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported kty %q", k.Kty)
+	}
+}
+
+func b64int(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
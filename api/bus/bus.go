@@ -0,0 +1,75 @@
+package bus
+
+import "sync"
+
+// Event é um evento de pedido já publicado no Kafka, repassado em processo
+// para quem acompanha mudanças (SSE). ID é o id do outbox: monotônico e
+// persistente, serve de Last-Event-ID.
+type Event struct {
+	ID      int64
+	Type    string
+	Tenant  string
+	OrderID string
+	Payload []byte
+}
+
+// Bus é um pub/sub em memória, sem bloqueio para quem publica: assinante que
+// não acompanha o ritmo é desligado (e recupera pelo Last-Event-ID).
+type Bus struct {
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+func New() *Bus {
+	return &Bus{subs: map[*Subscription]struct{}{}}
+}
+
+type Subscription struct {
+	C <-chan Event
+
+	c    chan Event
+	bus  *Bus
+	once sync.Once
+}
+
+// Subscribe registra um assinante com buffer de `buf` eventos. C é fechado
+// quando o assinante é desligado por lentidão ou por Close.
+func (b *Bus) Subscribe(buf int) *Subscription {
+	c := make(chan Event, buf)
+	s := &Subscription{C: c, c: c, bus: b}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Publish entrega e a todos os assinantes.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		select {
+		case s.c <- e:
+		default:
+			b.drop(s)
+		}
+	}
+}
+
+// Close desliga o assinante; pode ser chamado mais de uma vez.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.drop(s)
+}
+
+// drop remove e fecha o canal; chamado com b.mu travado.
+func (b *Bus) drop(s *Subscription) {
+	s.once.Do(func() {
+		delete(b.subs, s)
+		close(s.c)
+	})
+}
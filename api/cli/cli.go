@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"orders-api/api"
+	"orders-api/config"
+	"orders-api/events"
+	"orders-api/logging"
+	"orders-api/store"
+	"orders-api/tracing"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Comandos
+// ──────────────────────────────────────────────────────────────────────────────
+
+type command struct {
+	name    string // ex.: "migrate up"
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"serve", "run the HTTP API (default)", runServe},
+	{"migrate up", "apply pending migrations", runMigrateUp},
+	{"migrate down", "roll back migrations (--steps N, default 1)", runMigrateDown},
+	{"migrate status", "list migrations and whether they are applied", runMigrateStatus},
+	{"seed", "create sample orders (--count N)", runSeed},
+	{"replay", "republish stored events to Kafka (--id, --type, --since)", runReplay},
+	{"outbox drain", "publish pending outbox events", runOutboxDrain},
+	{"healthcheck", "probe the running API; exit 0 if healthy (Docker HEALTHCHECK)", runHealthcheck},
+	{"config print", "print the effective config with secrets redacted", runConfigPrint},
+	{"openapi", "print the OpenAPI 3.1 document of the HTTP API", runOpenAPI},
+}
+
+// usageError faz o processo sair com código 2 (uso incorreto).
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+// Run despacha os argumentos para o subcomando e devolve o exit code.
+// Sem subcomando (ou só flags) roda `serve`, como antes.
+func Run(args []string) int {
+	if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	cmd, rest, err := lookup(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		printUsage(os.Stderr)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := cmd.run(ctx, rest); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.name, err)
+		var ue usageError
+		if errors.As(err, &ue) || errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// lookup casa o maior prefixo de palavras com um comando conhecido.
+func lookup(args []string) (command, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return commands[0], args, nil
+	}
+	for n := 2; n >= 1; n-- {
+		if len(args) < n {
+			continue
+		}
+		name := strings.Join(args[:n], " ")
+		for _, c := range commands {
+			if c.name == name {
+				return c, args[n:], nil
+			}
+		}
+	}
+	return command{}, nil, fmt.Errorf("unknown command %q", strings.Join(args, " "))
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: orders-api <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	cs := append([]command(nil), commands...)
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].name < cs[j].name })
+	for _, c := range cs {
+		fmt.Fprintf(w, "  %-16s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "every command accepts the config flags (--config, --port, --db-dsn, ...);")
+	fmt.Fprintln(w, "run `orders-api <command> -h` for details.")
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Helpers comuns
+// ──────────────────────────────────────────────────────────────────────────────
+
+// parse cria o FlagSet do subcomando já com as flags de configuração,
+// deixa `extra` registrar as específicas e devolve a config carregada.
+func parse(name string, args []string, extra func(fs *flag.FlagSet)) (*config.Config, *flag.FlagSet, error) {
+	fs := flag.NewFlagSet("orders-api "+name, flag.ContinueOnError)
+	cf := config.RegisterFlags(fs)
+	if extra != nil {
+		extra(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, nil, usageError{fmt.Sprintf("unexpected arguments: %s", strings.Join(fs.Args(), " "))}
+	}
+	cfg, err := cf.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	// o config não conhece as rotas; quem as registra confere os limites
+	if err := api.CheckLimitRoutes(cfg.Limits); err != nil {
+		return nil, nil, err
+	}
+	// logs em JSON no stderr; stdout fica livre para a saída dos comandos
+	level, _ := logging.ParseLevel(cfg.Log.Level) // já validado no Load
+	slog.SetDefault(logging.New(os.Stderr, level))
+	return cfg, fs, nil
+}
+
+// openDB abre o MySQL para comandos de manutenção (sem reset nem migração).
+func openDB(cfg *config.Config) (*sql.DB, error) {
+	return store.Open(cfg.DB.DSN, 30*time.Second)
+}
+
+// newPublisher cria o publisher Kafka da config; com tópico por tenant,
+// libera a criação dos tópicos sob demanda.
+func newPublisher(cfg *config.Config) *events.Publisher {
+	p := events.NewPublisher(cfg.Kafka.Brokers, cfg.Kafka.Topic, cfg.Kafka.ClientID)
+	if cfg.Tenancy.TopicTemplate != "" {
+		p.AllowTopicCreation()
+	}
+	return p
+}
+
+// setupTracing liga o exporter configurado; o shutdown devolvido faz flush.
+func setupTracing(ctx context.Context, cfg *config.Config) (func(), error) {
+	shutdown, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			slog.Warn("tracing shutdown", "err", err)
+		}
+	}, nil
+}
@@ -0,0 +1,14 @@
+package cli
+
+import (
+	"context"
+	"os"
+)
+
+func runConfigPrint(_ context.Context, args []string) error {
+	cfg, _, err := parse("config print", args, nil)
+	if err != nil {
+		return err
+	}
+	return cfg.Print(os.Stdout)
+}
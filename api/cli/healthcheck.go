@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// healthcheck existe porque a imagem distroless não tem curl/wget para o
+// HEALTHCHECK do Docker: faz um GET no /livez local e sai 0 ou 1. Não usa o
+// /readyz: MySQL/Kafka fora do ar ou o drain do shutdown tiram o container
+// do tráfego, mas não o tornam unhealthy (nem fazem o orquestrador reiniciá-lo).
+func runHealthcheck(ctx context.Context, args []string) error {
+	var (
+		url     string
+		timeout time.Duration
+	)
+	cfg, _, err := parse("healthcheck", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&url, "url", "", "URL to probe (default http://127.0.0.1:<port>/livez)")
+		fs.DurationVar(&timeout, "timeout", 3*time.Second, "request timeout")
+	})
+	if err != nil {
+		return err
+	}
+	if url == "" {
+		url = "http://127.0.0.1:" + cfg.HTTP.Port + "/livez"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", url, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
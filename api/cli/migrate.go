@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"orders-api/store"
+)
+
+func runMigrateUp(ctx context.Context, args []string) error {
+	cfg, _, err := parse("migrate up", args, nil)
+	if err != nil {
+		return err
+	}
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	done, err := store.MigrateUp(ctx, db)
+	for _, m := range done {
+		fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		fmt.Println("nothing to apply")
+	}
+	return nil
+}
+
+func runMigrateDown(ctx context.Context, args []string) error {
+	var (
+		steps int
+		all   bool
+	)
+	cfg, _, err := parse("migrate down", args, func(fs *flag.FlagSet) {
+		fs.IntVar(&steps, "steps", 1, "number of migrations to roll back")
+		fs.BoolVar(&all, "all", false, "roll back every applied migration")
+	})
+	if err != nil {
+		return err
+	}
+	if all {
+		steps = 0
+	} else if steps < 1 {
+		return usageError{"--steps must be >= 1 (or use --all)"}
+	}
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	done, err := store.MigrateDown(ctx, db, steps)
+	for _, m := range done {
+		fmt.Printf("rolled back %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		fmt.Println("nothing to roll back")
+	}
+	return nil
+}
+
+func runMigrateStatus(ctx context.Context, args []string) error {
+	cfg, _, err := parse("migrate status", args, nil)
+	if err != nil {
+		return err
+	}
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	st, err := store.MigrationsStatus(ctx, db)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, m := range st {
+		status, at := "pending", "-"
+		if m.AppliedAt != nil {
+			status, at = "applied", m.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", m.Version, m.Name, status, at)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"orders-api/api"
+)
+
+// runOpenAPI imprime o documento servido em /openapi.json (para gerar
+// clientes ou versionar o contrato sem subir a API).
+func runOpenAPI(_ context.Context, args []string) error {
+	cfg, _, err := parse("openapi", args, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(api.OpenAPIDocument(cfg.Tenancy.Header)))
+	return nil
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"orders-api/outbox"
+)
+
+func runOutboxDrain(ctx context.Context, args []string) error {
+	var batch int
+	cfg, _, err := parse("outbox drain", args, func(fs *flag.FlagSet) {
+		fs.IntVar(&batch, "batch", 100, "events fetched per round")
+	})
+	if err != nil {
+		return err
+	}
+
+	stopTracing, err := setupTracing(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	publisher := newPublisher(cfg)
+	defer publisher.Close()
+
+	n, err := outbox.NewRelay(db, publisher, cfg.Outbox).Drain(ctx, batch)
+	fmt.Printf("published %d event(s)\n", n)
+	return err
+}
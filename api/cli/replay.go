@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"orders-api/outbox"
+	"orders-api/store"
+)
+
+// replay republica eventos do outbox (inclusive os já publicados), útil para
+// reconstruir consumidores. As mensagens levam o header x-replay=true.
+func runReplay(ctx context.Context, args []string) error {
+	var (
+		f      store.OutboxFilter
+		since  string
+		dryRun bool
+	)
+	cfg, _, err := parse("replay", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&f.OrderID, "id", "", "only events for this order id")
+		fs.StringVar(&f.Tenant, "tenant", "", "only events of this tenant")
+		fs.StringVar(&f.Type, "type", "", "only events of this type (e.g. OrderCreated)")
+		fs.StringVar(&since, "since", "", "only events created at or after this RFC3339 time")
+		fs.IntVar(&f.Limit, "limit", 0, "maximum number of events (0 = no limit)")
+		fs.BoolVar(&dryRun, "dry-run", false, "list matching events without publishing")
+	})
+	if err != nil {
+		return err
+	}
+	if since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return usageError{fmt.Sprintf("--since: %q is not RFC3339", since)}
+		}
+		f.Since = t
+	}
+
+	stopTracing, err := setupTracing(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	evs, err := store.ListEvents(ctx, db, f)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		for _, ev := range evs {
+			fmt.Printf("%d\t%s\t%s\t%s\n", ev.ID, ev.Key, ev.Type, ev.CreatedAt.Format(time.RFC3339Nano))
+		}
+		fmt.Printf("%d event(s) would be replayed\n", len(evs))
+		return nil
+	}
+
+	publisher := newPublisher(cfg)
+	defer publisher.Close()
+
+	for i, ev := range evs {
+		hdrs := outbox.Headers(ev)
+		hdrs["x-replay"] = "true"
+		if _, err := publisher.PublishTo(ctx, ev.Topic, ev.Key, ev.Payload, hdrs); err != nil {
+			return fmt.Errorf("replay event %d (%d/%d done): %w", ev.ID, i, len(evs), err)
+		}
+	}
+	fmt.Printf("replayed %d event(s)\n", len(evs))
+	return nil
+}
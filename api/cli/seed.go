@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"orders-api/events"
+	"orders-api/outbox"
+	"orders-api/store"
+	"orders-api/tenant"
+
+	ulid "github.com/oklog/ulid/v2"
+)
+
+var (
+	seedCustomers = []string{"Acme", "Umbrella", "Initech", "Globex", "Hooli", "Stark"}
+	seedItems     = []string{"x", "y", "z", "a", "b", "c"}
+)
+
+// seed cria pedidos de exemplo pelo mesmo caminho da API (pedido + outbox)
+// e, com --publish, drena o outbox para o Kafka no final.
+func runSeed(ctx context.Context, args []string) error {
+	var (
+		count   int
+		publish bool
+		tnt     string
+	)
+	cfg, _, err := parse("seed", args, func(fs *flag.FlagSet) {
+		fs.IntVar(&count, "count", 10, "number of orders to create")
+		fs.BoolVar(&publish, "publish", true, "publish the OrderCreated events after inserting")
+		fs.StringVar(&tnt, "tenant", "", "tenant of the seeded orders (default: tenancy.default)")
+	})
+	if err != nil {
+		return err
+	}
+	if count < 1 {
+		return usageError{"--count must be >= 1"}
+	}
+	if tnt == "" {
+		tnt = cfg.Tenancy.Default
+	}
+	if !tenant.Valid(tnt) {
+		return usageError{fmt.Sprintf("--tenant: %q is not a valid tenant id", tnt)}
+	}
+
+	stopTracing, err := setupTracing(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	db, err := openDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	entropy := ulid.Monotonic(rnd, 0)
+	for i := 0; i < count; i++ {
+		now := time.Now().UTC()
+		o := store.Order{
+			ID:        ulid.MustNew(ulid.Timestamp(now), entropy).String(),
+			TenantID:  tnt,
+			Customer:  seedCustomers[rnd.Intn(len(seedCustomers))],
+			Status:    "OPEN",
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		for n := 1 + rnd.Intn(3); n > 0; n-- {
+			o.Items = append(o.Items, seedItems[rnd.Intn(len(seedItems))])
+		}
+		if err := seedOrder(ctx, db, o, ulid.MustNew(ulid.Timestamp(now), entropy).String(), tenant.Topic(cfg.Tenancy.TopicTemplate, "", tnt)); err != nil {
+			return fmt.Errorf("seed order %d/%d: %w", i+1, count, err)
+		}
+	}
+	fmt.Printf("created %d order(s)\n", count)
+
+	if !publish {
+		return nil
+	}
+	publisher := newPublisher(cfg)
+	defer publisher.Close()
+
+	n, err := outbox.NewRelay(db, publisher, cfg.Outbox).Drain(ctx, 100)
+	fmt.Printf("published %d event(s)\n", n)
+	return err
+}
+
+// seedOrder grava o pedido e o OrderCreated; o cliente é criado com
+// customerID na primeira vez que o nome aparece (sem CustomerCreated).
+func seedOrder(ctx context.Context, db *sql.DB, o store.Order, customerID, topic string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	c, _, err := store.EnsureCustomer(ctx, tx, store.Customer{
+		ID: customerID, TenantID: o.TenantID, Name: o.Customer, CreatedAt: o.CreatedAt, UpdatedAt: o.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	o.CustomerID = c.ID
+	if err := store.InsertOrder(ctx, tx, o); err != nil {
+		return err
+	}
+	evt := map[string]any{
+		"type":       "OrderCreated",
+		"id":         o.ID,
+		"tenantId":   o.TenantID,
+		"customer":   o.Customer,
+		"customerId": o.CustomerID,
+		"status":     o.Status,
+		"items":      o.Items,
+		"ts":         o.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if _, err := store.EnqueueEvent(ctx, tx, store.OutboxEvent{
+		Tenant: o.TenantID,
+		Topic:  topic,
+		Key:    tenant.EventKey(o.TenantID, o.ID),
+		Type:   "OrderCreated",
+		Headers: map[string]string{
+			events.HeaderEvent:  "OrderCreated",
+			events.HeaderTenant: o.TenantID,
+		},
+	}, evt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
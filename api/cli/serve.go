@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"orders-api/api"
+	"orders-api/auth"
+	"orders-api/bus"
+	"orders-api/lifecycle"
+	"orders-api/metrics"
+	"orders-api/outbox"
+	"orders-api/store"
+	"orders-api/tracing"
+	"orders-api/webhook"
+)
+
+func runServe(ctx context.Context, args []string) error {
+	cfg, _, err := parse("serve", args, nil)
+	if err != nil {
+		return err
+	}
+
+	// falhas de config de auth (JWKS ilegível etc.) abortam antes de abrir o DB
+	opts := api.Options{Debug: cfg.HTTP.Debug, Tenancy: cfg.Tenancy, Limits: cfg.Limits, Imports: cfg.Imports, Outbox: cfg.Outbox, Webhooks: cfg.Webhooks, Validation: cfg.HTTP.Validation}
+	if cfg.Auth.Enabled {
+		if opts.Auth, err = auth.New(cfg.Auth); err != nil {
+			return err
+		}
+	}
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return err
+	}
+
+	// DB (reset controlado por db.reset; ligado por padrão no projeto de testes)
+	db := store.MustMySQL(cfg.DB.DSN, cfg.DB.Reset)
+	metrics.RegisterDB(db)
+
+	// Kafka
+	publisher := newPublisher(cfg)
+
+	// o que for publicado também alimenta os streams SSE desta instância
+	opts.Bus = bus.New()
+	publisher.Tee(opts.Bus)
+
+	// porta do gRPC reservada antes de subir os workers em background
+	var grpcLis net.Listener
+	if cfg.GRPC.Port != "" {
+		if grpcLis, err = net.Listen("tcp", ":"+cfg.GRPC.Port); err != nil {
+			return err
+		}
+	}
+
+	// relay do outbox em background (republica o que falhou no handler)
+	relay := outbox.NewRelay(db, publisher, cfg.Outbox)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	var relayWG sync.WaitGroup
+	if cfg.Outbox.RelayInterval > 0 {
+		relayWG.Add(1)
+		go func() {
+			defer relayWG.Done()
+			relay.Run(relayCtx, cfg.Outbox.RelayInterval, cfg.Outbox.BatchSize)
+		}()
+	}
+
+	// entregas de webhook em background (retries com backoff)
+	dispatcher := webhook.NewDispatcher(db, cfg.Webhooks)
+	dispatchCtx, stopDispatch := context.WithCancel(context.Background())
+	var dispatchWG sync.WaitGroup
+	if cfg.Webhooks.DispatchInterval > 0 {
+		dispatchWG.Add(1)
+		go func() {
+			defer dispatchWG.Done()
+			dispatcher.Run(dispatchCtx)
+		}()
+	}
+
+	// API HTTP
+	apiServer := api.NewServer(db, publisher, opts)
+
+	// imports em background (POST /imports só enfileira o arquivo)
+	importCtx, stopImports := context.WithCancel(context.Background())
+	var importWG sync.WaitGroup
+	if cfg.Imports.WorkerInterval > 0 {
+		importWG.Add(1)
+		go func() {
+			defer importWG.Done()
+			apiServer.RunImports(importCtx)
+		}()
+	}
+
+	srv := &http.Server{
+		Addr:              ":" + cfg.HTTP.Port,
+		Handler:           apiServer,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	errc := make(chan error, 2)
+	go func() {
+		slog.Info("http server listening", "addr", "http://0.0.0.0:"+cfg.HTTP.Port)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			errc <- err
+		}
+	}()
+
+	// gRPC (OrdersService) na própria porta, sobre as mesmas operações
+	grpcSrv := apiServer.NewGRPCServer()
+	if grpcLis != nil {
+		go func() {
+			slog.Info("grpc server listening", "addr", "0.0.0.0:"+cfg.GRPC.Port)
+			if err := grpcSrv.Serve(grpcLis); err != nil {
+				errc <- err
+			}
+		}()
+	}
+
+	// desligamento em etapas, na ordem inversa das dependências
+	sd := cfg.Shutdown
+	var lc lifecycle.Manager
+	lc.Add("readiness", sd.ReadinessDelay+time.Second, func(ctx context.Context) error {
+		apiServer.StartDraining()
+		return lifecycle.Sleep(ctx, sd.ReadinessDelay) // tempo p/ o balanceador ver o /readyz falhar
+	})
+	lc.Add("http", sd.HTTPTimeout, func(ctx context.Context) error {
+		if err := srv.Shutdown(ctx); err != nil {
+			_ = srv.Close() // estourou o prazo: corta quem sobrou
+			return err
+		}
+		return nil
+	})
+	lc.Add("grpc", sd.HTTPTimeout, func(ctx context.Context) error {
+		// os WatchOrders já acabaram no StartDraining; espera os unários
+		done := make(chan struct{})
+		go func() {
+			grpcSrv.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			grpcSrv.Stop() // estourou o prazo: corta quem sobrou
+			return ctx.Err()
+		}
+	})
+	lc.Add("import worker", sd.CloseTimeout, func(ctx context.Context) error {
+		// antes do relay, que publica o que o último lote gravado deixou no
+		// outbox; um lote em andamento é desfeito e retomado quando o lease vencer
+		stopImports()
+		return waitGroup(ctx, &importWG)
+	})
+	lc.Add("outbox relay", sd.DrainTimeout, func(ctx context.Context) error {
+		stopRelay()
+		if err := waitGroup(ctx, &relayWG); err != nil {
+			return err
+		}
+		n, err := relay.Drain(ctx, cfg.Outbox.BatchSize)
+		slog.InfoContext(ctx, "outbox flushed", "published", n)
+		return err
+	})
+	lc.Add("webhook dispatcher", sd.CloseTimeout, func(ctx context.Context) error {
+		// POSTs em voo são cancelados e contam como tentativa com retry
+		stopDispatch()
+		return waitGroup(ctx, &dispatchWG)
+	})
+	lc.Add("kafka publisher", sd.CloseTimeout, func(context.Context) error {
+		return publisher.Close()
+	})
+	lc.Add("tracing", sd.CloseTimeout, shutdownTracing)
+	lc.Add("mysql", sd.CloseTimeout, func(context.Context) error {
+		return db.Close()
+	})
+
+	var serveErr error
+	select {
+	case serveErr = <-errc:
+		slog.Error("http server failed", "err", serveErr)
+	case <-ctx.Done():
+		slog.Info("shutdown signal received")
+	}
+
+	return errors.Join(serveErr, lc.Shutdown(context.Background()))
+}
+
+// waitGroup espera wg até o prazo da etapa: uma goroutine presa não segura o
+// shutdown além dele (as etapas seguintes ainda rodam).
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
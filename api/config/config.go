@@ -0,0 +1,769 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"orders-api/logging"
+	"orders-api/tenant"
+
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v3"
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Tipos
+// ──────────────────────────────────────────────────────────────────────────────
+
+type Config struct {
+	HTTP     HTTP     `yaml:"http"`
+	GRPC     GRPC     `yaml:"grpc"`
+	DB       DB       `yaml:"db"`
+	Kafka    Kafka    `yaml:"kafka"`
+	Log      Log      `yaml:"log"`
+	Tracing  Tracing  `yaml:"tracing"`
+	Outbox   Outbox   `yaml:"outbox"`
+	Shutdown Shutdown `yaml:"shutdown"`
+	Auth     Auth     `yaml:"auth"`
+	Tenancy  Tenancy  `yaml:"tenancy"`
+	Limits   Limits   `yaml:"limits"`
+	Webhooks Webhooks `yaml:"webhooks"`
+	Imports  Imports  `yaml:"imports"`
+}
+
+type HTTP struct {
+	Port       string `yaml:"port"`
+	Debug      bool   `yaml:"debug"`
+	Validation string `yaml:"validation"` // off|warn|strict: requests e respostas contra o OpenAPI
+}
+
+// GRPC é o servidor OrdersService, numa porta separada do HTTP.
+type GRPC struct {
+	Port string `yaml:"port"` // "" desliga o gRPC
+}
+
+type DB struct {
+	DSN   string `yaml:"dsn"`
+	Reset bool   `yaml:"reset"` // derruba e recria as tabelas das migrações no startup (só testes)
+}
+
+type Log struct {
+	Level string `yaml:"level"` // debug|info|warn|error
+}
+
+type Tracing struct {
+	Exporter     string  `yaml:"exporter"`     // none|otlp|file
+	OTLPEndpoint string  `yaml:"otlpEndpoint"` // host:port do coletor (OTLP/HTTP)
+	File         string  `yaml:"file"`         // destino do exporter "file" (JSON por linha)
+	SampleRatio  float64 `yaml:"sampleRatio"`  // 0..1
+	ServiceName  string  `yaml:"serviceName"`
+}
+
+type Outbox struct {
+	RelayInterval  time.Duration `yaml:"relayInterval"` // 0 desliga o relay em background
+	BatchSize      int           `yaml:"batchSize"`
+	MaxAttempts    int           `yaml:"maxAttempts"`    // depois disso o evento fica estacionado
+	InitialBackoff time.Duration `yaml:"initialBackoff"` // dobra a cada falha...
+	MaxBackoff     time.Duration `yaml:"maxBackoff"`     // ...até este teto
+}
+
+// Webhooks controla o dispatcher que entrega eventos às assinaturas HTTP.
+type Webhooks struct {
+	DispatchInterval time.Duration `yaml:"dispatchInterval"` // 0 desliga o dispatcher
+	BatchSize        int           `yaml:"batchSize"`
+	Timeout          time.Duration `yaml:"timeout"`        // por POST
+	MaxAttempts      int           `yaml:"maxAttempts"`    // depois disso a entrega fica failed
+	InitialBackoff   time.Duration `yaml:"initialBackoff"` // dobra a cada falha...
+	MaxBackoff       time.Duration `yaml:"maxBackoff"`     // ...até este teto
+	AllowedHosts     []string      `yaml:"allowedHosts"`   // hosts/CIDRs internos liberados (o resto da rede interna é recusado)
+}
+
+// Imports controla o worker que processa os arquivos de POST /imports.
+type Imports struct {
+	WorkerInterval time.Duration `yaml:"workerInterval"` // 0 desliga o worker
+	ChunkSize      int           `yaml:"chunkSize"`      // linhas por transação (e por escrita no Kafka)
+	MaxRows        int           `yaml:"maxRows"`        // linhas por arquivo
+	Lease          time.Duration `yaml:"lease"`          // sem progresso nesse tempo, outra réplica retoma
+	MaxAttempts    int           `yaml:"maxAttempts"`    // retomadas antes de o import falhar
+}
+
+// Shutdown controla o desligamento gracioso (cada etapa tem seu timeout).
+type Shutdown struct {
+	ReadinessDelay time.Duration `yaml:"readinessDelay"` // /readyz falhando antes de parar o HTTP
+	HTTPTimeout    time.Duration `yaml:"httpTimeout"`    // espera pelos requests em andamento
+	DrainTimeout   time.Duration `yaml:"drainTimeout"`   // flush final do outbox
+	CloseTimeout   time.Duration `yaml:"closeTimeout"`   // Kafka, tracing e MySQL
+}
+
+// Auth liga a autenticação por API key (guardadas como SHA-256) e/ou JWT
+// validado contra um JWKS local.
+type Auth struct {
+	Enabled bool     `yaml:"enabled"`
+	APIKeys []APIKey `yaml:"apiKeys"`
+	JWT     JWT      `yaml:"jwt"`
+}
+
+type APIKey struct {
+	Subject  string   `yaml:"subject"`
+	SHA256   string   `yaml:"sha256"`             // hex do SHA-256 da chave; a chave em si nunca fica na config
+	Scopes   []string `yaml:"scopes"`             // orders:read, orders:write, orders:admin
+	Customer string   `yaml:"customer,omitempty"` // se setado, a chave só enxerga pedidos desse cliente
+	Tenant   string   `yaml:"tenant,omitempty"`   // se setado, a chave fica presa a esse tenant
+	// como customer, mas pelo id do cliente: continua valendo se ele for renomeado
+	CustomerID string `yaml:"customerId,omitempty"`
+	// sem tenant, a chave só usa tenancy.default e estes ("*": qualquer um)
+	Tenants []string `yaml:"tenants,omitempty"`
+}
+
+// Scopes conhecidos pela autorização por rota (ver auth.Scope*).
+var knownScopes = []string{"orders:read", "orders:write", "orders:admin"}
+
+// Tenancy define de onde vem o tenant de cada request e para onde vão os
+// eventos dele. O claim "tenant" do JWT (ou o campo da API key) tem
+// precedência sobre o header.
+type Tenancy struct {
+	Header        string `yaml:"header"`        // header HTTP com o tenant
+	Default       string `yaml:"default"`       // tenant sem header/claim; vazio torna o tenant obrigatório
+	TopicTemplate string `yaml:"topicTemplate"` // ex.: "orders.events.{tenant}"; vazio: kafka.topic para todos
+}
+
+// Limits protege a API de clientes descontrolados: token bucket por cliente
+// (subject autenticado ou IP) e tetos de tamanho, ajustáveis por rota.
+type Limits struct {
+	Default RouteLimits            `yaml:"default"`
+	Routes  map[string]RouteLimits `yaml:"routes"` // "POST /orders", "GET /orders/{id}"...; campos zerados herdam do default
+	// Auth: bucket por IP de credenciais recusadas, checado antes de
+	// validar a credencial; só ratePerSecond e burst valem.
+	Auth RouteLimits `yaml:"auth"`
+}
+
+type RouteLimits struct {
+	RatePerSecond   float64 `yaml:"ratePerSecond"` // 0: sem limite de taxa
+	Burst           int     `yaml:"burst"`
+	MaxBodyBytes    int64   `yaml:"maxBodyBytes"`    // 0: sem limite
+	MaxItems        int     `yaml:"maxItems"`        // itens por pedido; 0: sem limite
+	MaxItemLength   int     `yaml:"maxItemLength"`   // bytes por item; 0: sem limite
+	MaxBatchEntries int     `yaml:"maxBatchEntries"` // entradas por request de lote; 0: sem limite
+}
+
+// For devolve os limites efetivos de uma rota ("MÉTODO /padrão").
+func (l Limits) For(route string) RouteLimits {
+	out := l.Default
+	r, ok := l.Routes[route]
+	if !ok {
+		return out
+	}
+	if r.RatePerSecond != 0 {
+		out.RatePerSecond, out.Burst = r.RatePerSecond, r.Burst
+	}
+	if r.Burst != 0 {
+		out.Burst = r.Burst
+	}
+	if r.MaxBodyBytes != 0 {
+		out.MaxBodyBytes = r.MaxBodyBytes
+	}
+	if r.MaxItems != 0 {
+		out.MaxItems = r.MaxItems
+	}
+	if r.MaxItemLength != 0 {
+		out.MaxItemLength = r.MaxItemLength
+	}
+	if r.MaxBatchEntries != 0 {
+		out.MaxBatchEntries = r.MaxBatchEntries
+	}
+	return out
+}
+
+type JWT struct {
+	JWKSFile string `yaml:"jwksFile"`
+	Issuer   string `yaml:"issuer"`   // vazio: não confere
+	Audience string `yaml:"audience"` // vazio: não confere
+}
+
+type Kafka struct {
+	Brokers  []string `yaml:"brokers"`
+	Topic    string   `yaml:"topic"`
+	ClientID string   `yaml:"clientId"`
+}
+
+// Default devolve a configuração usada quando nada é informado
+// (mesmos valores do docker-compose).
+func Default() Config {
+	return Config{
+		HTTP: HTTP{Port: "3000", Validation: "off"},
+		GRPC: GRPC{Port: "9090"},
+		DB: DB{
+			DSN: "app:apppass@tcp(mysql:3306)/orders?parseTime=true&charset=utf8mb4&collation=utf8mb4_0900_ai_ci",
+		},
+		Kafka: Kafka{
+			Brokers:  []string{"kafka:9092"},
+			Topic:    "orders.events",
+			ClientID: "orders-api",
+		},
+		Log: Log{Level: "info"},
+		Tracing: Tracing{
+			Exporter:     "none",
+			OTLPEndpoint: "localhost:4318",
+			File:         "traces.jsonl",
+			SampleRatio:  1,
+			ServiceName:  "orders-api",
+		},
+		Outbox: Outbox{
+			RelayInterval:  5 * time.Second,
+			BatchSize:      100,
+			MaxAttempts:    10,
+			InitialBackoff: time.Second,
+			MaxBackoff:     5 * time.Minute,
+		},
+		Shutdown: Shutdown{
+			ReadinessDelay: 2 * time.Second,
+			HTTPTimeout:    10 * time.Second,
+			DrainTimeout:   5 * time.Second,
+			CloseTimeout:   5 * time.Second,
+		},
+		Auth:    Auth{Enabled: false},
+		Tenancy: Tenancy{Header: "X-Tenant-Id", Default: "default"},
+		Webhooks: Webhooks{
+			DispatchInterval: time.Second,
+			BatchSize:        50,
+			Timeout:          10 * time.Second,
+			MaxAttempts:      8,
+			InitialBackoff:   time.Second,
+			MaxBackoff:       10 * time.Minute,
+		},
+		Imports: Imports{
+			WorkerInterval: time.Second,
+			ChunkSize:      500,
+			MaxRows:        100_000,
+			Lease:          time.Minute,
+			MaxAttempts:    3,
+		},
+		Limits: Limits{
+			Default: RouteLimits{RatePerSecond: 50, Burst: 100, MaxBodyBytes: 1 << 20},
+			Auth:    RouteLimits{RatePerSecond: 1, Burst: 20},
+			Routes: map[string]RouteLimits{
+				"POST /orders":             {RatePerSecond: 20, Burst: 20, MaxBodyBytes: 64 << 10, MaxItems: 100, MaxItemLength: 256},
+				"PUT /orders/{id}/status":  {MaxBodyBytes: 4 << 10},
+				"GET /orders/export":       {RatePerSecond: 1, Burst: 5},
+				"POST /orders:batch":       {RatePerSecond: 5, Burst: 10, MaxBodyBytes: 4 << 20, MaxBatchEntries: 500},
+				"PUT /orders/status:batch": {RatePerSecond: 5, Burst: 10, MaxBodyBytes: 1 << 20, MaxBatchEntries: 500},
+				"POST /imports":            {RatePerSecond: 1, Burst: 5, MaxBodyBytes: 32 << 20},
+			},
+		},
+	}
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Carga: defaults → arquivo YAML → env → flags
+// ──────────────────────────────────────────────────────────────────────────────
+
+// Flags registra as flags de configuração num FlagSet de subcomando;
+// depois do Parse, Load aplica a precedência completa.
+type Flags struct {
+	fs   *flag.FlagSet
+	path string
+	v    flagValues
+}
+
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs}
+	fs.StringVar(&f.path, "config", os.Getenv("CONFIG_FILE"), "path to YAML config file")
+	f.v.bind(fs)
+	return f
+}
+
+// Load monta a configuração efetiva. O arquivo vem de --config ou CONFIG_FILE;
+// env sobrescreve o arquivo e flags sobrescrevem tudo.
+func (f *Flags) Load() (*Config, error) {
+	cfg := Default()
+	if f.path != "" {
+		if err := loadFile(&cfg, f.path); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+	f.v.apply(f.fs, &cfg)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Load é o atalho para quem só precisa das flags de configuração.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("orders-api", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	f := RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return f.Load()
+}
+
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config: read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config: parse %s: %w", path, err)
+	}
+	return nil
+}
+
+func applyEnv(cfg *Config) error {
+	if v := os.Getenv("PORT"); v != "" {
+		cfg.HTTP.Port = v
+	}
+	if v, ok := os.LookupEnv("GRPC_PORT"); ok {
+		cfg.GRPC.Port = v // vazio desliga
+	}
+	if v := os.Getenv("HTTP_DEBUG"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("config: HTTP_DEBUG: invalid boolean %q", v)
+		}
+		cfg.HTTP.Debug = b
+	}
+	if v := os.Getenv("HTTP_VALIDATION"); v != "" {
+		cfg.HTTP.Validation = v
+	}
+	if v := os.Getenv("DB_DSN"); v != "" {
+		cfg.DB.DSN = v
+	}
+	if v := os.Getenv("DB_RESET"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("config: DB_RESET: invalid boolean %q", v)
+		}
+		cfg.DB.Reset = b
+	}
+	if v := os.Getenv("KAFKA_BROKERS"); v != "" {
+		cfg.Kafka.Brokers = splitList(v)
+	}
+	if v := os.Getenv("KAFKA_TOPIC"); v != "" {
+		cfg.Kafka.Topic = v
+	}
+	if v := os.Getenv("KAFKA_CLIENT_ID"); v != "" {
+		cfg.Kafka.ClientID = v
+	}
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		cfg.Log.Level = v
+	}
+	if v := os.Getenv("TRACING_EXPORTER"); v != "" {
+		cfg.Tracing.Exporter = v
+	}
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); v != "" {
+		cfg.Tracing.OTLPEndpoint = v
+	}
+	if v := os.Getenv("TRACING_FILE"); v != "" {
+		cfg.Tracing.File = v
+	}
+	if v := os.Getenv("TRACING_SAMPLE_RATIO"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("config: TRACING_SAMPLE_RATIO: invalid number %q", v)
+		}
+		cfg.Tracing.SampleRatio = f
+	}
+	if v := os.Getenv("OTEL_SERVICE_NAME"); v != "" {
+		cfg.Tracing.ServiceName = v
+	}
+	if v := os.Getenv("AUTH_ENABLED"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("config: AUTH_ENABLED: invalid boolean %q", v)
+		}
+		cfg.Auth.Enabled = b
+	}
+	if v := os.Getenv("AUTH_API_KEYS"); v != "" {
+		keys, err := parseAPIKeys(v)
+		if err != nil {
+			return fmt.Errorf("config: AUTH_API_KEYS: %w", err)
+		}
+		cfg.Auth.APIKeys = keys
+	}
+	if v := os.Getenv("AUTH_JWKS_FILE"); v != "" {
+		cfg.Auth.JWT.JWKSFile = v
+	}
+	if v := os.Getenv("AUTH_JWT_ISSUER"); v != "" {
+		cfg.Auth.JWT.Issuer = v
+	}
+	if v := os.Getenv("AUTH_JWT_AUDIENCE"); v != "" {
+		cfg.Auth.JWT.Audience = v
+	}
+	if v := os.Getenv("TENANT_HEADER"); v != "" {
+		cfg.Tenancy.Header = v
+	}
+	if v, ok := os.LookupEnv("TENANT_DEFAULT"); ok {
+		cfg.Tenancy.Default = v // vazio é válido: exige tenant em todo request
+	}
+	if v := os.Getenv("TENANT_TOPIC_TEMPLATE"); v != "" {
+		cfg.Tenancy.TopicTemplate = v
+	}
+	if v := os.Getenv("WEBHOOK_ALLOWED_HOSTS"); v != "" {
+		cfg.Webhooks.AllowedHosts = splitList(v)
+	}
+	if v := os.Getenv("RATE_LIMIT_RPS"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("config: RATE_LIMIT_RPS: invalid number %q", v)
+		}
+		cfg.Limits.Default.RatePerSecond = f
+	}
+	if v := os.Getenv("RATE_LIMIT_BURST"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("config: RATE_LIMIT_BURST: invalid integer %q", v)
+		}
+		cfg.Limits.Default.Burst = n
+	}
+	if v := os.Getenv("MAX_BODY_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("config: MAX_BODY_BYTES: invalid integer %q", v)
+		}
+		cfg.Limits.Default.MaxBodyBytes = n
+	}
+	for env, dst := range map[string]*time.Duration{
+		"OUTBOX_RELAY_INTERVAL":     &cfg.Outbox.RelayInterval,
+		"OUTBOX_INITIAL_BACKOFF":    &cfg.Outbox.InitialBackoff,
+		"OUTBOX_MAX_BACKOFF":        &cfg.Outbox.MaxBackoff,
+		"WEBHOOK_DISPATCH_INTERVAL": &cfg.Webhooks.DispatchInterval,
+		"WEBHOOK_TIMEOUT":           &cfg.Webhooks.Timeout,
+		"WEBHOOK_INITIAL_BACKOFF":   &cfg.Webhooks.InitialBackoff,
+		"WEBHOOK_MAX_BACKOFF":       &cfg.Webhooks.MaxBackoff,
+		"IMPORT_WORKER_INTERVAL":    &cfg.Imports.WorkerInterval,
+		"SHUTDOWN_READINESS_DELAY":  &cfg.Shutdown.ReadinessDelay,
+		"SHUTDOWN_HTTP_TIMEOUT":     &cfg.Shutdown.HTTPTimeout,
+		"SHUTDOWN_DRAIN_TIMEOUT":    &cfg.Shutdown.DrainTimeout,
+		"SHUTDOWN_CLOSE_TIMEOUT":    &cfg.Shutdown.CloseTimeout,
+	} {
+		if v := os.Getenv(env); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("config: %s: invalid duration %q", env, v)
+			}
+			*dst = d
+		}
+	}
+	return nil
+}
+
+// parseAPIKeys lê "subject:sha256hex[:scope+scope...]" separados por vírgula
+// (os scopes têm ':' no nome, então tudo depois do hash é a lista).
+func parseAPIKeys(s string) ([]APIKey, error) {
+	var out []APIKey
+	for _, item := range splitList(s) {
+		item, tenants, _ := strings.Cut(item, "@")
+		parts := strings.SplitN(item, ":", 3)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("%q must be subject:sha256hex[:scope+scope][@tenant+tenant]", item)
+		}
+		k := APIKey{Subject: parts[0], SHA256: parts[1]}
+		if tenants != "" {
+			k.Tenants = strings.Split(tenants, "+")
+		}
+		if len(parts) == 3 && parts[2] != "" {
+			k.Scopes = strings.Split(parts[2], "+")
+		}
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+// flagValues guarda os valores crus das flags; só as que foram
+// efetivamente passadas na linha de comando são aplicadas.
+type flagValues struct {
+	port, grpcPort, dsn, logLevel string
+	brokers, topic, clientID      string
+	tracingExporter, tracingFile  string
+	debug, reset                  bool
+}
+
+func (f *flagValues) bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.port, "port", "", "HTTP port")
+	fs.StringVar(&f.grpcPort, "grpc-port", "", "gRPC port (empty disables gRPC)")
+	fs.BoolVar(&f.debug, "http-debug", false, "log HTTP requests/responses")
+	fs.StringVar(&f.dsn, "db-dsn", "", "MySQL DSN")
+	fs.BoolVar(&f.reset, "db-reset", false, "drop and recreate tables on startup")
+	fs.StringVar(&f.brokers, "kafka-brokers", "", "comma-separated Kafka brokers")
+	fs.StringVar(&f.topic, "kafka-topic", "", "Kafka topic for order events")
+	fs.StringVar(&f.clientID, "kafka-client-id", "", "Kafka client id")
+	fs.StringVar(&f.logLevel, "log-level", "", "log level (debug, info, warn, error)")
+	fs.StringVar(&f.tracingExporter, "tracing-exporter", "", "trace exporter (none, otlp, file)")
+	fs.StringVar(&f.tracingFile, "tracing-file", "", "output file for the \"file\" trace exporter")
+}
+
+func (f *flagValues) apply(fs *flag.FlagSet, cfg *Config) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "port":
+			cfg.HTTP.Port = f.port
+		case "grpc-port":
+			cfg.GRPC.Port = f.grpcPort
+		case "http-debug":
+			cfg.HTTP.Debug = f.debug
+		case "db-dsn":
+			cfg.DB.DSN = f.dsn
+		case "db-reset":
+			cfg.DB.Reset = f.reset
+		case "kafka-brokers":
+			cfg.Kafka.Brokers = splitList(f.brokers)
+		case "kafka-topic":
+			cfg.Kafka.Topic = f.topic
+		case "kafka-client-id":
+			cfg.Kafka.ClientID = f.clientID
+		case "log-level":
+			cfg.Log.Level = f.logLevel
+		case "tracing-exporter":
+			cfg.Tracing.Exporter = f.tracingExporter
+		case "tracing-file":
+			cfg.Tracing.File = f.tracingFile
+		}
+	})
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Validação e impressão
+// ──────────────────────────────────────────────────────────────────────────────
+
+// Validate junta todos os problemas encontrados num único erro,
+// para o usuário corrigir tudo de uma vez na subida.
+func (c Config) Validate() error {
+	var errs []error
+	if n, err := strconv.Atoi(c.HTTP.Port); err != nil || n < 1 || n > 65535 {
+		errs = append(errs, fmt.Errorf("http.port: %q is not a valid TCP port", c.HTTP.Port))
+	}
+	switch c.HTTP.Validation {
+	case "off", "warn", "strict":
+	default:
+		errs = append(errs, fmt.Errorf("http.validation: %q must be off, warn or strict", c.HTTP.Validation))
+	}
+	if c.GRPC.Port != "" {
+		if n, err := strconv.Atoi(c.GRPC.Port); err != nil || n < 1 || n > 65535 {
+			errs = append(errs, fmt.Errorf("grpc.port: %q is not a valid TCP port", c.GRPC.Port))
+		} else if c.GRPC.Port == c.HTTP.Port {
+			errs = append(errs, errors.New("grpc.port: must differ from http.port"))
+		}
+	}
+	if c.DB.DSN == "" {
+		errs = append(errs, errors.New("db.dsn: required"))
+	} else if _, err := mysql.ParseDSN(c.DB.DSN); err != nil {
+		errs = append(errs, fmt.Errorf("db.dsn: %v", err))
+	}
+	if len(c.Kafka.Brokers) == 0 {
+		errs = append(errs, errors.New("kafka.brokers: at least one broker is required"))
+	}
+	for _, b := range c.Kafka.Brokers {
+		if !strings.Contains(b, ":") {
+			errs = append(errs, fmt.Errorf("kafka.brokers: %q must be host:port", b))
+		}
+	}
+	if c.Kafka.Topic == "" {
+		errs = append(errs, errors.New("kafka.topic: required"))
+	}
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %v", err))
+	}
+	switch c.Tracing.Exporter {
+	case "none":
+	case "otlp":
+		if c.Tracing.OTLPEndpoint == "" {
+			errs = append(errs, errors.New("tracing.otlpEndpoint: required when tracing.exporter=otlp"))
+		}
+	case "file":
+		if c.Tracing.File == "" {
+			errs = append(errs, errors.New("tracing.file: required when tracing.exporter=file"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter: %q is not one of none, otlp, file", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sampleRatio: %v must be between 0 and 1", c.Tracing.SampleRatio))
+	}
+	if c.Outbox.RelayInterval < 0 {
+		errs = append(errs, errors.New("outbox.relayInterval: must not be negative"))
+	}
+	if c.Outbox.BatchSize < 1 {
+		errs = append(errs, errors.New("outbox.batchSize: must be >= 1"))
+	}
+	if c.Outbox.MaxAttempts < 1 {
+		errs = append(errs, errors.New("outbox.maxAttempts: must be >= 1"))
+	}
+	if c.Outbox.InitialBackoff <= 0 || c.Outbox.MaxBackoff < c.Outbox.InitialBackoff {
+		errs = append(errs, errors.New("outbox: initialBackoff must be positive and maxBackoff >= initialBackoff"))
+	}
+	if c.Webhooks.DispatchInterval < 0 {
+		errs = append(errs, errors.New("webhooks.dispatchInterval: must not be negative"))
+	}
+	if c.Webhooks.BatchSize < 1 {
+		errs = append(errs, errors.New("webhooks.batchSize: must be >= 1"))
+	}
+	if c.Webhooks.MaxAttempts < 1 {
+		errs = append(errs, errors.New("webhooks.maxAttempts: must be >= 1"))
+	}
+	if c.Webhooks.Timeout <= 0 || c.Webhooks.InitialBackoff <= 0 || c.Webhooks.MaxBackoff < c.Webhooks.InitialBackoff {
+		errs = append(errs, errors.New("webhooks: timeout and initialBackoff must be positive and maxBackoff >= initialBackoff"))
+	}
+	for i, h := range c.Webhooks.AllowedHosts {
+		if strings.Contains(h, "/") {
+			if _, err := netip.ParsePrefix(h); err != nil {
+				errs = append(errs, fmt.Errorf("webhooks.allowedHosts[%d]: %q is not a CIDR", i, h))
+			}
+		} else if h == "" || strings.ContainsAny(h, " :") {
+			errs = append(errs, fmt.Errorf("webhooks.allowedHosts[%d]: %q is not a host name or CIDR", i, h))
+		}
+	}
+	if c.Imports.WorkerInterval < 0 {
+		errs = append(errs, errors.New("imports.workerInterval: must not be negative"))
+	}
+	if c.Imports.ChunkSize < 1 || c.Imports.MaxRows < 1 || c.Imports.MaxAttempts < 1 {
+		errs = append(errs, errors.New("imports: chunkSize, maxRows and maxAttempts must be >= 1"))
+	}
+	if c.Imports.Lease <= 0 {
+		errs = append(errs, errors.New("imports.lease: must be positive"))
+	}
+	if c.Auth.Enabled {
+		if len(c.Auth.APIKeys) == 0 && c.Auth.JWT.JWKSFile == "" {
+			errs = append(errs, errors.New("auth: enabled but neither auth.apiKeys nor auth.jwt.jwksFile is set"))
+		}
+		for i, k := range c.Auth.APIKeys {
+			if k.Subject == "" {
+				errs = append(errs, fmt.Errorf("auth.apiKeys[%d].subject: required", i))
+			}
+			if b, err := hex.DecodeString(k.SHA256); err != nil || len(b) != sha256.Size {
+				errs = append(errs, fmt.Errorf("auth.apiKeys[%d].sha256: must be 64 hex chars", i))
+			}
+			for _, sc := range k.Scopes {
+				if !slices.Contains(knownScopes, sc) {
+					errs = append(errs, fmt.Errorf("auth.apiKeys[%d].scopes: unknown scope %q (want one of %s)",
+						i, sc, strings.Join(knownScopes, ", ")))
+				}
+			}
+		}
+	}
+	if c.Tenancy.Header == "" {
+		errs = append(errs, errors.New("tenancy.header: required"))
+	}
+	if c.Tenancy.Default != "" && !tenant.Valid(c.Tenancy.Default) {
+		errs = append(errs, fmt.Errorf("tenancy.default: %q must match [A-Za-z0-9_-]{1,64}", c.Tenancy.Default))
+	}
+	if t := c.Tenancy.TopicTemplate; t != "" && !strings.Contains(t, "{tenant}") {
+		errs = append(errs, fmt.Errorf("tenancy.topicTemplate: %q must contain {tenant}", t))
+	}
+	for i, k := range c.Auth.APIKeys {
+		if k.Tenant != "" && !tenant.Valid(k.Tenant) {
+			errs = append(errs, fmt.Errorf("auth.apiKeys[%d].tenant: %q must match [A-Za-z0-9_-]{1,64}", i, k.Tenant))
+		}
+		if k.Tenant != "" && len(k.Tenants) > 0 {
+			errs = append(errs, fmt.Errorf("auth.apiKeys[%d]: set tenant or tenants, not both", i))
+		}
+		for _, t := range k.Tenants {
+			if t != "*" && !tenant.Valid(t) {
+				errs = append(errs, fmt.Errorf("auth.apiKeys[%d].tenants: %q must be \"*\" or match [A-Za-z0-9_-]{1,64}", i, t))
+			}
+		}
+		if k.Customer != "" && k.CustomerID != "" {
+			errs = append(errs, fmt.Errorf("auth.apiKeys[%d]: set customer or customerId, not both", i))
+		}
+	}
+	errs = append(errs, c.Limits.Default.validate("limits.default")...)
+	errs = append(errs, c.Limits.Auth.validate("limits.auth")...)
+	routes := make([]string, 0, len(c.Limits.Routes))
+	for route := range c.Limits.Routes {
+		routes = append(routes, route)
+	}
+	slices.Sort(routes)
+	for _, route := range routes {
+		name := fmt.Sprintf("limits.routes[%q]", route)
+		if method, path, ok := strings.Cut(route, " "); !ok || method == "" || !strings.HasPrefix(path, "/") {
+			errs = append(errs, fmt.Errorf("%s: key must be \"METHOD /path\"", name))
+		}
+		errs = append(errs, c.Limits.Routes[route].validate(name)...)
+	}
+	for _, d := range []struct {
+		name string
+		v    time.Duration
+	}{
+		{"shutdown.readinessDelay", c.Shutdown.ReadinessDelay},
+		{"shutdown.httpTimeout", c.Shutdown.HTTPTimeout},
+		{"shutdown.drainTimeout", c.Shutdown.DrainTimeout},
+		{"shutdown.closeTimeout", c.Shutdown.CloseTimeout},
+	} {
+		if d.v < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative", d.name))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (l RouteLimits) validate(name string) []error {
+	var errs []error
+	if l.RatePerSecond < 0 {
+		errs = append(errs, fmt.Errorf("%s.ratePerSecond: must not be negative", name))
+	}
+	if l.RatePerSecond > 0 && l.Burst < 1 {
+		errs = append(errs, fmt.Errorf("%s.burst: must be >= 1 when ratePerSecond is set", name))
+	}
+	if l.MaxBodyBytes < 0 || l.MaxItems < 0 || l.MaxItemLength < 0 || l.MaxBatchEntries < 0 {
+		errs = append(errs, fmt.Errorf("%s: size limits must not be negative", name))
+	}
+	return errs
+}
+
+// Redacted devolve uma cópia sem segredos (senha do DSN, hashes de API key).
+func (c Config) Redacted() Config {
+	out := c
+	out.Kafka.Brokers = append([]string(nil), c.Kafka.Brokers...)
+	out.Auth.APIKeys = make([]APIKey, len(c.Auth.APIKeys))
+	for i, k := range c.Auth.APIKeys {
+		k.SHA256 = "REDACTED"
+		out.Auth.APIKeys[i] = k
+	}
+	if dc, err := mysql.ParseDSN(c.DB.DSN); err == nil && dc.Passwd != "" {
+		dc.Passwd = "REDACTED"
+		out.DB.DSN = dc.FormatDSN()
+	}
+	return out
+}
+
+// Print escreve a configuração efetiva (já redigida) em YAML.
+func (c Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return enc.Close()
+}
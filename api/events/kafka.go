@@ -5,25 +5,98 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
 	"time"
 
+	"orders-api/bus"
+	"orders-api/logging"
+	"orders-api/metrics"
+	"orders-api/tenant"
+	"orders-api/tracing"
+
 	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Headers Kafka de contexto do request que originou o evento.
+const (
+	HeaderRequestID = "x-request-id" // X-Request-Id do HTTP
+	HeaderSubject   = "x-subject"    // subject autenticado (API key ou JWT)
+	HeaderTenant    = "x-tenant-id"  // tenant dono do pedido
+	HeaderEvent     = "x-event"      // tipo do evento (OrderCreated...)
+	HeaderOutboxID  = "x-outbox-id"  // id da linha no outbox; deduplicação no consumidor
 )
 
 type Publisher struct {
 	writer *kafka.Writer
+	client *kafka.Client // metadata (readiness)
+	topic  string        // padrão; cada mensagem pode ir para outro (tópico por tenant)
+	bus    *bus.Bus      // opcional: recebe cópia do que foi publicado (SSE)
 }
 
+// NewPublisher cria o writer sem tópico fixo: o tópico vai em cada mensagem,
+// o que permite rotear eventos por tenant.
 func NewPublisher(brokers []string, topic, clientID string) *Publisher {
+	transport := &kafka.Transport{ClientID: clientID}
 	w := &kafka.Writer{
 		Addr:         kafka.TCP(brokers...),
-		Topic:        topic,
 		Balancer:     &kafka.Hash{},    // ordenação por chave
 		RequiredAcks: kafka.RequireAll, // acks=-1
 		Async:        false,
-		Transport:    &kafka.Transport{ClientID: clientID},
+		Transport:    transport,
+		ErrorLogger: kafka.LoggerFunc(func(msg string, args ...any) {
+			slog.Error(fmt.Sprintf(msg, args...), "component", "kafka-writer")
+		}),
+	}
+	return &Publisher{
+		writer: w,
+		client: &kafka.Client{Addr: w.Addr, Transport: transport},
+		topic:  topic,
+	}
+}
+
+// AllowTopicCreation deixa o broker criar tópicos que ainda não existem
+// (tópicos por tenant nascem no primeiro evento do tenant).
+func (p *Publisher) AllowTopicCreation() {
+	p.writer.AllowAutoTopicCreation = true
+}
+
+// Tee faz cada evento do outbox publicado com sucesso ser repassado também ao
+// bus em memória.
+func (p *Publisher) Tee(b *bus.Bus) {
+	p.bus = b
+}
+
+// Topic devolve o tópico padrão dos eventos.
+func (p *Publisher) Topic() string {
+	return p.topic
+}
+
+// CheckTopic consulta o metadata do broker e falha se o tópico não existir
+// ou não tiver partições (usado pelo /readyz).
+func (p *Publisher) CheckTopic(ctx context.Context) error {
+	md, err := p.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{p.topic}})
+	if err != nil {
+		return err
+	}
+	for _, t := range md.Topics {
+		if t.Name != p.topic {
+			continue
+		}
+		if t.Error != nil {
+			return t.Error
+		}
+		if len(t.Partitions) == 0 {
+			return fmt.Errorf("topic %q has no partitions", t.Name)
+		}
+		return nil
 	}
-	return &Publisher{writer: w}
+	return fmt.Errorf("topic %q not found in metadata", p.topic)
 }
 
 func (p *Publisher) Close() error {
@@ -41,20 +114,190 @@ func (p *Publisher) PublishWithDigest(
 	headers map[string]string,
 ) (string, error) {
 	b, _ := json.Marshal(evt)
-	sum := sha256.Sum256(b)
+	return p.PublishRaw(ctx, key, b, headers)
+}
+
+// PublishRaw publica um payload já serializado no tópico padrão.
+func (p *Publisher) PublishRaw(
+	ctx context.Context,
+	key string,
+	payload []byte,
+	headers map[string]string,
+) (string, error) {
+	return p.PublishTo(ctx, "", key, payload, headers)
+}
+
+// PublishTo publica um payload já serializado (ex.: vindo do outbox) em
+// `topic` ("" → tópico padrão), calculando o x-sha256 sobre os mesmos bytes.
+func (p *Publisher) PublishTo(
+	ctx context.Context,
+	topic string,
+	key string,
+	payload []byte,
+	headers map[string]string,
+) (string, error) {
+	if topic == "" {
+		topic = p.topic
+	}
+	sum := sha256.Sum256(payload)
 	digest := hex.EncodeToString(sum[:])
 
-	var hs []kafka.Header
+	// span de producer; sem span no ctx (ex.: outbox drain), continua o trace
+	// cujo traceparent foi gravado junto com o evento
+	carrier := carrierOf(headers)
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = tracing.Propagator.Extract(ctx, carrier)
+	}
+	ctx, span := tracing.Tracer().Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingKafkaMessageKey(key),
+			semconv.MessagingOperationTypePublish,
+		),
+	)
+	defer span.End()
+	msg := p.message(ctx, topic, key, payload, carrier)
+
+	start := time.Now()
+	err := p.writer.WriteMessages(ctx, msg)
+	metrics.KafkaPublishDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.KafkaPublish.WithLabelValues("failure").Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return digest, err
+	}
+	metrics.KafkaPublish.WithLabelValues("success").Inc()
+	p.tee(key, payload, headers)
+	slog.DebugContext(ctx, "kafka message published",
+		"topic", topic, "key", key, "sha256", digest)
+	return digest, nil
+}
+
+func carrierOf(headers map[string]string) tracing.MapCarrier {
+	carrier := tracing.MapCarrier{}
 	for k, v := range headers {
-		hs = append(hs, kafka.Header{Key: k, Value: []byte(v)})
+		carrier[k] = v
 	}
-	hs = append(hs, kafka.Header{Key: "x-sha256", Value: []byte(digest)})
+	return carrier
+}
 
-	err := p.writer.WriteMessages(ctx, kafka.Message{
+// message monta a mensagem Kafka: headers do evento com o traceparent do
+// span de producer em ctx, o X-Request-Id (se quem chamou não informou) e o
+// x-sha256 do payload.
+func (p *Publisher) message(ctx context.Context, topic, key string, payload []byte, carrier tracing.MapCarrier) kafka.Message {
+	tracing.Propagator.Inject(ctx, carrier)
+
+	var hs []kafka.Header
+	for k, v := range carrier {
+		hs = append(hs, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	// correlação: segue o request até o tópico, se quem chamou não informou
+	if _, ok := carrier[HeaderRequestID]; !ok {
+		if id := logging.RequestID(ctx); id != "" {
+			hs = append(hs, kafka.Header{Key: HeaderRequestID, Value: []byte(id)})
+		}
+	}
+	sum := sha256.Sum256(payload)
+	hs = append(hs, kafka.Header{Key: "x-sha256", Value: []byte(hex.EncodeToString(sum[:]))})
+	return kafka.Message{
+		Topic:   topic,
 		Key:     []byte(key),
-		Value:   b,
+		Value:   payload,
 		Time:    time.Now(),
 		Headers: hs,
+	}
+}
+
+// Message é um evento já serializado para PublishBatch.
+type Message struct {
+	Topic   string // "" → tópico padrão
+	Key     string
+	Payload []byte
+	Headers map[string]string
+}
+
+// PublishBatch publica várias mensagens numa única escrita (o writer agrupa
+// por partição), com um span de producer para o lote. Devolve um erro por
+// mensagem, nil nas publicadas.
+func (p *Publisher) PublishBatch(ctx context.Context, msgs []Message) []error {
+	errs := make([]error, len(msgs))
+	if len(msgs) == 0 {
+		return errs
+	}
+	topic := msgs[0].Topic
+	if topic == "" {
+		topic = p.topic
+	}
+	ctx, span := tracing.Tracer().Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingBatchMessageCount(len(msgs)),
+			semconv.MessagingOperationTypePublish,
+		),
+	)
+	defer span.End()
+
+	out := make([]kafka.Message, len(msgs))
+	for i, m := range msgs {
+		t := m.Topic
+		if t == "" {
+			t = p.topic
+		}
+		out[i] = p.message(ctx, t, m.Key, m.Payload, carrierOf(m.Headers))
+	}
+
+	start := time.Now()
+	err := p.writer.WriteMessages(ctx, out...)
+	metrics.KafkaPublishDuration.Observe(time.Since(start).Seconds())
+	var werrs kafka.WriteErrors
+	switch {
+	case errors.As(err, &werrs) && len(werrs) == len(msgs):
+		copy(errs, werrs)
+	case err != nil:
+		for i := range errs {
+			errs[i] = err
+		}
+	}
+
+	failed := 0
+	for i, m := range msgs {
+		if errs[i] != nil {
+			failed++
+			metrics.KafkaPublish.WithLabelValues("failure").Inc()
+			continue
+		}
+		metrics.KafkaPublish.WithLabelValues("success").Inc()
+		p.tee(m.Key, m.Payload, m.Headers)
+	}
+	if failed > 0 {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, fmt.Sprintf("%d of %d messages failed", failed, len(msgs)))
+	}
+	slog.DebugContext(ctx, "kafka batch published", "topic", topic, "messages", len(msgs), "failed", failed)
+	return errs
+}
+
+// tee repassa ao bus os eventos que vieram do outbox (os únicos com id
+// estável para Last-Event-ID).
+func (p *Publisher) tee(key string, payload []byte, headers map[string]string) {
+	if p.bus == nil {
+		return
+	}
+	id, err := strconv.ParseInt(headers[HeaderOutboxID], 10, 64)
+	if err != nil {
+		return
+	}
+	_, orderID := tenant.SplitEventKey(key)
+	p.bus.Publish(bus.Event{
+		ID:      id,
+		Type:    headers[HeaderEvent],
+		Tenant:  headers[HeaderTenant],
+		OrderID: orderID,
+		Payload: payload,
 	})
-	return digest, err
 }
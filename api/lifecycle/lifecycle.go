@@ -0,0 +1,74 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Manager executa as etapas de desligamento na ordem em que foram
+// registradas, cada uma com seu próprio timeout. Uma etapa que falha não
+// impede as seguintes (ainda queremos fechar o DB se o Kafka travar).
+type Manager struct {
+	steps []step
+}
+
+type step struct {
+	name    string
+	timeout time.Duration
+	fn      func(ctx context.Context) error
+}
+
+// Add registra uma etapa; timeout<=0 significa sem limite próprio.
+func (m *Manager) Add(name string, timeout time.Duration, fn func(ctx context.Context) error) {
+	m.steps = append(m.steps, step{name: name, timeout: timeout, fn: fn})
+}
+
+// Shutdown roda as etapas e devolve os erros agregados.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	start := time.Now()
+	slog.InfoContext(ctx, "shutdown started", "steps", len(m.steps))
+
+	var errs []error
+	for i, st := range m.steps {
+		stepCtx, cancel := ctx, context.CancelFunc(func() {})
+		if st.timeout > 0 {
+			stepCtx, cancel = context.WithTimeout(ctx, st.timeout)
+		}
+		t0 := time.Now()
+		slog.InfoContext(ctx, "shutdown step started",
+			"step", st.name, "index", i+1, "timeout", st.timeout.String())
+
+		err := st.fn(stepCtx)
+		cancel()
+
+		elapsed := time.Since(t0).Round(time.Millisecond).String()
+		if err != nil {
+			slog.ErrorContext(ctx, "shutdown step failed", "step", st.name, "elapsed", elapsed, "err", err)
+			errs = append(errs, fmt.Errorf("%s: %w", st.name, err))
+			continue
+		}
+		slog.InfoContext(ctx, "shutdown step done", "step", st.name, "elapsed", elapsed)
+	}
+
+	slog.InfoContext(ctx, "shutdown finished",
+		"elapsed", time.Since(start).Round(time.Millisecond).String(), "failed_steps", len(errs))
+	return errors.Join(errs...)
+}
+
+// Sleep espera d ou até o contexto acabar (para atrasos entre etapas).
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "orders"
+
+// Registry próprio (em vez do global) para controlar exatamente o que é
+// exposto em /metrics.
+var Registry = prometheus.NewRegistry()
+
+// ──────────────────────────────────────────────────────────────────────────────
+// HTTP
+// ──────────────────────────────────────────────────────────────────────────────
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_rate_limited_total",
+		Help:      "Requests rejected with 429 by route.",
+	}, []string{"route"})
+
+	ContractViolations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_contract_violations_total",
+		Help:      "Requests and responses that do not match the OpenAPI document, by kind and route.",
+	}, []string{"kind", "route"})
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// gRPC
+// ──────────────────────────────────────────────────────────────────────────────
+
+var (
+	GRPCRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC calls by full method and status code.",
+	}, []string{"method", "code"})
+
+	GRPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC call latency by full method and status code (streams: connection lifetime).",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "code"})
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Kafka
+// ──────────────────────────────────────────────────────────────────────────────
+
+var (
+	KafkaPublish = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kafka_publish_total",
+		Help:      "Kafka publish attempts by result (success|failure).",
+	}, []string{"result"})
+
+	KafkaPublishDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kafka_publish_duration_seconds",
+		Help:      "Latency of synchronous Kafka writes.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	})
+)
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Outbox
+// ──────────────────────────────────────────────────────────────────────────────
+
+var OutboxParked = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "outbox_parked_total",
+	Help:      "Outbox events parked after exhausting outbox.maxAttempts.",
+})
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Negócio
+// ──────────────────────────────────────────────────────────────────────────────
+
+var (
+	OrdersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "created_total",
+		Help:      "Orders created.",
+	})
+
+	StatusTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "status_transitions_total",
+		Help:      "Order status updates by target status.",
+	}, []string{"status"})
+)
+
+// statusLabels são os status conhecidos; o status é texto livre do cliente,
+// então qualquer outro vira "other" para não abrir séries sem limite.
+var statusLabels = map[string]bool{
+	"OPEN": true, "PAID": true, "SHIPPED": true, "DONE": true, "CANCELLED": true,
+}
+
+// StatusTransition conta uma troca de status em StatusTransitions.
+func StatusTransition(status string) {
+	if !statusLabels[status] {
+		status = "other"
+	}
+	StatusTransitions.WithLabelValues(status).Inc()
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Webhooks
+// ──────────────────────────────────────────────────────────────────────────────
+
+var (
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook delivery attempts by result (success|retry|failed).",
+	}, []string{"result"})
+
+	WebhookDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_duration_seconds",
+		Help:      "Latency of webhook POSTs.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, HTTPDuration, RateLimited, ContractViolations,
+		GRPCRequests, GRPCDuration,
+		KafkaPublish, KafkaPublishDuration,
+		OutboxParked,
+		OrdersCreated, StatusTransitions,
+		WebhookDeliveries, WebhookDuration,
+	)
+}
+
+// RegisterDB expõe as estatísticas do pool (*sql.DB) como go_sql_*{db_name="orders"}.
+func RegisterDB(db *sql.DB) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, "orders"))
+}
+
+// Handler serve o Registry no formato de exposição do Prometheus.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
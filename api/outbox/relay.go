@@ -0,0 +1,226 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"orders-api/config"
+	"orders-api/events"
+	"orders-api/metrics"
+	"orders-api/store"
+)
+
+// claimLease é por quanto tempo um lote reivindicado fica reservado a este
+// relay; passa disso só se o processo morrer no meio do lote.
+const claimLease = time.Minute
+
+// Relay publica no Kafka os eventos gravados no outbox e marca o resultado.
+// Várias réplicas podem drenar juntas (ver store.ClaimEvents).
+// Um evento que falha volta depois de um backoff exponencial e, passadas
+// cfg.MaxAttempts tentativas, é estacionado para não travar os demais.
+type Relay struct {
+	db        *sql.DB
+	publisher *events.Publisher
+	cfg       config.Outbox
+}
+
+func NewRelay(db *sql.DB, publisher *events.Publisher, cfg config.Outbox) *Relay {
+	return &Relay{db: db, publisher: publisher, cfg: cfg}
+}
+
+// ErrQueued: o evento não foi publicado na hora porque um anterior da mesma
+// chave ainda está no outbox (falhando ou estacionado); o relay o publica
+// depois dele, mantendo a ordem por chave.
+var ErrQueued = errors.New("event queued behind an earlier event for the same key")
+
+// Publish envia um evento recém-gravado e registra sucesso/falha na linha do
+// outbox. O erro devolvido é o da publicação (ErrQueued se o evento ficou
+// para o relay); falhas ao marcar são anexadas.
+func (r *Relay) Publish(ctx context.Context, ev store.OutboxEvent) error {
+	claimed, err := store.ClaimHeads(ctx, r.db, []store.OutboxEvent{ev}, time.Now().UTC(), claimLease)
+	if err != nil {
+		return err
+	}
+	if len(claimed) == 0 {
+		return ErrQueued
+	}
+	_, err = r.publisher.PublishTo(ctx, ev.Topic, ev.Key, ev.Payload, Headers(ev))
+	if err != nil {
+		if mErr := r.fail(ctx, ev, err); mErr != nil {
+			return fmt.Errorf("%w (mark failed: %v)", err, mErr)
+		}
+		return err
+	}
+	return store.MarkPublished(context.WithoutCancel(ctx), r.db, ev.ID, time.Now().UTC())
+}
+
+// PublishBatch envia os eventos recém-gravados numa única escrita no Kafka e
+// marca cada linha do outbox. Devolve um erro por evento, nil nos publicados
+// e ErrQueued nos que ficaram para o relay (ver Publish).
+func (r *Relay) PublishBatch(ctx context.Context, evs []store.OutboxEvent) []error {
+	errs := make([]error, len(evs))
+	claimed, err := store.ClaimHeads(ctx, r.db, evs, time.Now().UTC(), claimLease)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	idx := make(map[int64]int, len(evs))
+	for i, ev := range evs {
+		idx[ev.ID] = i
+		errs[i] = ErrQueued
+	}
+	msgs := make([]events.Message, len(claimed))
+	for i, ev := range claimed {
+		msgs[i] = events.Message{Topic: ev.Topic, Key: ev.Key, Payload: ev.Payload, Headers: Headers(ev)}
+	}
+	perrs := r.publisher.PublishBatch(ctx, msgs)
+	now := time.Now().UTC()
+	for k, ev := range claimed {
+		i := idx[ev.ID]
+		if errs[i] = perrs[k]; errs[i] != nil {
+			if mErr := r.fail(ctx, ev, errs[i]); mErr != nil {
+				errs[i] = fmt.Errorf("%w (mark failed: %v)", errs[i], mErr)
+			}
+			continue
+		}
+		errs[i] = store.MarkPublished(context.WithoutCancel(ctx), r.db, ev.ID, now)
+	}
+	return errs
+}
+
+// Drain publica os pendentes em lotes de `batch` até esvaziar o outbox. Um
+// evento que falha segura só os seguintes da mesma chave (a ordem por chave
+// se mantém); o erro devolvido resume as falhas da rodada.
+func (r *Relay) Drain(ctx context.Context, batch int) (int, error) {
+	return r.drain(ctx, batch, time.Now().UTC())
+}
+
+// Run drena o outbox a cada `interval` até ctx acabar, republicando o que
+// falhou no caminho síncrono dos handlers. Só pega eventos com mais de
+// `interval` de idade, para não disputar com o handler que acabou de gravar
+// (a entrega continua at-least-once).
+func (r *Relay) Run(ctx context.Context, interval time.Duration, batch int) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, err := r.drain(ctx, batch, time.Now().UTC().Add(-interval)); err != nil && ctx.Err() == nil {
+				slog.WarnContext(ctx, "outbox relay round failed", "err", err)
+			}
+		}
+	}
+}
+
+func (r *Relay) drain(ctx context.Context, batch int, before time.Time) (int, error) {
+	if batch <= 0 {
+		batch = 100
+	}
+	var (
+		total, failed int
+		lastErr       error
+	)
+	for {
+		pending, err := store.ClaimEvents(ctx, r.db, before, time.Now().UTC(), claimLease, batch)
+		if err != nil {
+			return total, err
+		}
+		if len(pending) == 0 {
+			if total > 0 {
+				slog.InfoContext(ctx, "outbox drained", "published", total)
+			}
+			if failed > 0 {
+				return total, fmt.Errorf("%d outbox event(s) not published, last: %w", failed, lastErr)
+			}
+			return total, nil
+		}
+		// chaves com um evento que falhou nesta rodada: os seguintes esperam
+		// por ele, soltos do lease para não atrasar além do backoff
+		var (
+			held    = map[string]bool{}
+			skipped []store.OutboxEvent
+		)
+		for i, ev := range pending {
+			if ctx.Err() != nil {
+				r.release(ctx, append(skipped, pending[i:]...))
+				return total, ctx.Err()
+			}
+			if held[ev.Key] {
+				skipped = append(skipped, ev)
+				continue
+			}
+			_, err := r.publisher.PublishTo(ctx, ev.Topic, ev.Key, ev.Payload, Headers(ev))
+			if err == nil {
+				err = store.MarkPublished(context.WithoutCancel(ctx), r.db, ev.ID, time.Now().UTC())
+				if err != nil {
+					return total, err
+				}
+				total++
+				continue
+			}
+			if ctx.Err() != nil {
+				r.release(ctx, append(skipped, pending[i:]...))
+				return total, ctx.Err()
+			}
+			// sem conseguir marcar, a mesma linha voltaria na próxima volta
+			if mErr := r.fail(ctx, ev, err); mErr != nil {
+				return total, fmt.Errorf("publish outbox event %d: %w (mark failed: %v)", ev.ID, err, mErr)
+			}
+			held[ev.Key] = true
+			failed, lastErr = failed+1, fmt.Errorf("publish outbox event %d: %w", ev.ID, err)
+		}
+		r.release(ctx, skipped)
+	}
+}
+
+// release solta eventos reivindicados que não foram tentados, para o drain
+// do shutdown (ou outra réplica) pegar na hora em vez de esperar o lease.
+func (r *Relay) release(ctx context.Context, evs []store.OutboxEvent) {
+	if err := store.ReleaseEvents(context.WithoutCancel(ctx), r.db, evs); err != nil {
+		slog.WarnContext(ctx, "release outbox claim failed", "events", len(evs), "err", err)
+	}
+}
+
+// fail registra a tentativa que falhou: agenda a próxima com backoff ou,
+// esgotadas as tentativas, estaciona o evento.
+func (r *Relay) fail(ctx context.Context, ev store.OutboxEvent, cause error) error {
+	ctx = context.WithoutCancel(ctx)
+	now := time.Now().UTC()
+	attempts := ev.Attempts + 1
+	if attempts >= r.cfg.MaxAttempts {
+		metrics.OutboxParked.Inc()
+		slog.ErrorContext(ctx, "outbox event parked",
+			"outbox_id", ev.ID, "event", ev.Type, "key", ev.Key, "attempts", attempts, "err", cause)
+		return store.ParkEvent(ctx, r.db, ev.ID, cause, now)
+	}
+	return store.MarkFailed(ctx, r.db, ev.ID, cause, now.Add(r.backoff(attempts)))
+}
+
+// backoff é o intervalo antes da tentativa seguinte à de número `attempts`.
+func (r *Relay) backoff(attempts int) time.Duration {
+	b := r.cfg.InitialBackoff
+	for i := 1; i < attempts && b < r.cfg.MaxBackoff; i++ {
+		b *= 2
+	}
+	return min(b, r.cfg.MaxBackoff)
+}
+
+// Headers devolve os headers gravados do evento mais o x-outbox-id, que
+// identifica a linha (deduplicação e Last-Event-ID do SSE).
+func Headers(ev store.OutboxEvent) map[string]string {
+	h := make(map[string]string, len(ev.Headers)+1)
+	for k, v := range ev.Headers {
+		h[k] = v
+	}
+	h[events.HeaderOutboxID] = strconv.FormatInt(ev.ID, 10)
+	return h
+}
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Customer é o cliente dono dos pedidos. O nome é único no tenant e é
+// copiado para orders.customer, que continua servindo aos filtros e à
+// autorização por cliente.
+type Customer struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenantId"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	UpdatedBy string    `json:"updatedBy,omitempty"`
+}
+
+// ErrConflict: nome de cliente já usado no tenant, ou cliente com pedidos
+// na hora de apagar.
+var ErrConflict = errors.New("conflict")
+
+// Querier é satisfeito por *sql.DB e *sql.Tx.
+type Querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const customerColumns = "id, tenant_id, name, email, created_at, updated_at, created_by, updated_by"
+
+// InsertCustomer grava um cliente novo (ErrConflict se o nome já existe).
+func InsertCustomer(ctx context.Context, db Execer, c Customer) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO customers (`+customerColumns+`) VALUES (?,?,?,?,?,?,?,?)`,
+		c.ID, c.TenantID, c.Name, nullString(c.Email), c.CreatedAt, c.UpdatedAt, nullString(c.CreatedBy), nullString(c.UpdatedBy))
+	return conflict(err)
+}
+
+// EnsureCustomer devolve o cliente com o nome de c no tenant, criando-o se
+// ainda não existir (created=true). Dois pedidos simultâneos com o mesmo
+// nome novo acabam no mesmo cliente: o segundo INSERT espera o primeiro.
+func EnsureCustomer(ctx context.Context, tx *sql.Tx, c Customer) (Customer, bool, error) {
+	res, err := tx.ExecContext(ctx, `INSERT INTO customers (`+customerColumns+`) VALUES (?,?,?,?,?,?,?,?)
+		ON DUPLICATE KEY UPDATE id = id`,
+		c.ID, c.TenantID, c.Name, nullString(c.Email), c.CreatedAt, c.UpdatedAt, nullString(c.CreatedBy), nullString(c.UpdatedBy))
+	if err != nil {
+		return Customer{}, false, err
+	}
+	if n, _ := res.RowsAffected(); n == 1 {
+		return c, true, nil
+	}
+	existing, err := scanCustomer(tx.QueryRowContext(ctx,
+		`SELECT `+customerColumns+` FROM customers WHERE tenant_id=? AND name=? FOR SHARE`, c.TenantID, c.Name))
+	return existing, false, err
+}
+
+// GetCustomer devolve o cliente do tenant (ErrNotFound se não existir).
+func GetCustomer(ctx context.Context, db Querier, tenant, id string) (Customer, error) {
+	return getCustomer(ctx, db, "", tenant, id)
+}
+
+// LockCustomer é o GetCustomer dentro de tx, travando a linha até o commit
+// (FOR SHARE para ligar um pedido, FOR UPDATE para alterar o cliente).
+func LockCustomer(ctx context.Context, tx *sql.Tx, tenant, id string, forUpdate bool) (Customer, error) {
+	lock := " FOR SHARE"
+	if forUpdate {
+		lock = " FOR UPDATE"
+	}
+	return getCustomer(ctx, tx, lock, tenant, id)
+}
+
+func getCustomer(ctx context.Context, db Querier, lock, tenant, id string) (Customer, error) {
+	c, err := scanCustomer(db.QueryRowContext(ctx,
+		`SELECT `+customerColumns+` FROM customers WHERE id=? AND tenant_id=?`+lock, id, tenant))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Customer{}, ErrNotFound
+	}
+	return c, err
+}
+
+// CustomerFilter restringe ListCustomers ao cliente de um principal preso a
+// ele, pelo id ou pelo nome.
+type CustomerFilter struct {
+	ID   string
+	Name string
+}
+
+// ListCustomers lista os clientes do tenant por nome, restritos pelo filtro.
+func ListCustomers(ctx context.Context, db *sql.DB, tenant string, f CustomerFilter, limit, offset int) ([]Customer, error) {
+	q := `SELECT ` + customerColumns + ` FROM customers WHERE tenant_id=?`
+	args := []any{tenant}
+	if f.ID != "" {
+		q += ` AND id=?`
+		args = append(args, f.ID)
+	}
+	if f.Name != "" {
+		q += ` AND name=?`
+		args = append(args, f.Name)
+	}
+	q += ` ORDER BY name, id LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Customer{}
+	for rows.Next() {
+		c, err := scanCustomer(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// UpdateCustomer grava nome e email e leva o nome novo para a cópia em
+// orders.customer, na mesma transação.
+func UpdateCustomer(ctx context.Context, tx *sql.Tx, c Customer) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE customers SET name=?, email=?, updated_at=?, updated_by=? WHERE id=? AND tenant_id=?`,
+		c.Name, nullString(c.Email), c.UpdatedAt, nullString(c.UpdatedBy), c.ID, c.TenantID); err != nil {
+		return conflict(err)
+	}
+	_, err := tx.ExecContext(ctx, `UPDATE orders SET customer=? WHERE customer_id=? AND customer<>BINARY ?`, c.Name, c.ID, c.Name)
+	return err
+}
+
+// DeleteCustomer apaga o cliente; ErrConflict se algum pedido aponta para ele.
+func DeleteCustomer(ctx context.Context, db Execer, tenant, id string) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM customers WHERE id=? AND tenant_id=?`, id, tenant)
+	if err != nil {
+		return conflict(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanCustomer(sc scanner) (Customer, error) {
+	var (
+		c                           Customer
+		email, createdBy, updatedBy sql.NullString
+	)
+	if err := sc.Scan(&c.ID, &c.TenantID, &c.Name, &email, &c.CreatedAt, &c.UpdatedAt, &createdBy, &updatedBy); err != nil {
+		return Customer{}, err
+	}
+	c.Email, c.CreatedBy, c.UpdatedBy = email.String, createdBy.String, updatedBy.String
+	return c, nil
+}
+
+// conflict traduz chave duplicada (1062) e FK em uso (1451) para ErrConflict.
+func conflict(err error) error {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && (me.Number == 1062 || me.Number == 1451) {
+		return ErrConflict
+	}
+	return err
+}
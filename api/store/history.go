@@ -0,0 +1,18 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// RecordStatus fecha a passagem atual do pedido pelo status anterior (se
+// houver) e abre uma nova em status, na mesma transação da mudança.
+func RecordStatus(ctx context.Context, db Execer, tenant, orderID, status, by string, at time.Time) error {
+	if _, err := db.ExecContext(ctx,
+		`UPDATE order_status_history SET left_at=? WHERE order_id=? AND left_at IS NULL`, at, orderID); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO order_status_history (order_id, tenant_id, status, entered_at, changed_by)
+		VALUES (?,?,?,?,?)`, orderID, tenant, status, at, nullString(by))
+	return err
+}
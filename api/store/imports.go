@@ -0,0 +1,220 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Estados de um import.
+const (
+	ImportQueued    = "queued"
+	ImportRunning   = "running"
+	ImportCompleted = "completed" // pode ter linhas recusadas
+	ImportFailed    = "failed"    // desistiu no meio; o que foi gravado fica
+)
+
+// Import é um arquivo de POST /imports e o progresso do worker sobre ele.
+type Import struct {
+	ID            string     `json:"id"`
+	TenantID      string     `json:"tenantId"`
+	Status        string     `json:"status"`
+	Format        string     `json:"format"`
+	Filename      string     `json:"filename,omitempty"`
+	Header        string     `json:"-"` // linha de cabeçalho do CSV
+	TotalRows     int        `json:"totalRows"`
+	ProcessedRows int        `json:"processedRows"`
+	CreatedRows   int        `json:"createdRows"`
+	RejectedRows  int        `json:"rejectedRows"`
+	Attempts      int        `json:"-"`
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	StartedAt     *time.Time `json:"startedAt,omitempty"`
+	FinishedAt    *time.Time `json:"finishedAt,omitempty"`
+	CreatedBy     string     `json:"createdBy,omitempty"`
+}
+
+// ImportJob é um import reivindicado pelo worker, com o arquivo, o principal
+// de quem enviou (JSON, nil sem auth) e os headers de correlação do upload.
+type ImportJob struct {
+	Import
+	Content   []byte
+	Principal []byte
+	Headers   map[string]string
+}
+
+// ImportReject é uma linha recusada, com o conteúdo original (CSV ou JSON).
+type ImportReject struct {
+	Line    int    `json:"line"`
+	Content string `json:"-"`
+	Error   string `json:"error"`
+}
+
+const importColumns = `id, tenant_id, status, format, filename, header, total_rows, processed_rows, created_rows, rejected_rows,
+	attempts, error, created_at, updated_at, started_at, finished_at, created_by`
+
+func InsertImport(ctx context.Context, db Execer, job ImportJob) error {
+	headers, err := json.Marshal(job.Headers)
+	if err != nil {
+		return err
+	}
+	var principal any // NULL sem auth
+	if job.Principal != nil {
+		principal = string(job.Principal)
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO imports
+		(id, tenant_id, status, format, filename, header, content, total_rows, principal, headers, created_at, updated_at, created_by)
+		VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+		job.ID, job.TenantID, job.Status, job.Format, nullString(job.Filename), nullString(job.Header), job.Content, job.TotalRows,
+		principal, string(headers), job.CreatedAt, job.UpdatedAt, nullString(job.CreatedBy))
+	return err
+}
+
+// GetImport devolve o import do tenant (ErrNotFound se não existir).
+func GetImport(ctx context.Context, db *sql.DB, tenant, id string) (Import, error) {
+	im, err := scanImport(db.QueryRowContext(ctx,
+		`SELECT `+importColumns+` FROM imports WHERE id=? AND tenant_id=?`, id, tenant))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Import{}, ErrNotFound
+	}
+	return im, err
+}
+
+// ClaimImport reivindica um import na fila, ou em andamento cujo lease
+// venceu (a réplica que o processava parou), e estende o lease. Devolve nil
+// se não houver nenhum.
+func ClaimImport(ctx context.Context, db *sql.DB, now time.Time, lease time.Duration) (*ImportJob, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var (
+		job       ImportJob
+		principal sql.NullString
+		headers   []byte
+	)
+	row := tx.QueryRowContext(ctx, `SELECT `+importColumns+`, content, principal, headers FROM imports
+		WHERE status=? OR (status=? AND lease_until < ?)
+		ORDER BY created_at LIMIT 1
+		FOR UPDATE SKIP LOCKED`, ImportQueued, ImportRunning, now)
+	job.Import, err = scanImport(row, &job.Content, &principal, &headers)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if principal.Valid {
+		job.Principal = []byte(principal.String)
+	}
+	_ = json.Unmarshal(headers, &job.Headers)
+
+	if _, err := tx.ExecContext(ctx, `UPDATE imports
+		SET status=?, attempts=attempts+1, lease_until=?, started_at=COALESCE(started_at, ?), updated_at=?
+		WHERE id=?`, ImportRunning, now.Add(lease), now, now, job.ID); err != nil {
+		return nil, err
+	}
+	job.Status = ImportRunning
+	job.Attempts++
+	return &job, tx.Commit()
+}
+
+// RecordImportChunk grava as recusas de um lote e o progresso acumulado,
+// na mesma transação dos pedidos do lote: um import retomado continua
+// exatamente depois do último lote gravado.
+func RecordImportChunk(ctx context.Context, db Execer, im Import, rejects []ImportReject, leaseUntil, now time.Time) error {
+	for _, r := range rejects {
+		if _, err := db.ExecContext(ctx, `INSERT INTO import_rejects (import_id, line, content, error) VALUES (?,?,?,?)`,
+			im.ID, r.Line, r.Content, truncate(r.Error, 1024)); err != nil {
+			return err
+		}
+	}
+	_, err := db.ExecContext(ctx, `UPDATE imports
+		SET processed_rows=?, created_rows=?, rejected_rows=?, lease_until=?, updated_at=?
+		WHERE id=?`, im.ProcessedRows, im.CreatedRows, im.RejectedRows, leaseUntil, now, im.ID)
+	return err
+}
+
+// FinishImport encerra o import como completed ou failed (com a causa).
+func FinishImport(ctx context.Context, db Execer, id, status, cause string, now time.Time) error {
+	_, err := db.ExecContext(ctx, `UPDATE imports
+		SET status=?, error=?, lease_until=NULL, updated_at=?, finished_at=?
+		WHERE id=?`, status, nullString(cause), now, now, id)
+	return err
+}
+
+// ListImportRejects devolve as primeiras `limit` recusas, na ordem do arquivo.
+func ListImportRejects(ctx context.Context, db *sql.DB, id string, limit int) ([]ImportReject, error) {
+	out := []ImportReject{}
+	err := EachImportReject(ctx, db, id, limit, func(r ImportReject) error {
+		out = append(out, r)
+		return nil
+	})
+	return out, err
+}
+
+// EachImportReject percorre as recusas conforme chegam do cursor (limit 0:
+// todas), para o arquivo de rejeitos não precisar caber em memória.
+func EachImportReject(ctx context.Context, db *sql.DB, id string, limit int, fn func(ImportReject) error) error {
+	q := `SELECT line, content, error FROM import_rejects WHERE import_id=? ORDER BY line`
+	args := []any{id}
+	if limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r ImportReject
+		if err := rows.Scan(&r.Line, &r.Content, &r.Error); err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func scanImport(sc scanner, extra ...any) (Import, error) {
+	var (
+		im                    Import
+		filename, header      sql.NullString
+		cause, by             sql.NullString
+		startedAt, finishedAt sql.NullTime
+	)
+	dest := append([]any{&im.ID, &im.TenantID, &im.Status, &im.Format, &filename, &header, &im.TotalRows, &im.ProcessedRows,
+		&im.CreatedRows, &im.RejectedRows, &im.Attempts, &cause, &im.CreatedAt, &im.UpdatedAt, &startedAt, &finishedAt, &by}, extra...)
+	if err := sc.Scan(dest...); err != nil {
+		return Import{}, err
+	}
+	im.Filename, im.Header, im.Error, im.CreatedBy = filename.String, header.String, cause.String, by.String
+	if startedAt.Valid {
+		t := startedAt.Time
+		im.StartedAt = &t
+	}
+	if finishedAt.Valid {
+		t := finishedAt.Time
+		im.FinishedAt = &t
+	}
+	return im, nil
+}
+
+// truncate corta s em n bytes sem partir um caractere UTF-8 no meio.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,241 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// Migration é um par up/down versionado pelo prefixo numérico do arquivo
+// (ex.: 0001_create_orders.up.sql).
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus indica se uma migração já foi aplicada e quando.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// createTable acha as tabelas criadas por um script up.
+var createTable = regexp.MustCompile("(?i)CREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?`?(\\w+)`?")
+
+// MigrationTables lista as tabelas que as migrações criam, mais o histórico
+// schema_migrations: as únicas que o ResetSchema derruba.
+func MigrationTables() ([]string, error) {
+	ms, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	tables := []string{"schema_migrations"}
+	for _, m := range ms {
+		for _, match := range createTable.FindAllStringSubmatch(m.Up, -1) {
+			if !slices.Contains(tables, match[1]) {
+				tables = append(tables, match[1])
+			}
+		}
+	}
+	return tables, nil
+}
+
+// Migrations lê as migrações embutidas em ordem crescente de versão.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		var dir string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			dir = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			dir = "down"
+		default:
+			continue
+		}
+		prefix, rest, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected <version>_<name>.<up|down>.sql", name)
+		}
+		v, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version %q", name, prefix)
+		}
+		b, err := migrationsFS.ReadFile("migrations/" + name)
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[v]
+		if m == nil {
+			m = &Migration{Version: v, Name: strings.TrimSuffix(rest, "."+dir+".sql")}
+			byVersion[v] = m
+		}
+		if dir == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s: missing up or down file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+func ensureMigrationsTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INT          PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		applied_at DATETIME(6)  NOT NULL
+	) ENGINE=InnoDB`)
+	return err
+}
+
+func appliedVersions(ctx context.Context, db *sql.DB) (map[int]time.Time, error) {
+	if err := ensureMigrationsTable(ctx, db); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[int]time.Time{}
+	for rows.Next() {
+		var (
+			v  int
+			at time.Time
+		)
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		out[v] = at
+	}
+	return out, rows.Err()
+}
+
+// MigrationsStatus lista todas as migrações conhecidas com seu estado.
+func MigrationsStatus(ctx context.Context, db *sql.DB) ([]MigrationStatus, error) {
+	all, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, 0, len(all))
+	for _, m := range all {
+		st := MigrationStatus{Migration: m}
+		if at, ok := applied[m.Version]; ok {
+			at := at
+			st.AppliedAt = &at
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// MigrateUp aplica todas as migrações pendentes e devolve as aplicadas.
+func MigrateUp(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	st, err := MigrationsStatus(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, m := range st {
+		if m.AppliedAt != nil {
+			continue
+		}
+		if err := execScript(ctx, db, m.Up); err != nil {
+			return done, fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+		}
+		if _, err := db.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?,?,?)`,
+			m.Version, m.Name, time.Now().UTC()); err != nil {
+			return done, err
+		}
+		done = append(done, m.Migration)
+	}
+	return done, nil
+}
+
+// MigrateDown desfaz as últimas `steps` migrações aplicadas (steps<=0 → todas).
+func MigrateDown(ctx context.Context, db *sql.DB, steps int) ([]Migration, error) {
+	st, err := MigrationsStatus(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(st) - 1; i >= 0; i-- {
+		if steps > 0 && len(done) == steps {
+			break
+		}
+		m := st[i]
+		if m.AppliedAt == nil {
+			continue
+		}
+		if err := execScript(ctx, db, m.Down); err != nil {
+			return done, fmt.Errorf("migration %04d_%s down: %w", m.Version, m.Name, err)
+		}
+		if _, err := db.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version=?`, m.Version); err != nil {
+			return done, err
+		}
+		done = append(done, m.Migration)
+	}
+	return done, nil
+}
+
+// execScript roda um arquivo com várias instruções separadas por ';' no fim
+// da linha (o driver não habilita multiStatements por padrão).
+func execScript(ctx context.Context, db *sql.DB, script string) error {
+	var stmt strings.Builder
+	flush := func() error {
+		q := strings.TrimSuffix(strings.TrimSpace(stmt.String()), ";")
+		stmt.Reset()
+		if q == "" {
+			return nil
+		}
+		_, err := db.ExecContext(ctx, q)
+		return err
+	}
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		stmt.WriteString(line)
+		stmt.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
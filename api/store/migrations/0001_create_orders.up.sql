@@ -0,0 +1,10 @@
+CREATE TABLE orders (
+	id         CHAR(26)     PRIMARY KEY,
+	customer   VARCHAR(255) NOT NULL,
+	status     VARCHAR(32)  NOT NULL,
+	items_json JSON         NOT NULL,
+	created_at DATETIME(6)  NOT NULL,
+	updated_at DATETIME(6)  NOT NULL,
+	KEY idx_status (status),
+	KEY idx_created (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,15 @@
+-- payload fica em LONGBLOB (não JSON) para preservar os bytes exatos
+-- usados no digest x-sha256.
+CREATE TABLE outbox (
+	id           BIGINT       AUTO_INCREMENT PRIMARY KEY,
+	event_key    VARCHAR(64)  NOT NULL,
+	event_type   VARCHAR(64)  NOT NULL,
+	payload      LONGBLOB     NOT NULL,
+	headers_json JSON         NOT NULL,
+	created_at   DATETIME(6)  NOT NULL,
+	published_at DATETIME(6)  NULL,
+	attempts     INT          NOT NULL DEFAULT 0,
+	last_error   TEXT         NULL,
+	KEY idx_outbox_pending (published_at, id),
+	KEY idx_outbox_key (event_key)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
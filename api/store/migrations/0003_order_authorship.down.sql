@@ -0,0 +1,3 @@
+ALTER TABLE orders
+	DROP COLUMN updated_by,
+	DROP COLUMN created_by;
@@ -0,0 +1,3 @@
+ALTER TABLE orders
+	ADD COLUMN created_by VARCHAR(255) NULL AFTER updated_at,
+	ADD COLUMN updated_by VARCHAR(255) NULL AFTER created_by;
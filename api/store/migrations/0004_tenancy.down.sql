@@ -0,0 +1,8 @@
+ALTER TABLE outbox
+	DROP COLUMN topic,
+	DROP COLUMN tenant_id,
+	MODIFY COLUMN event_key VARCHAR(64) NOT NULL;
+
+ALTER TABLE orders
+	DROP KEY idx_tenant_created,
+	DROP COLUMN tenant_id;
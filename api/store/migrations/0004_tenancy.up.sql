@@ -0,0 +1,10 @@
+-- pedidos existentes ficam no tenant "default" (o padrão de tenancy.default).
+ALTER TABLE orders
+	ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' AFTER id,
+	ADD KEY idx_tenant_created (tenant_id, created_at);
+
+-- event_key passa a ser "<tenant>/<id>"; topic NULL usa kafka.topic.
+ALTER TABLE outbox
+	MODIFY COLUMN event_key VARCHAR(128) NOT NULL,
+	ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default' AFTER id,
+	ADD COLUMN topic VARCHAR(255) NULL AFTER event_type;
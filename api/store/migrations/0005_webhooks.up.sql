@@ -0,0 +1,31 @@
+CREATE TABLE webhooks (
+	id          CHAR(26)      PRIMARY KEY,
+	tenant_id   VARCHAR(64)   NOT NULL,
+	url         VARCHAR(2048) NOT NULL,
+	event_types JSON          NOT NULL,
+	secret      VARCHAR(255)  NOT NULL,
+	active      BOOLEAN       NOT NULL DEFAULT TRUE,
+	created_at  DATETIME(6)   NOT NULL,
+	created_by  VARCHAR(255)  NULL,
+	KEY idx_webhooks_tenant (tenant_id, active)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- uma linha por (webhook, evento do outbox); o dispatcher reivindica as
+-- pendentes vencidas e registra o resultado da última tentativa.
+CREATE TABLE webhook_deliveries (
+	id               BIGINT       AUTO_INCREMENT PRIMARY KEY,
+	webhook_id       CHAR(26)     NOT NULL,
+	outbox_id        BIGINT       NOT NULL,
+	event_type       VARCHAR(64)  NOT NULL,
+	status           VARCHAR(16)  NOT NULL,
+	attempts         INT          NOT NULL DEFAULT 0,
+	next_attempt_at  DATETIME(6)  NOT NULL,
+	last_status_code INT          NULL,
+	last_error       TEXT         NULL,
+	created_at       DATETIME(6)  NOT NULL,
+	updated_at       DATETIME(6)  NOT NULL,
+	delivered_at     DATETIME(6)  NULL,
+	UNIQUE KEY uq_delivery (webhook_id, outbox_id),
+	KEY idx_deliveries_due (status, next_attempt_at),
+	CONSTRAINT fk_delivery_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,5 @@
+ALTER TABLE orders
+	DROP KEY idx_tenant_updated;
+
+ALTER TABLE orders
+	DROP KEY ft_orders_customer;
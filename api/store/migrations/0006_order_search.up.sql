@@ -0,0 +1,7 @@
+-- busca full-text em customer (?q=) e filtro por updated_at (?updated_since=).
+-- O índice FULLTEXT vai num ALTER só dele (o InnoDB recria a tabela).
+ALTER TABLE orders
+	ADD FULLTEXT KEY ft_orders_customer (customer);
+
+ALTER TABLE orders
+	ADD KEY idx_tenant_updated (tenant_id, updated_at);
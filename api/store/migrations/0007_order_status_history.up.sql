@@ -0,0 +1,18 @@
+-- uma linha por passagem de um pedido por um status; left_at NULL é o status
+-- atual. Alimenta o tempo médio em cada status de GET /orders/stats.
+CREATE TABLE order_status_history (
+	id         BIGINT       AUTO_INCREMENT PRIMARY KEY,
+	order_id   CHAR(26)     NOT NULL,
+	tenant_id  VARCHAR(64)  NOT NULL,
+	status     VARCHAR(32)  NOT NULL,
+	entered_at DATETIME(6)  NOT NULL,
+	left_at    DATETIME(6)  NULL,
+	changed_by VARCHAR(255) NULL,
+	KEY idx_history_order (order_id, left_at),
+	KEY idx_history_tenant (tenant_id, status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- pedidos existentes entram só com o status atual: o histórico anterior
+-- não foi guardado.
+INSERT INTO order_status_history (order_id, tenant_id, status, entered_at, changed_by)
+	SELECT id, tenant_id, status, updated_at, updated_by FROM orders;
@@ -0,0 +1,38 @@
+-- imports em massa (POST /imports): o arquivo fica no banco para qualquer
+-- réplica poder processar; o progresso anda junto com cada lote gravado,
+-- então um import interrompido retoma de processed_rows.
+CREATE TABLE imports (
+	id             CHAR(26)     PRIMARY KEY,
+	tenant_id      VARCHAR(64)  NOT NULL,
+	status         VARCHAR(16)  NOT NULL,
+	format         VARCHAR(16)  NOT NULL,
+	filename       VARCHAR(255) NULL,
+	header         TEXT         NULL, -- cabeçalho do CSV, repetido no arquivo de rejeitos
+	content        LONGBLOB     NOT NULL,
+	total_rows     INT          NOT NULL,
+	processed_rows INT          NOT NULL DEFAULT 0,
+	created_rows   INT          NOT NULL DEFAULT 0,
+	rejected_rows  INT          NOT NULL DEFAULT 0,
+	attempts       INT          NOT NULL DEFAULT 0,
+	lease_until    DATETIME(6)  NULL,
+	error          TEXT         NULL,
+	principal      JSON         NULL,
+	headers        JSON         NOT NULL,
+	created_at     DATETIME(6)  NOT NULL,
+	updated_at     DATETIME(6)  NOT NULL,
+	started_at     DATETIME(6)  NULL,
+	finished_at    DATETIME(6)  NULL,
+	created_by     VARCHAR(255) NULL,
+	KEY idx_imports_due (status, lease_until),
+	KEY idx_imports_tenant (tenant_id, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- linhas recusadas, com o conteúdo original para o arquivo de rejeitos
+CREATE TABLE import_rejects (
+	import_id CHAR(26)      NOT NULL,
+	line      INT           NOT NULL,
+	content   TEXT          NOT NULL,
+	error     VARCHAR(1024) NOT NULL,
+	PRIMARY KEY (import_id, line),
+	CONSTRAINT fk_reject_import FOREIGN KEY (import_id) REFERENCES imports (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,6 @@
+ALTER TABLE orders
+	DROP FOREIGN KEY fk_orders_customer,
+	DROP KEY idx_orders_customer,
+	DROP COLUMN customer_id;
+
+DROP TABLE IF EXISTS customers;
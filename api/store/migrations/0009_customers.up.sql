@@ -0,0 +1,32 @@
+-- clientes como recurso: o pedido passa a apontar para customers.id e
+-- orders.customer fica como cópia do nome (filtros, busca, autorização).
+-- O nome é único no tenant, sem diferenciar maiúsculas (collation ai_ci).
+CREATE TABLE customers (
+	id         CHAR(26)     PRIMARY KEY,
+	tenant_id  VARCHAR(64)  NOT NULL,
+	name       VARCHAR(255) NOT NULL,
+	email      VARCHAR(255) NULL,
+	created_at DATETIME(6)  NOT NULL,
+	updated_at DATETIME(6)  NOT NULL,
+	created_by VARCHAR(255) NULL,
+	updated_by VARCHAR(255) NULL,
+	UNIQUE KEY uq_customers_name (tenant_id, name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- um cliente por nome já usado em pedidos. O id sai de um hash do tenant e
+-- do nome: 26 caracteres hexadecimais, que também são um ULID válido.
+INSERT INTO customers (id, tenant_id, name, created_at, updated_at)
+	SELECT CONCAT('0', UPPER(LEFT(SHA2(CONCAT(tenant_id, '/', MIN(customer)), 256), 25))),
+		tenant_id, MIN(customer), MIN(created_at), MIN(created_at)
+	FROM orders GROUP BY tenant_id, customer;
+
+ALTER TABLE orders
+	ADD COLUMN customer_id CHAR(26) NULL AFTER tenant_id;
+
+UPDATE orders o JOIN customers c ON c.tenant_id = o.tenant_id AND c.name = o.customer
+	SET o.customer_id = c.id;
+
+ALTER TABLE orders
+	MODIFY COLUMN customer_id CHAR(26) NOT NULL,
+	ADD KEY idx_orders_customer (customer_id, created_at),
+	ADD CONSTRAINT fk_orders_customer FOREIGN KEY (customer_id) REFERENCES customers (id);
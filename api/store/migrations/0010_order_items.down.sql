@@ -0,0 +1,18 @@
+ALTER TABLE orders ADD COLUMN items_json JSON NULL AFTER status;
+
+-- remonta o array na ordem de position (JSON_ARRAYAGG como janela ordenada)
+UPDATE orders o JOIN (
+	SELECT order_id, items FROM (
+		SELECT order_id,
+			JSON_ARRAYAGG(sku) OVER (PARTITION BY order_id ORDER BY position
+				ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING) AS items,
+			ROW_NUMBER() OVER (PARTITION BY order_id ORDER BY position) AS rn
+		FROM order_items) w
+	WHERE rn = 1) i ON i.order_id = o.id
+	SET o.items_json = i.items;
+
+UPDATE orders SET items_json = JSON_ARRAY() WHERE items_json IS NULL;
+
+ALTER TABLE orders MODIFY COLUMN items_json JSON NOT NULL;
+
+DROP TABLE IF EXISTS order_items;
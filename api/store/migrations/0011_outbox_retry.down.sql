@@ -0,0 +1,3 @@
+ALTER TABLE outbox
+	DROP COLUMN parked_at,
+	DROP COLUMN next_attempt_at;
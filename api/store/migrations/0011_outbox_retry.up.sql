@@ -0,0 +1,6 @@
+-- retry por evento: quem falha espera next_attempt_at (backoff) e, depois de
+-- outbox.maxAttempts tentativas, fica estacionado em parked_at sem travar o
+-- resto do outbox.
+ALTER TABLE outbox
+	ADD COLUMN next_attempt_at DATETIME(6) NULL AFTER attempts,
+	ADD COLUMN parked_at       DATETIME(6) NULL AFTER next_attempt_at;
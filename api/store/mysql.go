@@ -3,99 +3,285 @@ package store
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/go-sql-driver/mysql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 type Order struct {
-	ID        string    `json:"id"`
-	Customer  string    `json:"customer"`
-	Status    string    `json:"status"`
-	Items     []string  `json:"items"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenantId"`
+	Customer   string    `json:"customer"`
+	CustomerID string    `json:"customerId"`
+	Status     string    `json:"status"`
+	Items      []string  `json:"items"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	CreatedBy  string    `json:"createdBy,omitempty"` // subject autenticado
+	UpdatedBy  string    `json:"updatedBy,omitempty"`
 }
 
-// MustMySQL abre conexão com MySQL, espera o DB ficar pronto e
-// opcionalmente derruba/cria a tabela orders (reset=true).
-func MustMySQL(dsn string, reset bool) *sql.DB {
-	db, err := sql.Open("mysql", dsn)
+// OrderColumns é a lista de colunas na ordem esperada por ScanOrder/ScanOrders.
+// Os itens ficam em order_items: veja LoadOrderItems.
+const OrderColumns = "id, tenant_id, customer, customer_id, status, created_at, updated_at, created_by, updated_by"
+
+// MaxItemLength é o tamanho de order_items.sku, que vale acima de qualquer
+// maxItemLength da config.
+const MaxItemLength = 512
+
+// Execer é satisfeito por *sql.DB e *sql.Tx, para as funções de escrita
+// poderem rodar dentro ou fora de uma transação.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Open abre conexão com MySQL e espera o DB ficar pronto (até `wait`).
+func Open(dsn string, wait time.Duration) (*sql.DB, error) {
+	// otelsql cria um span por query/exec/tx (no-op se o tracing estiver desligado)
+	db, err := otelsql.Open("mysql", dsn,
+		otelsql.WithAttributes(semconv.DBSystemMySQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{DisableErrSkip: true, OmitConnResetSession: true}),
+	)
 	if err != nil {
-		log.Fatalf("open mysql: %v", err)
+		return nil, fmt.Errorf("open mysql: %w", err)
 	}
 
 	// espera o MySQL ficar pronto
-	deadline := time.Now().Add(60 * time.Second)
+	deadline := time.Now().Add(wait)
 	for {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		err = db.PingContext(ctx)
 		cancel()
 		if err == nil {
-			break
+			slog.Info("mysql ready")
+			return db, nil
 		}
 		if time.Now().After(deadline) {
-			log.Fatalf("mysql ping timeout: %v", err)
+			_ = db.Close()
+			return nil, fmt.Errorf("mysql ping timeout: %w", err)
 		}
+		slog.Debug("waiting for mysql", "err", err)
 		time.Sleep(500 * time.Millisecond)
 	}
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
 
+// MustMySQL abre conexão com MySQL, espera o DB ficar pronto e aplica as
+// migrações pendentes. Com reset=true derruba tudo antes (projeto de testes).
+func MustMySQL(dsn string, reset bool) *sql.DB {
+	db, err := Open(dsn, 60*time.Second)
+	if err != nil {
+		fatal("open mysql", err)
+	}
+
+	ctx := context.Background()
 	if reset {
-		if _, err := db.Exec(`DROP TABLE IF EXISTS orders`); err != nil {
-			log.Fatalf("drop table orders: %v", err)
+		if err := ResetSchema(ctx, db); err != nil {
+			fatal("reset schema", err)
 		}
+		slog.Warn("database schema reset", "reason", "db.reset=true")
+	}
+	done, err := MigrateUp(ctx, db)
+	if err != nil {
+		fatal("apply migrations", err)
+	}
+	for _, m := range done {
+		slog.Info("migration applied", "version", m.Version, "name", m.Name)
+	}
+
+	return db
+}
+
+// ResetSchema derruba as tabelas criadas pelas migrações (inclusive o
+// histórico), deixando-as para o MigrateUp recriar; outras tabelas do mesmo
+// database ficam intactas. Não depende dos scripts down, que nem sempre
+// rodam num banco vazio (ALTER TABLE).
+func ResetSchema(ctx context.Context, db *sql.DB) error {
+	names, err := MigrationTables()
+	if err != nil {
+		return err
+	}
+	tables := make([]string, len(names))
+	for i, t := range names {
+		tables[i] = "`" + t + "`"
+	}
+
+	// FOREIGN_KEY_CHECKS é por sessão: fixa uma conexão para o DROP
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SET FOREIGN_KEY_CHECKS=0`); err != nil {
+		return err
+	}
+	defer func() { _, _ = conn.ExecContext(context.WithoutCancel(ctx), `SET FOREIGN_KEY_CHECKS=1`) }()
+	_, err = conn.ExecContext(ctx, `DROP TABLE IF EXISTS `+strings.Join(tables, ", "))
+	return err
+}
+
+// InsertOrder grava um pedido novo e os seus itens, na ordem, em order_items
+// (rode dentro de uma transação).
+func InsertOrder(ctx context.Context, db Execer, o Order) error {
+	if _, err := db.ExecContext(ctx, `INSERT INTO orders (id, tenant_id, customer, customer_id, status, created_at, updated_at, created_by, updated_by)
+		VALUES (?,?,?,?,?,?,?,?,?)`,
+		o.ID, o.TenantID, o.Customer, o.CustomerID, o.Status, o.CreatedAt, o.UpdatedAt, nullString(o.CreatedBy), nullString(o.UpdatedBy)); err != nil {
+		return err
+	}
+	if len(o.Items) == 0 {
+		return nil
+	}
+	args := make([]any, 0, 4*len(o.Items))
+	for i, sku := range o.Items {
+		args = append(args, o.ID, i, o.TenantID, sku)
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO order_items (order_id, position, tenant_id, sku) VALUES `+
+		placeholders(len(o.Items), "(?,?,?,?)"), args...)
+	return err
+}
 
-		ddl := `
-		CREATE TABLE orders (
-			id         CHAR(26)     PRIMARY KEY,
-			customer   VARCHAR(255) NOT NULL,
-			status     VARCHAR(32)  NOT NULL,
-			items_json JSON         NOT NULL,
-			created_at DATETIME(6)  NOT NULL,
-			updated_at DATETIME(6)  NOT NULL,
-			KEY idx_status (status),
-			KEY idx_created (created_at)
-		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;`
-		if _, err := db.Exec(ddl); err != nil {
-			log.Fatalf("apply ddl: %v", err)
+// Queryer é satisfeito por *sql.DB, *sql.Tx e *sql.Conn.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// LoadOrderItems preenche Items de uma página de pedidos com uma consulta
+// só em order_items (não uma por pedido). Pedido sem itens fica com [].
+func LoadOrderItems(ctx context.Context, db Queryer, orders []Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	byID := make(map[string]int, len(orders))
+	args := make([]any, len(orders))
+	for i := range orders {
+		orders[i].Items = []string{}
+		byID[orders[i].ID] = i
+		args[i] = orders[i].ID
+	}
+	rows, err := db.QueryContext(ctx, `SELECT order_id, sku FROM order_items WHERE order_id IN (`+
+		placeholders(len(orders), "?")+`) ORDER BY order_id, position`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, sku string
+		if err := rows.Scan(&id, &sku); err != nil {
+			return err
+		}
+		if i, ok := byID[id]; ok {
+			orders[i].Items = append(orders[i].Items, sku)
 		}
 	}
+	return rows.Err()
+}
 
-	return db
+// OrdersWithItemsQuery monta o SELECT dos pedidos que passam por where, na
+// ordem orderBy, com uma linha por item, para EachOrderWithItems: um cursor
+// só, para quem não pode abrir outra consulta na mesma conexão (exportação
+// num snapshot). where e orderBy usam as colunas de orders sem prefixo.
+func OrdersWithItemsQuery(where, orderBy string) string {
+	cols := strings.Split(OrderColumns, ", ")
+	for i, c := range cols {
+		cols[i] = "o." + c
+	}
+	return "SELECT " + strings.Join(cols, ", ") + ", i.sku FROM (SELECT " + OrderColumns + " FROM orders WHERE " + where + ") o" +
+		" LEFT JOIN order_items i ON i.order_id = o.id ORDER BY " + orderBy + ", i.position"
 }
 
-func ScanOrder(row *sql.Row) (*Order, error) {
+func placeholders(n int, one string) string {
+	return strings.TrimSuffix(strings.Repeat(one+",", n), ",")
+}
+
+// scanner é satisfeito por *sql.Row e *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOrder(sc scanner, extra ...any) (Order, error) {
 	var (
-		o         Order
-		itemsJSON []byte
+		o                    Order
+		createdBy, updatedBy sql.NullString
 	)
-	err := row.Scan(&o.ID, &o.Customer, &o.Status, &itemsJSON, &o.CreatedAt, &o.UpdatedAt)
+	dest := append([]any{&o.ID, &o.TenantID, &o.Customer, &o.CustomerID, &o.Status, &o.CreatedAt, &o.UpdatedAt, &createdBy, &updatedBy}, extra...)
+	if err := sc.Scan(dest...); err != nil {
+		return Order{}, err
+	}
+	o.CreatedBy, o.UpdatedBy = createdBy.String, updatedBy.String
+	return o, nil
+}
+
+func ScanOrder(row *sql.Row) (*Order, error) {
+	o, err := scanOrder(row)
 	if err != nil {
 		return nil, err
 	}
-	_ = json.Unmarshal(itemsJSON, &o.Items)
 	return &o, nil
 }
 
 func ScanOrders(rows *sql.Rows) ([]Order, error) {
-	var out []Order
+	out := []Order{} // lista vazia sai como [], não null
 	for rows.Next() {
-		var (
-			o         Order
-			itemsJSON []byte
-		)
-		if err := rows.Scan(&o.ID, &o.Customer, &o.Status, &itemsJSON, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		o, err := scanOrder(rows)
+		if err != nil {
 			return nil, err
 		}
-		_ = json.Unmarshal(itemsJSON, &o.Items)
 		out = append(out, o)
 	}
 	return out, rows.Err()
 }
 
+// EachOrderWithItems lê um cursor de OrdersWithItemsQuery e chama fn para
+// cada pedido, com os itens, conforme ele chega, sem juntar a lista em
+// memória como ScanOrders.
+func EachOrderWithItems(rows *sql.Rows, fn func(Order) error) error {
+	var (
+		cur     Order
+		pending bool // cur ainda não foi entregue a fn
+	)
+	for rows.Next() {
+		var sku sql.NullString
+		o, err := scanOrder(rows, &sku)
+		if err != nil {
+			return err
+		}
+		if !pending || o.ID != cur.ID {
+			if pending {
+				if err := fn(cur); err != nil {
+					return err
+				}
+			}
+			cur, pending = o, true
+			cur.Items = []string{}
+		}
+		if sku.Valid {
+			cur.Items = append(cur.Items, sku.String)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if pending {
+		return fn(cur)
+	}
+	return nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
 // Helpers para distinguir erro de "não encontrado" se quiser reutilizar
 var ErrNotFound = errors.New("not found")
 
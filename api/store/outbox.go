@@ -0,0 +1,292 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// OutboxEvent é um evento gravado na mesma transação da mudança de estado.
+// Payload guarda os bytes exatos publicados (o digest é calculado sobre eles).
+type OutboxEvent struct {
+	ID          int64
+	Tenant      string
+	Topic       string // "" → tópico padrão do publisher
+	Key         string
+	Type        string
+	Payload     []byte
+	Headers     map[string]string
+	CreatedAt   time.Time
+	PublishedAt *time.Time
+	Attempts    int
+}
+
+// OutboxFilter restringe a leitura de eventos (replay e retomada do SSE).
+type OutboxFilter struct {
+	OrderID   string
+	Tenant    string
+	AfterID   int64 // só ids maiores (retomada de SSE)
+	Published bool  // só eventos já publicados
+	Type      string
+	Since     time.Time
+	Limit     int
+}
+
+// EnqueueEvent serializa evt e grava no outbox como pendente. ev traz
+// tenant, tópico, chave, tipo e headers; o resto é preenchido aqui. Na mesma
+// transação agenda a entrega para os webhooks que assinam o tipo.
+func EnqueueEvent(ctx context.Context, db Execer, ev OutboxEvent, evt any) (OutboxEvent, error) {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+	if ev.Headers == nil {
+		ev.Headers = map[string]string{}
+	}
+	hdrJSON, err := json.Marshal(ev.Headers)
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, `INSERT INTO outbox (tenant_id, event_key, event_type, topic, payload, headers_json, created_at)
+		VALUES (?,?,?,?,?,?,?)`, ev.Tenant, ev.Key, ev.Type, nullString(ev.Topic), payload, string(hdrJSON), now)
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return OutboxEvent{}, err
+	}
+	ev.ID, ev.Payload, ev.CreatedAt = id, payload, now
+	if err := enqueueDeliveries(ctx, db, ev); err != nil {
+		return OutboxEvent{}, err
+	}
+	return ev, nil
+}
+
+// ClaimEvents reivindica até `limit` eventos ainda não publicados criados até
+// `before`, em ordem, empurrando o next_attempt_at deles por `lease` para
+// outra réplica não publicar os mesmos (SKIP LOCKED evita esperar pelos que
+// outra réplica está reivindicando). Ficam de fora os estacionados, os que
+// esperam o backoff ou o lease até depois de `now` e os que vêm atrás de um
+// desses na mesma chave (a ordem por chave se mantém; as outras seguem).
+func ClaimEvents(ctx context.Context, db *sql.DB, before, now time.Time, lease time.Duration, limit int) ([]OutboxEvent, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `SELECT `+outboxColumns+`
+		FROM outbox o
+		WHERE o.published_at IS NULL AND o.parked_at IS NULL AND o.created_at <= ?
+			AND (o.next_attempt_at IS NULL OR o.next_attempt_at <= ?)
+			AND NOT EXISTS (SELECT 1 FROM outbox b
+				WHERE b.event_key = o.event_key AND b.id < o.id
+					AND b.published_at IS NULL AND b.parked_at IS NULL AND b.next_attempt_at > ?)
+		ORDER BY o.id LIMIT ?
+		FOR UPDATE OF o SKIP LOCKED`, before, now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	out, err := scanOutbox(rows)
+	rows.Close()
+	if err != nil || len(out) == 0 {
+		return nil, err
+	}
+
+	ids := make([]any, 0, len(out)+1)
+	ids = append(ids, now.Add(lease))
+	for _, ev := range out {
+		ids = append(ids, ev.ID)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE outbox SET next_attempt_at=? WHERE id IN (`+
+		strings.TrimSuffix(strings.Repeat("?,", len(out)), ",")+`)`, ids...); err != nil {
+		return nil, err
+	}
+	return out, tx.Commit()
+}
+
+// ClaimHeads reivindica, dentre evs (recém-gravados, em ordem), os que são
+// os próximos da fila da chave: nenhum evento anterior da mesma chave segue
+// sem publicar, pendente ou estacionado (os de evs não contam, são
+// publicados em ordem por quem chamou). Os demais ficam para o relay.
+func ClaimHeads(ctx context.Context, db *sql.DB, evs []OutboxEvent, now time.Time, lease time.Duration) ([]OutboxEvent, error) {
+	if len(evs) == 0 {
+		return nil, nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	in := strings.TrimSuffix(strings.Repeat("?,", len(evs)), ",")
+	args := make([]any, 0, 2*len(evs)+1)
+	for _, ev := range evs {
+		args = append(args, ev.ID)
+	}
+	args = append(args, now)
+	args = append(args, args[:len(evs)]...)
+	rows, err := tx.QueryContext(ctx, `SELECT o.id FROM outbox o
+		WHERE o.id IN (`+in+`) AND o.published_at IS NULL AND o.parked_at IS NULL
+			AND (o.next_attempt_at IS NULL OR o.next_attempt_at <= ?)
+			AND NOT EXISTS (SELECT 1 FROM outbox b
+				WHERE b.event_key = o.event_key AND b.id < o.id
+					AND b.published_at IS NULL AND b.id NOT IN (`+in+`))
+		FOR UPDATE OF o SKIP LOCKED`, args...)
+	if err != nil {
+		return nil, err
+	}
+	free := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		free[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// um evento preso segura os seguintes da mesma chave dentro de evs
+	var (
+		out  []OutboxEvent
+		ids  = []any{now.Add(lease)}
+		held = map[string]bool{}
+	)
+	for _, ev := range evs {
+		if held[ev.Key] || !free[ev.ID] {
+			held[ev.Key] = true
+			continue
+		}
+		out = append(out, ev)
+		ids = append(ids, ev.ID)
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE outbox SET next_attempt_at=? WHERE id IN (`+
+		strings.TrimSuffix(strings.Repeat("?,", len(out)), ",")+`)`, ids...); err != nil {
+		return nil, err
+	}
+	return out, tx.Commit()
+}
+
+// ReleaseEvents devolve ao outbox eventos reivindicados que não chegaram a
+// ser tentados (relay interrompido), sem esperar o lease vencer.
+func ReleaseEvents(ctx context.Context, db Execer, evs []OutboxEvent) error {
+	if len(evs) == 0 {
+		return nil
+	}
+	ids := make([]any, len(evs))
+	for i, ev := range evs {
+		ids[i] = ev.ID
+	}
+	_, err := db.ExecContext(ctx, `UPDATE outbox SET next_attempt_at=NULL
+		WHERE published_at IS NULL AND id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(evs)), ",")+`)`, ids...)
+	return err
+}
+
+// ListEvents devolve eventos (publicados ou não) que casam com o filtro.
+func ListEvents(ctx context.Context, db *sql.DB, f OutboxFilter) ([]OutboxEvent, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	if f.OrderID != "" {
+		// chaves antigas são só o id; as novas, "<tenant>/<id>"
+		conds = append(conds, "(event_key = ? OR event_key LIKE ?)")
+		args = append(args, f.OrderID, "%/"+f.OrderID)
+	}
+	if f.Tenant != "" {
+		conds = append(conds, "tenant_id = ?")
+		args = append(args, f.Tenant)
+	}
+	if f.AfterID > 0 {
+		conds = append(conds, "id > ?")
+		args = append(args, f.AfterID)
+	}
+	if f.Published {
+		conds = append(conds, "published_at IS NOT NULL")
+	}
+	if f.Type != "" {
+		conds = append(conds, "event_type = ?")
+		args = append(args, f.Type)
+	}
+	if !f.Since.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, f.Since)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + outboxColumns + " FROM outbox")
+	if len(conds) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(conds, " AND "))
+	}
+	sb.WriteString(" ORDER BY id")
+	if f.Limit > 0 {
+		sb.WriteString(" LIMIT ?")
+		args = append(args, f.Limit)
+	}
+
+	rows, err := db.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanOutbox(rows)
+}
+
+// MarkPublished registra a publicação bem-sucedida.
+func MarkPublished(ctx context.Context, db Execer, id int64, at time.Time) error {
+	_, err := db.ExecContext(ctx, `UPDATE outbox SET published_at=?, attempts=attempts+1, last_error=NULL WHERE id=?`, at, id)
+	return err
+}
+
+// MarkFailed registra uma tentativa que falhou; o evento continua pendente
+// e só volta a ser tentado a partir de `next`.
+func MarkFailed(ctx context.Context, db Execer, id int64, cause error, next time.Time) error {
+	_, err := db.ExecContext(ctx, `UPDATE outbox SET attempts=attempts+1, last_error=?, next_attempt_at=? WHERE id=?`,
+		cause.Error(), next, id)
+	return err
+}
+
+// ParkEvent registra a última tentativa e estaciona o evento: o relay não o
+// tenta mais (um `replay --id` ainda o publica).
+func ParkEvent(ctx context.Context, db Execer, id int64, cause error, at time.Time) error {
+	_, err := db.ExecContext(ctx, `UPDATE outbox SET attempts=attempts+1, last_error=?, parked_at=? WHERE id=?`,
+		cause.Error(), at, id)
+	return err
+}
+
+const outboxColumns = "id, tenant_id, event_key, event_type, topic, payload, headers_json, created_at, published_at, attempts"
+
+func scanOutbox(rows *sql.Rows) ([]OutboxEvent, error) {
+	var out []OutboxEvent
+	for rows.Next() {
+		var (
+			e       OutboxEvent
+			hdrJSON []byte
+			topic   sql.NullString
+			pubAt   sql.NullTime
+		)
+		if err := rows.Scan(&e.ID, &e.Tenant, &e.Key, &e.Type, &topic, &e.Payload, &hdrJSON, &e.CreatedAt, &pubAt, &e.Attempts); err != nil {
+			return nil, err
+		}
+		e.Topic = topic.String
+		_ = json.Unmarshal(hdrJSON, &e.Headers)
+		if pubAt.Valid {
+			t := pubAt.Time
+			e.PublishedAt = &t
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,245 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Estados de uma entrega de webhook.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed" // esgotou as tentativas
+)
+
+// Webhook é uma assinatura de um parceiro: recebe por HTTP os eventos do
+// tenant cujos tipos estão em EventTypes.
+type Webhook struct {
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenantId"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"eventTypes"`
+	Secret     string    `json:"-"` // só aparece na resposta da criação
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"createdAt"`
+	CreatedBy  string    `json:"createdBy,omitempty"`
+}
+
+// WebhookDelivery é o log de entrega de um evento (OutboxID) a um webhook,
+// com o resultado da última tentativa.
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      string     `json:"webhookId"`
+	OutboxID       int64      `json:"eventId"`
+	EventType      string     `json:"eventType"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"` // só enquanto pending
+	LastStatusCode int        `json:"lastStatusCode,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+}
+
+// DueDelivery é uma entrega reivindicada pelo dispatcher, com o que ele
+// precisa para fazer o POST.
+type DueDelivery struct {
+	WebhookDelivery
+	URL     string
+	Secret  string
+	Payload []byte
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Assinaturas
+// ──────────────────────────────────────────────────────────────────────────────
+
+const webhookColumns = "id, tenant_id, url, event_types, secret, active, created_at, created_by"
+
+func InsertWebhook(ctx context.Context, db Execer, w Webhook) error {
+	types, err := json.Marshal(w.EventTypes)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO webhooks (`+webhookColumns+`) VALUES (?,?,?,?,?,?,?,?)`,
+		w.ID, w.TenantID, w.URL, string(types), w.Secret, w.Active, w.CreatedAt, nullString(w.CreatedBy))
+	return err
+}
+
+// GetWebhook devolve o webhook do tenant (ErrNotFound se não existir).
+func GetWebhook(ctx context.Context, db *sql.DB, tenant, id string) (Webhook, error) {
+	w, err := scanWebhook(db.QueryRowContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE id=? AND tenant_id=?`, id, tenant))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Webhook{}, ErrNotFound
+	}
+	return w, err
+}
+
+func ListWebhooks(ctx context.Context, db *sql.DB, tenant string) ([]Webhook, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE tenant_id=? ORDER BY created_at, id`, tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+// DeleteWebhook remove a assinatura e, em cascata, o log de entregas.
+func DeleteWebhook(ctx context.Context, db *sql.DB, tenant, id string) error {
+	res, err := db.ExecContext(ctx, `DELETE FROM webhooks WHERE id=? AND tenant_id=?`, id, tenant)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func scanWebhook(sc scanner) (Webhook, error) {
+	var (
+		w         Webhook
+		types     []byte
+		createdBy sql.NullString
+	)
+	if err := sc.Scan(&w.ID, &w.TenantID, &w.URL, &types, &w.Secret, &w.Active, &w.CreatedAt, &createdBy); err != nil {
+		return Webhook{}, err
+	}
+	_ = json.Unmarshal(types, &w.EventTypes)
+	w.CreatedBy = createdBy.String
+	return w, nil
+}
+
+// ──────────────────────────────────────────────────────────────────────────────
+// Entregas
+// ──────────────────────────────────────────────────────────────────────────────
+
+// enqueueDeliveries cria uma entrega pendente para cada webhook ativo do
+// tenant que assina o tipo do evento. Roda na transação do EnqueueEvent.
+func enqueueDeliveries(ctx context.Context, db Execer, ev OutboxEvent) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO webhook_deliveries
+		(webhook_id, outbox_id, event_type, status, attempts, next_attempt_at, created_at, updated_at)
+		SELECT id, ?, ?, ?, 0, ?, ?, ? FROM webhooks
+		WHERE tenant_id=? AND active AND JSON_CONTAINS(event_types, JSON_QUOTE(?))`,
+		ev.ID, ev.Type, DeliveryPending, ev.CreatedAt, ev.CreatedAt, ev.CreatedAt, ev.Tenant, ev.Type)
+	return err
+}
+
+const deliveryColumns = `d.id, d.webhook_id, d.outbox_id, d.event_type, d.status, d.attempts, d.next_attempt_at,
+	d.last_status_code, d.last_error, d.created_at, d.updated_at, d.delivered_at`
+
+// ListDeliveries devolve o log de entregas de um webhook, mais recentes antes.
+func ListDeliveries(ctx context.Context, db *sql.DB, webhookID string, limit int) ([]WebhookDelivery, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries d
+		WHERE d.webhook_id=? ORDER BY d.id DESC LIMIT ?`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// ClaimDeliveries reivindica até `limit` entregas vencidas, empurrando o
+// next_attempt_at delas por `lease` para outra réplica não pegar as mesmas
+// (SKIP LOCKED evita esperar pelas que outra réplica está reivindicando).
+func ClaimDeliveries(ctx context.Context, db *sql.DB, now time.Time, lease time.Duration, limit int) ([]DueDelivery, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `SELECT `+deliveryColumns+`, w.url, w.secret, o.payload
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		JOIN outbox o ON o.id = d.outbox_id
+		WHERE d.status=? AND d.next_attempt_at <= ?
+		ORDER BY d.id LIMIT ?
+		FOR UPDATE OF d SKIP LOCKED`, DeliveryPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	var out []DueDelivery
+	for rows.Next() {
+		var due DueDelivery
+		d, err := scanDelivery(rows, &due.URL, &due.Secret, &due.Payload)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due.WebhookDelivery = d
+		out = append(out, due)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, d := range out {
+		if _, err := tx.ExecContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at=? WHERE id=?`,
+			now.Add(lease), d.ID); err != nil {
+			return nil, err
+		}
+	}
+	return out, tx.Commit()
+}
+
+// RecordAttempt grava o resultado de uma tentativa: status final ou pending
+// com a próxima tentativa em `next`.
+func RecordAttempt(ctx context.Context, db Execer, id int64, status string, code int, cause string, next, now time.Time) error {
+	var deliveredAt sql.NullTime
+	if status == DeliverySucceeded {
+		deliveredAt = sql.NullTime{Time: now, Valid: true}
+	}
+	_, err := db.ExecContext(ctx, `UPDATE webhook_deliveries
+		SET status=?, attempts=attempts+1, last_status_code=?, last_error=?, next_attempt_at=?, updated_at=?, delivered_at=?
+		WHERE id=?`,
+		status, sql.NullInt64{Int64: int64(code), Valid: code != 0}, nullString(cause), next, now, deliveredAt, id)
+	return err
+}
+
+func scanDelivery(rows *sql.Rows, extra ...any) (WebhookDelivery, error) {
+	var (
+		d           WebhookDelivery
+		next        time.Time
+		code        sql.NullInt64
+		lastErr     sql.NullString
+		deliveredAt sql.NullTime
+	)
+	dest := append([]any{&d.ID, &d.WebhookID, &d.OutboxID, &d.EventType, &d.Status, &d.Attempts, &next,
+		&code, &lastErr, &d.CreatedAt, &d.UpdatedAt, &deliveredAt}, extra...)
+	if err := rows.Scan(dest...); err != nil {
+		return WebhookDelivery{}, err
+	}
+	if d.Status == DeliveryPending {
+		d.NextAttemptAt = &next
+	}
+	d.LastStatusCode, d.LastError = int(code.Int64), lastErr.String
+	if deliveredAt.Valid {
+		t := deliveredAt.Time
+		d.DeliveredAt = &t
+	}
+	return d, nil
+}
@@ -0,0 +1,52 @@
+package tenant
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// ids viram parte da chave Kafka e, opcionalmente, do nome do tópico; por isso
+// o alfabeto restrito.
+var idRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Valid diz se id pode ser usado como tenant.
+func Valid(id string) bool {
+	return idRe.MatchString(id)
+}
+
+type ctxKey struct{}
+
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext devolve o tenant resolvido para o request ("" se nenhum).
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// EventKey é a chave Kafka dos eventos de um pedido: "<tenant>/<orderID>".
+// Mantém a ordenação por pedido e separa tenants que reusem ids.
+func EventKey(tenant, orderID string) string {
+	return tenant + "/" + orderID
+}
+
+// Topic resolve o tópico de um tenant a partir do template (ex.:
+// "orders.events.{tenant}"); template vazio usa o tópico padrão.
+func Topic(template, fallback, tenant string) string {
+	if template == "" {
+		return fallback
+	}
+	return strings.ReplaceAll(template, "{tenant}", tenant)
+}
+
+// SplitEventKey separa uma chave de evento em tenant e id do pedido (chaves
+// antigas, sem tenant, devolvem só o id).
+func SplitEventKey(key string) (tenant, orderID string) {
+	if i := strings.LastIndexByte(key, '/'); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return "", key
+}
@@ -0,0 +1,203 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"orders-api/config"
+	"orders-api/metrics"
+	"orders-api/store"
+	"orders-api/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Headers enviados em cada entrega. A assinatura é
+// "sha256=" + hex(HMAC-SHA256(secret, "<timestamp>.<corpo>")).
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign calcula o valor do header X-Webhook-Signature.
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// maxErrorBody limita quanto da resposta do parceiro vai para o last_error.
+const maxErrorBody = 512
+
+// Dispatcher entrega as entregas pendentes do MySQL, com retry e backoff
+// exponencial. Várias réplicas podem rodar juntas (ver ClaimDeliveries).
+type Dispatcher struct {
+	db     *sql.DB
+	client *http.Client
+	cfg    config.Webhooks
+}
+
+func NewDispatcher(db *sql.DB, cfg config.Webhooks) *Dispatcher {
+	// sem proxy (o proxy faria a conexão por nós, fora do Guard) e sem seguir
+	// redirects: um 3xx para a rede interna vira só uma tentativa que falhou
+	transport := &http.Transport{
+		DialContext:           NewGuard(cfg.AllowedHosts).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &Dispatcher{
+		db: db,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		cfg: cfg,
+	}
+}
+
+// Run despacha a cada DispatchInterval até ctx acabar.
+func (d *Dispatcher) Run(ctx context.Context) {
+	t := time.NewTicker(d.cfg.DispatchInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, err := d.Dispatch(ctx); err != nil && ctx.Err() == nil {
+				slog.WarnContext(ctx, "webhook dispatch round failed", "err", err)
+			}
+		}
+	}
+}
+
+// Dispatch entrega lotes de entregas vencidas até não sobrar nenhuma e
+// devolve quantas tentativas fez.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		// a reivindicação dura mais que um POST, para ninguém repetir a entrega
+		due, err := store.ClaimDeliveries(ctx, d.db, time.Now().UTC(), 2*d.cfg.Timeout, d.cfg.BatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(due) == 0 {
+			return total, nil
+		}
+		var wg sync.WaitGroup
+		for _, dd := range due {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.deliver(ctx, dd)
+			}()
+		}
+		wg.Wait()
+		total += len(due)
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, dd store.DueDelivery) {
+	ctx, span := tracing.Tracer().Start(ctx, "webhook deliver",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("webhook.id", dd.WebhookID),
+			attribute.Int64("webhook.delivery_id", dd.ID),
+			attribute.String("webhook.event_type", dd.EventType),
+			attribute.Int("webhook.attempt", dd.Attempts+1),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+	code, err := d.post(ctx, dd)
+	metrics.WebhookDuration.Observe(time.Since(start).Seconds())
+
+	now := time.Now().UTC()
+	attempts := dd.Attempts + 1
+	status, next, cause := store.DeliverySucceeded, now, ""
+	if err != nil {
+		cause = err.Error()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, cause)
+		if attempts >= d.cfg.MaxAttempts {
+			status = store.DeliveryFailed
+			slog.WarnContext(ctx, "webhook delivery gave up",
+				"webhook_id", dd.WebhookID, "delivery_id", dd.ID, "attempts", attempts, "err", err)
+		} else {
+			status, next = store.DeliveryPending, now.Add(d.backoff(attempts))
+		}
+	}
+	metrics.WebhookDeliveries.WithLabelValues(map[string]string{
+		store.DeliverySucceeded: "success",
+		store.DeliveryPending:   "retry",
+		store.DeliveryFailed:    "failed",
+	}[status]).Inc()
+
+	if err := store.RecordAttempt(context.WithoutCancel(ctx), d.db, dd.ID, status, code, cause, next, now); err != nil {
+		slog.ErrorContext(ctx, "record webhook attempt failed", "delivery_id", dd.ID, "err", err)
+	}
+}
+
+// post faz o POST assinado; qualquer resposta fora de 2xx é erro.
+func (d *Dispatcher) post(ctx context.Context, dd store.DueDelivery) (int, error) {
+	ts := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dd.URL, bytes.NewReader(dd.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "orders-api-webhooks")
+	req.Header.Set(HeaderID, dd.WebhookID)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(dd.ID, 10))
+	req.Header.Set(HeaderEvent, dd.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(dd.Secret, ts, dd.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff é o intervalo antes da tentativa seguinte à de número `attempts`.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	b := d.cfg.InitialBackoff
+	for i := 1; i < attempts && b < d.cfg.MaxBackoff; i++ {
+		b *= 2
+	}
+	return min(b, d.cfg.MaxBackoff)
+}
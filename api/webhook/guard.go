@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress: a URL do webhook aponta para a rede interna.
+var ErrForbiddenAddress = errors.New("address not allowed for webhooks")
+
+// Guard impede que um webhook seja usado para alcançar a rede interna
+// (SSRF): loopback, redes privadas, link-local (169.254.169.254 dos
+// metadados de nuvem) e afins são recusados no cadastro e de novo na
+// conexão, já com o IP resolvido (um DNS que muda depois do cadastro não
+// escapa). allowedHosts libera nomes ou CIDRs, p.ex. o sink dos testes.
+type Guard struct {
+	hosts map[string]bool
+	nets  []netip.Prefix
+}
+
+// NewGuard monta o Guard a partir de webhooks.allowedHosts (nomes de host
+// ou CIDRs, já validados pela config).
+func NewGuard(allowed []string) *Guard {
+	g := &Guard{hosts: map[string]bool{}}
+	for _, a := range allowed {
+		if p, err := netip.ParsePrefix(a); err == nil {
+			g.nets = append(g.nets, p.Masked())
+			continue
+		}
+		g.hosts[strings.ToLower(a)] = true
+	}
+	return g
+}
+
+// CheckURL resolve o host da URL e recusa se algum endereço é interno.
+func (g *Guard) CheckURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	if g.hosts[strings.ToLower(host)] {
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("host %q does not resolve", host)
+	}
+	for _, ip := range addrs {
+		if !g.allowedIP(ip) {
+			return fmt.Errorf("host %q: %w (%s)", host, ErrForbiddenAddress, ip.Unmap())
+		}
+	}
+	return nil
+}
+
+// DialContext é o dial do http.Transport do dispatcher: confere o IP de
+// fato conectado, depois da resolução.
+func (g *Guard) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if host, _, err := net.SplitHostPort(addr); err == nil && g.hosts[strings.ToLower(host)] {
+		return d.DialContext(ctx, network, addr)
+	}
+	d.Control = func(_, address string, _ syscall.RawConn) error {
+		ap, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return err
+		}
+		if !g.allowedIP(ap.Addr()) {
+			return fmt.Errorf("dial %s: %w", address, ErrForbiddenAddress)
+		}
+		return nil
+	}
+	return d.DialContext(ctx, network, addr)
+}
+
+func (g *Guard) allowedIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, p := range g.nets {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace (100.64.0.0/10, CGNAT) não entra em IsPrivate mas
+// também é rede interna em vários provedores.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
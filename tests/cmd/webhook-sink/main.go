@@ -0,0 +1,79 @@
+// webhook-sink é o parceiro de mentira dos testes de webhook: guarda cada
+// POST recebido em /hooks/{nome} e devolve a lista num GET do mesmo path.
+// Com ?fail=N as N primeiras entregas daquele nome recebem 500, para
+// exercitar os retries do dispatcher.
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Received é uma entrega como o sink viu (também usada pelos steps).
+type Received struct {
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+	Status     int               `json:"status"` // o que o sink respondeu
+	ReceivedAt time.Time         `json:"receivedAt"`
+}
+
+type sink struct {
+	mu   sync.Mutex
+	hits map[string][]Received
+}
+
+func (s *sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/hooks/")
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		body, _ := io.ReadAll(r.Body)
+		hdr := map[string]string{}
+		for k := range r.Header {
+			hdr[k] = r.Header.Get(k)
+		}
+		fail, _ := strconv.Atoi(r.URL.Query().Get("fail"))
+
+		s.mu.Lock()
+		status := http.StatusOK
+		if len(s.hits[name]) < fail {
+			status = http.StatusInternalServerError
+		}
+		s.hits[name] = append(s.hits[name], Received{
+			Headers: hdr, Body: string(body), Status: status, ReceivedAt: time.Now().UTC(),
+		})
+		s.mu.Unlock()
+
+		log.Printf("POST /hooks/%s event=%s → %d", name, r.Header.Get("X-Webhook-Event"), status)
+		w.WriteHeader(status)
+	case http.MethodGet:
+		s.mu.Lock()
+		out := append([]Received{}, s.hits[name]...)
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func main() {
+	addr := os.Getenv("SINK_ADDR")
+	if addr == "" {
+		addr = ":8099"
+	}
+	http.Handle("/hooks/", &sink{hits: map[string][]Received{}})
+	log.Printf("webhook sink listening on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
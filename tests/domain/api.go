@@ -3,11 +3,13 @@ package domain
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"orders-tests/helpers"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,18 +23,69 @@ type ApiCtx struct {
 	Debug    bool
 	LastBody []byte
 	Vars     map[string]string
+
+	// APIKey é enviada em X-Api-Key quando o cenário não define outra
+	// credencial; Anonymous desliga esse padrão.
+	APIKey    string
+	Anonymous bool
+
+	// Contract, se carregado (OPENAPI_VALIDATE), confere cada request e
+	// resposta contra o OpenAPI da API; OffContract desliga só a parte dos
+	// requests, para cenários que mandam corpos inválidos de propósito.
+	Contract    *Contract
+	OffContract bool
 }
 
-func (a *ApiCtx) ResolvePath(p string) string {
+// ResolveVars troca cada {nome} pela variável guardada no cenário.
+func (a *ApiCtx) ResolveVars(s string) string {
 	for k, v := range a.Vars {
-		p = strings.ReplaceAll(p, "{"+k+"}", v)
+		s = strings.ReplaceAll(s, "{"+k+"}", v)
 	}
+	return s
+}
+
+func (a *ApiCtx) ResolvePath(p string) string {
+	p = a.ResolveVars(p)
 	if !strings.HasPrefix(p, "/") {
 		p = "/" + p
 	}
 	return p
 }
 
+// applyReqHeaders copia os headers configurados via step, garante um
+// X-Request-Id por request (para casar com os logs da API, HTTP_DEBUG) e
+// autentica com a API key padrão se o cenário não trouxe credencial.
+func (a *ApiCtx) applyReqHeaders(req *http.Request) {
+	for k, vals := range a.ReqHdr {
+		for _, v := range vals {
+			req.Header.Add(k, v)
+		}
+	}
+	if req.Header.Get("X-Request-Id") == "" {
+		req.Header.Set("X-Request-Id", fmt.Sprintf("bdd-%d", time.Now().UnixNano()))
+	}
+	if !a.Anonymous && a.APIKey != "" &&
+		req.Header.Get("Authorization") == "" && req.Header.Get("X-Api-Key") == "" {
+		req.Header.Set("X-Api-Key", a.APIKey)
+	}
+}
+
+// checkRequest e checkResponse falham o step com o erro de schema quando
+// o Contract está carregado.
+func (a *ApiCtx) checkRequest(req *http.Request, body []byte) error {
+	if a.Contract == nil || a.OffContract {
+		return nil
+	}
+	return a.Contract.CheckRequest(req, body)
+}
+
+func (a *ApiCtx) checkResponse(req *http.Request, resp *http.Response, body []byte) error {
+	if a.Contract == nil {
+		return nil
+	}
+	return a.Contract.CheckResponse(req, resp, body)
+}
+
 func (a *ApiCtx) LogReq(method, url string, body []byte, hdr http.Header) {
 	if !a.Debug {
 		return
@@ -101,13 +154,12 @@ func (a *ApiCtx) Post(path string, reqBody any, respDest any) error {
 	}
 
 	// 2) aplica headers configurados via step
-	for k, vals := range a.ReqHdr {
-		for _, v := range vals {
-			req.Header.Add(k, v)
-		}
-	}
+	a.applyReqHeaders(req)
 
 	a.LogReq(http.MethodPost, url, bodyBytes, req.Header)
+	if err := a.checkRequest(req, bodyBytes); err != nil {
+		return err
+	}
 
 	// 3) faz a chamada
 	resp, err := client.Do(req)
@@ -126,9 +178,13 @@ func (a *ApiCtx) Post(path string, reqBody any, respDest any) error {
 	a.LastBody = b
 
 	a.LogResp(resp, b)
+	if err := a.checkResponse(req, resp, b); err != nil {
+		return err
+	}
 
-	// 4) desserializa na struct de resposta, se pedirem
-	if respDest != nil && len(a.LastBody) > 0 {
+	// 4) desserializa na struct de resposta, se pedirem (erros podem vir em
+	// texto puro; o step de status cuida deles)
+	if respDest != nil && len(a.LastBody) > 0 && resp.StatusCode < 300 {
 		if err := json.Unmarshal(a.LastBody, respDest); err != nil {
 			return fmt.Errorf("unmarshal response: %w", err)
 		}
@@ -137,6 +193,21 @@ func (a *ApiCtx) Post(path string, reqBody any, respDest any) error {
 	return nil
 }
 
+// PostAs envia o corpo com o Content-Type informado só neste request
+// (uploads de arquivo), sem mexer nos headers do cenário.
+func (a *ApiCtx) PostAs(path, contentType string, body []byte, respDest any) error {
+	prev, had := a.ReqHdr["Content-Type"]
+	a.ReqHdr.Set("Content-Type", contentType)
+	defer func() {
+		if had {
+			a.ReqHdr["Content-Type"] = prev
+		} else {
+			a.ReqHdr.Del("Content-Type")
+		}
+	}()
+	return a.Post(path, body, respDest)
+}
+
 func (a *ApiCtx) Put(path string, reqBody any, respDest any) error {
 	client := &http.Client{Timeout: 15 * time.Second}
 	path = a.ResolvePath(path)
@@ -168,13 +239,12 @@ func (a *ApiCtx) Put(path string, reqBody any, respDest any) error {
 	}
 
 	// 2) aplica headers configurados via step
-	for k, vals := range a.ReqHdr {
-		for _, v := range vals {
-			req.Header.Add(k, v)
-		}
-	}
+	a.applyReqHeaders(req)
 
 	a.LogReq(http.MethodPut, url, bodyBytes, req.Header)
+	if err := a.checkRequest(req, bodyBytes); err != nil {
+		return err
+	}
 
 	// 3) faz a chamada
 	resp, err := client.Do(req)
@@ -193,9 +263,12 @@ func (a *ApiCtx) Put(path string, reqBody any, respDest any) error {
 	a.LastBody = b
 
 	a.LogResp(resp, b)
+	if err := a.checkResponse(req, resp, b); err != nil {
+		return err
+	}
 
 	// 4) desserializa na struct de resposta, se pedirem
-	if respDest != nil && len(a.LastBody) > 0 {
+	if respDest != nil && len(a.LastBody) > 0 && resp.StatusCode < 300 {
 		if err := json.Unmarshal(a.LastBody, respDest); err != nil {
 			return fmt.Errorf("unmarshal response: %w", err)
 		}
@@ -214,13 +287,12 @@ func (a *ApiCtx) Get(path string, respDest any) error {
 		return err
 	}
 
-	for k, vals := range a.ReqHdr {
-		for _, v := range vals {
-			req.Header.Add(k, v)
-		}
-	}
+	a.applyReqHeaders(req)
 
 	a.LogReq(http.MethodGet, url, nil, req.Header)
+	if err := a.checkRequest(req, nil); err != nil {
+		return err
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -238,8 +310,11 @@ func (a *ApiCtx) Get(path string, respDest any) error {
 	a.LastBody = body
 
 	a.LogResp(resp, body)
+	if err := a.checkResponse(req, resp, body); err != nil {
+		return err
+	}
 
-	if respDest != nil && len(a.LastBody) > 0 {
+	if respDest != nil && len(a.LastBody) > 0 && resp.StatusCode < 300 {
 		if err := json.Unmarshal(a.LastBody, respDest); err != nil {
 			return fmt.Errorf("unmarshal response: %w", err)
 		}
@@ -247,3 +322,86 @@ func (a *ApiCtx) Get(path string, respDest any) error {
 
 	return nil
 }
+
+func (a *ApiCtx) Delete(path string) error {
+	client := &http.Client{Timeout: 15 * time.Second}
+	url := a.BaseURL + a.ResolvePath(path)
+
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return err
+	}
+	a.applyReqHeaders(req)
+	a.LogReq(http.MethodDelete, url, nil, req.Header)
+	if err := a.checkRequest(req, nil); err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	a.LastResp = resp
+	a.LastHdr = resp.Header.Clone()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	a.LastBody = body
+	a.LogResp(resp, body)
+	return a.checkResponse(req, resp, body)
+}
+
+// BurstResult é o resultado de um dos requests de Burst.
+type BurstResult struct {
+	Status int
+	Header http.Header
+}
+
+// Burst dispara n requests iguais em paralelo (para exercitar o rate limit)
+// e devolve status e headers de cada um.
+func (a *ApiCtx) Burst(method, path string, body []byte, n int) ([]BurstResult, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	url := a.BaseURL + a.ResolvePath(path)
+	if len(body) > 0 && a.ReqHdr.Get("Content-Type") == "" {
+		a.ReqHdr.Set("Content-Type", "application/json")
+	}
+
+	probe, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	a.applyReqHeaders(probe)
+	if err := a.checkRequest(probe, body); err != nil {
+		return nil, err
+	}
+
+	out := make([]BurstResult, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req, err := http.NewRequest(method, url, bytes.NewReader(body))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			a.applyReqHeaders(req)
+			resp, err := client.Do(req)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			b, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			out[i] = BurstResult{Status: resp.StatusCode, Header: resp.Header.Clone()}
+			errs[i] = a.checkResponse(req, resp, b)
+		}(i)
+	}
+	wg.Wait()
+	return out, errors.Join(errs...)
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeyID precisa bater com o "kid" do fixtures/auth/jwks.json montado na API.
+const KeyID = "bdd-key-1"
+
+// privateKeyPath resolve a chave de assinatura dos testes; dá para trocar
+// via JWT_PRIVATE_KEY_FILE.
+func privateKeyPath() string {
+	if p := os.Getenv("JWT_PRIVATE_KEY_FILE"); p != "" {
+		return p
+	}
+	return filepath.Join("..", "fixtures", "auth", "jwt-private.pem")
+}
+
+func loadSigningKey() (crypto.Signer, error) {
+	b, err := os.ReadFile(privateKeyPath())
+	if err != nil {
+		return nil, fmt.Errorf("read JWT signing key: %w", err)
+	}
+	blk, _ := pem.Decode(b)
+	if blk == nil {
+		return nil, fmt.Errorf("JWT signing key %s is not PEM", privateKeyPath())
+	}
+	k, err := x509.ParsePKCS8PrivateKey(blk.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse JWT signing key: %w", err)
+	}
+	signer, ok := k.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("JWT signing key has unsupported type %T", k)
+	}
+	return signer, nil
+}
+
+// SignJWT emite um RS256 para `subject` com os claims extras, válido por ttl
+// (ttl negativo gera um token já expirado).
+func SignJWT(subject string, ttl time.Duration, extra map[string]any) (string, error) {
+	key, err := loadSigningKey()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = KeyID
+	return tok.SignedString(key)
+}
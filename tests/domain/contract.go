@@ -0,0 +1,391 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// Contract é o documento OpenAPI da API (GET /openapi.json ou um arquivo
+// gerado com `orders-api openapi`). Com ele o ApiCtx confere cada request e
+// resposta e anota quais operações/status a suíte exercitou.
+type Contract struct {
+	Source string
+	ops    []*contractOp
+
+	mu   sync.Mutex
+	seen map[*contractOp]map[int]bool
+}
+
+type contractOp struct {
+	method, path, id string
+	segs             []string
+	body             *jsonschema.Schema // corpo application/json, nil sem corpo
+	files            map[string]bool    // outros content types do corpo (uploads), sem schema
+	query            map[string]contractParam
+	responses        map[int]map[string]*jsonschema.Schema // status → media → schema (nil: não é JSON)
+}
+
+type contractParam struct {
+	typ    string // type do schema, para converter o valor da query string
+	schema *jsonschema.Schema
+}
+
+type openAPIDoc struct {
+	Paths map[string]map[string]struct {
+		OperationID string `json:"operationId"`
+		Parameters  []struct {
+			Name   string         `json:"name"`
+			In     string         `json:"in"`
+			Schema map[string]any `json:"schema"`
+		} `json:"parameters"`
+		RequestBody *struct {
+			Content map[string]json.RawMessage `json:"content"`
+		} `json:"requestBody"`
+		Responses map[string]struct {
+			Content map[string]json.RawMessage `json:"content"`
+		} `json:"responses"`
+	} `json:"paths"`
+}
+
+// LoadContract lê o documento de uma URL (http/https) ou de um arquivo e
+// compila os schemas de corpos, parâmetros e respostas.
+func LoadContract(source string) (*Contract, error) {
+	raw, err := readSource(source)
+	if err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	var doc openAPIDoc
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("openapi: %s: %w", source, err)
+	}
+	res, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("openapi: %s: %w", source, err)
+	}
+
+	// o documento inteiro é o recurso: cada schema é compilado pelo seu
+	// ponteiro JSON e os $ref para components resolvem nele
+	const base = "openapi.json"
+	comp := jsonschema.NewCompiler()
+	comp.DefaultDraft(jsonschema.Draft2020)
+	if err := comp.AddResource(base, res); err != nil {
+		return nil, fmt.Errorf("openapi: %w", err)
+	}
+	compile := func(ptr ...string) (*jsonschema.Schema, error) {
+		for i := range ptr {
+			ptr[i] = strings.NewReplacer("~", "~0", "/", "~1").Replace(ptr[i])
+		}
+		loc := base + "#/" + strings.Join(ptr, "/")
+		sch, err := comp.Compile(loc)
+		if err != nil {
+			return nil, fmt.Errorf("openapi: %s: %w", loc, err)
+		}
+		return sch, nil
+	}
+
+	c := &Contract{Source: source, seen: map[*contractOp]map[int]bool{}}
+	for path, item := range doc.Paths {
+		for method, o := range item {
+			op := &contractOp{
+				method:    strings.ToUpper(method),
+				path:      path,
+				id:        o.OperationID,
+				segs:      strings.Split(strings.Trim(path, "/"), "/"),
+				query:     map[string]contractParam{},
+				responses: map[int]map[string]*jsonschema.Schema{},
+			}
+			for i, p := range o.Parameters {
+				if p.In != "query" {
+					continue
+				}
+				sch, err := compile("paths", path, method, "parameters", strconv.Itoa(i), "schema")
+				if err != nil {
+					return nil, err
+				}
+				typ, _ := p.Schema["type"].(string)
+				op.query[p.Name] = contractParam{typ: typ, schema: sch}
+			}
+			if o.RequestBody != nil {
+				for m := range o.RequestBody.Content {
+					if !isJSON(m) {
+						if op.files == nil {
+							op.files = map[string]bool{}
+						}
+						op.files[m] = true
+					}
+				}
+				if _, ok := o.RequestBody.Content["application/json"]; ok {
+					if op.body, err = compile("paths", path, method, "requestBody", "content", "application/json", "schema"); err != nil {
+						return nil, err
+					}
+				}
+			}
+			for code, r := range o.Responses {
+				status, err := strconv.Atoi(code)
+				if err != nil {
+					continue // "default" e faixas ("4XX") não são usados pela API
+				}
+				media := map[string]*jsonschema.Schema{}
+				for m := range r.Content {
+					media[m] = nil
+					if isJSON(m) {
+						if media[m], err = compile("paths", path, method, "responses", code, "content", m, "schema"); err != nil {
+							return nil, err
+						}
+					}
+				}
+				op.responses[status] = media
+			}
+			c.ops = append(c.ops, op)
+		}
+	}
+	sort.Slice(c.ops, func(i, j int) bool {
+		if c.ops[i].path != c.ops[j].path {
+			return c.ops[i].path < c.ops[j].path
+		}
+		return c.ops[i].method < c.ops[j].method
+	})
+	return c, nil
+}
+
+func readSource(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %d %s", source, resp.StatusCode, b)
+	}
+	return b, nil
+}
+
+func isJSON(media string) bool {
+	return media == "application/json" || strings.HasSuffix(media, "+json")
+}
+
+// operation acha a operação pelo método e caminho concreto; segmentos fixos
+// ganham dos parâmetros (/orders/stream antes de /orders/{id}).
+func (c *Contract) operation(method, path string) *contractOp {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	var best *contractOp
+	bestFixed := -1
+	for _, op := range c.ops {
+		if op.method != method || len(op.segs) != len(segs) {
+			continue
+		}
+		fixed, ok := 0, true
+		for i, s := range op.segs {
+			switch {
+			case strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}"):
+			case s == segs[i]:
+				fixed++
+			default:
+				ok = false
+			}
+			if !ok {
+				break
+			}
+		}
+		if ok && fixed > bestFixed {
+			best, bestFixed = op, fixed
+		}
+	}
+	return best
+}
+
+func (op *contractOp) String() string {
+	return fmt.Sprintf("%s %s (%s)", op.method, op.path, op.id)
+}
+
+// CheckRequest confere query string e corpo JSON de um request antes do
+// envio. Corpos que não são JSON válido ficam para o 400 da própria API, e
+// rotas fora do documento para o CheckResponse (só podem responder erro).
+func (c *Contract) CheckRequest(req *http.Request, body []byte) error {
+	op := c.operation(req.Method, req.URL.Path)
+	if op == nil {
+		return nil
+	}
+	for name, vals := range req.URL.Query() {
+		p, ok := op.query[name]
+		if !ok {
+			continue
+		}
+		for _, v := range vals {
+			if err := p.schema.Validate(queryValue(p.typ, v)); err != nil {
+				return fmt.Errorf("openapi: request %s: query parameter %q=%q does not match the contract:\n%s",
+					op, name, v, schemaError(err))
+			}
+		}
+	}
+	media, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if len(body) == 0 || !json.Valid(body) || op.files[media] {
+		return nil // arquivo (CSV, NDJSON) não tem schema
+	}
+	if op.body == nil {
+		return fmt.Errorf("openapi: request %s: the operation takes no body", op)
+	}
+	if media != "application/json" {
+		return fmt.Errorf("openapi: request %s: content type %q is not documented", op, media)
+	}
+	v, _ := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err := op.body.Validate(v); err != nil {
+		return fmt.Errorf("openapi: request %s does not match the contract:\n%s", op, schemaError(err))
+	}
+	return nil
+}
+
+// queryValue converte o texto da query string para o tipo do schema; se não
+// converter, fica string e a validação acusa o tipo.
+func queryValue(typ, v string) any {
+	switch typ {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// CheckResponse anota o status para o relatório de cobertura e confere
+// status, content type e corpo contra o documento.
+func (c *Contract) CheckResponse(req *http.Request, resp *http.Response, body []byte) error {
+	return c.checkResponse(req, resp, body, true)
+}
+
+// Record faz o mesmo para streams (SSE, WebSocket), sem ler o corpo.
+func (c *Contract) Record(req *http.Request, resp *http.Response) error {
+	return c.checkResponse(req, resp, nil, false)
+}
+
+func (c *Contract) checkResponse(req *http.Request, resp *http.Response, body []byte, withBody bool) error {
+	op := c.operation(req.Method, req.URL.Path)
+	if op == nil {
+		if resp.StatusCode < 400 {
+			return fmt.Errorf("openapi: %s %s answered %d but is not documented", req.Method, req.URL.Path, resp.StatusCode)
+		}
+		return nil // 404/405 do roteador
+	}
+
+	c.mu.Lock()
+	if c.seen[op] == nil {
+		c.seen[op] = map[int]bool{}
+	}
+	c.seen[op][resp.StatusCode] = true
+	c.mu.Unlock()
+
+	content, ok := op.responses[resp.StatusCode]
+	if !ok {
+		return fmt.Errorf("openapi: response %d to %s is not documented (body: %s)", resp.StatusCode, op, truncate(body))
+	}
+	if len(content) == 0 && len(body) == 0 {
+		return nil
+	}
+	media, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	sch, ok := content[media]
+	if !ok {
+		return fmt.Errorf("openapi: response %d to %s: content type %q is not documented", resp.StatusCode, op, media)
+	}
+	if !withBody || sch == nil {
+		return nil
+	}
+	v, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("openapi: response %d to %s: invalid JSON: %w", resp.StatusCode, op, err)
+	}
+	if err := sch.Validate(v); err != nil {
+		return fmt.Errorf("openapi: response %d to %s does not match the contract:\n%s\nbody: %s",
+			resp.StatusCode, op, schemaError(err), truncate(body))
+	}
+	return nil
+}
+
+// schemaError tira o cabeçalho com a URL do schema e indenta a lista de
+// erros (um por instância: "at '/items/0': got number, want string").
+func schemaError(err error) string {
+	lines := strings.Split(strings.TrimSpace(err.Error()), "\n")
+	if len(lines) > 1 && strings.HasPrefix(lines[0], "jsonschema validation failed") {
+		lines = lines[1:]
+	}
+	for i := range lines {
+		lines[i] = "  " + strings.TrimSpace(lines[i])
+	}
+	return strings.Join(lines, "\n")
+}
+
+func truncate(b []byte) string {
+	if len(b) > 500 {
+		return string(b[:500]) + "…"
+	}
+	return string(b)
+}
+
+// Report lista as operações que a suíte nunca chamou e, das chamadas, os
+// status documentados que nunca apareceram.
+func (c *Contract) Report() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var never, missing []string
+	var called, responses, covered int
+	for _, op := range c.ops {
+		responses += len(op.responses)
+		seen := c.seen[op]
+		if len(seen) == 0 {
+			never = append(never, "    "+op.String())
+			continue
+		}
+		called++
+		var codes []int
+		for code := range op.responses {
+			if seen[code] {
+				covered++
+			} else {
+				codes = append(codes, code)
+			}
+		}
+		if len(codes) > 0 {
+			sort.Ints(codes)
+			strs := make([]string, len(codes))
+			for i, code := range codes {
+				strs[i] = strconv.Itoa(code)
+			}
+			missing = append(missing, fmt.Sprintf("    %s: %s", op, strings.Join(strs, ", ")))
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "OpenAPI coverage (%s): %d/%d operations, %d/%d documented responses\n",
+		c.Source, called, len(c.ops), covered, responses)
+	if len(never) > 0 {
+		sb.WriteString("  never called:\n" + strings.Join(never, "\n") + "\n")
+	}
+	if len(missing) > 0 {
+		sb.WriteString("  status codes never seen:\n" + strings.Join(missing, "\n") + "\n")
+	}
+	return sb.String()
+}
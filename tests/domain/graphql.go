@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+)
+
+// GraphQL manda uma query/mutation em POST /graphql; a resposta fica em
+// LastBody, como nos outros requests.
+func (a *ApiCtx) GraphQL(query string, variables map[string]any) error {
+	body := map[string]any{"query": query}
+	if len(variables) > 0 {
+		body["variables"] = variables
+	}
+	return a.Post("/graphql", body, nil)
+}
+
+// GqlMessage é uma mensagem do servidor no protocolo graphql-transport-ws.
+type GqlMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// GqlSubscription é uma subscription aberta; "next", "error" e "complete"
+// chegam em C até Close ou o fim da conexão.
+type GqlSubscription struct {
+	C      chan GqlMessage
+	conn   *websocket.Conn
+	cancel context.CancelFunc
+}
+
+func (s *GqlSubscription) Close() {
+	if s != nil {
+		s.cancel()
+		_ = s.conn.Close(websocket.StatusNormalClosure, "")
+	}
+}
+
+// SubscribeGraphQL abre o WebSocket em /graphql (com as credenciais e
+// headers do ApiCtx), faz o handshake e envia o subscribe. Só volta depois
+// do pong a um ping enviado em seguida: o servidor processa as mensagens em
+// ordem, então a assinatura já está ativa.
+func (a *ApiCtx) SubscribeGraphQL(query string, variables map[string]any) (*GqlSubscription, error) {
+	url := "ws" + strings.TrimPrefix(a.BaseURL, "http") + "/graphql"
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	a.applyReqHeaders(req)
+	a.LogReq("WS", url, nil, req.Header)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dialCtx, cancelDial := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelDial()
+	conn, resp, err := websocket.Dial(dialCtx, url, &websocket.DialOptions{
+		HTTPHeader:   req.Header,
+		Subprotocols: []string{"graphql-transport-ws"},
+	})
+	if err != nil {
+		cancel()
+		if resp != nil {
+			return nil, fmt.Errorf("graphql websocket: status %d: %w", resp.StatusCode, err)
+		}
+		return nil, fmt.Errorf("graphql websocket: %w", err)
+	}
+	if a.Contract != nil {
+		if err := a.Contract.Record(req, resp); err != nil {
+			cancel()
+			conn.CloseNow()
+			return nil, err
+		}
+	}
+
+	fail := func(err error) (*GqlSubscription, error) {
+		cancel()
+		conn.CloseNow()
+		return nil, err
+	}
+	expect := func(typ string) error {
+		var m GqlMessage
+		if err := wsjson.Read(dialCtx, conn, &m); err != nil {
+			return fmt.Errorf("graphql websocket: waiting for %s: %w", typ, err)
+		}
+		if m.Type != typ {
+			return fmt.Errorf("graphql websocket: expected %s, got %s %s", typ, m.Type, m.Payload)
+		}
+		return nil
+	}
+
+	if err := wsjson.Write(dialCtx, conn, GqlMessage{Type: "connection_init"}); err != nil {
+		return fail(err)
+	}
+	if err := expect("connection_ack"); err != nil {
+		return fail(err)
+	}
+	payload, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err := wsjson.Write(dialCtx, conn, GqlMessage{ID: "1", Type: "subscribe", Payload: payload}); err != nil {
+		return fail(err)
+	}
+	if err := wsjson.Write(dialCtx, conn, GqlMessage{Type: "ping"}); err != nil {
+		return fail(err)
+	}
+
+	// o pong confirma a assinatura; um "error" do subscribe pode chegar antes
+	sub := &GqlSubscription{C: make(chan GqlMessage, 64), conn: conn, cancel: cancel}
+	for {
+		var m GqlMessage
+		if err := wsjson.Read(dialCtx, conn, &m); err != nil {
+			return fail(fmt.Errorf("graphql websocket: waiting for pong: %w", err))
+		}
+		if m.Type == "pong" {
+			break
+		}
+		sub.C <- m
+	}
+
+	go func() {
+		defer close(sub.C)
+		for {
+			var m GqlMessage
+			if err := wsjson.Read(ctx, conn, &m); err != nil {
+				return
+			}
+			if a.Debug {
+				fmt.Printf("=== GraphQL ws ← %s %s\n", m.Type, m.Payload)
+			}
+			select {
+			case sub.C <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
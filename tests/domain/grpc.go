@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ordersv1 "orders-tests/gen/orders/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// GrpcCtx é o cliente do OrdersService. As credenciais e o tenant vêm do
+// ApiCtx (mesma API key/JWT e headers configurados nos steps HTTP).
+type GrpcCtx struct {
+	Addr string
+	API  *ApiCtx
+
+	conn   *grpc.ClientConn
+	client ordersv1.OrdersServiceClient
+
+	LastResp   proto.Message
+	LastStatus *status.Status
+	LastHeader metadata.MD
+}
+
+// grpcCall invoca um método unário a partir do JSON do request.
+type grpcCall func(ctx context.Context, c ordersv1.OrdersServiceClient, body []byte, opts ...grpc.CallOption) (proto.Message, error)
+
+func unary[Req proto.Message, Resp proto.Message](newReq func() Req,
+	call func(ordersv1.OrdersServiceClient, context.Context, Req, ...grpc.CallOption) (Resp, error)) grpcCall {
+	return func(ctx context.Context, c ordersv1.OrdersServiceClient, body []byte, opts ...grpc.CallOption) (proto.Message, error) {
+		req := newReq()
+		if err := protojson.Unmarshal(body, req); err != nil {
+			return nil, fmt.Errorf("invalid JSON for %T: %w", req, err)
+		}
+		return call(c, ctx, req, opts...)
+	}
+}
+
+var grpcCalls = map[string]grpcCall{
+	"CreateOrder": unary(func() *ordersv1.CreateOrderRequest { return &ordersv1.CreateOrderRequest{} },
+		ordersv1.OrdersServiceClient.CreateOrder),
+	"GetOrder": unary(func() *ordersv1.GetOrderRequest { return &ordersv1.GetOrderRequest{} },
+		ordersv1.OrdersServiceClient.GetOrder),
+	"ListOrders": unary(func() *ordersv1.ListOrdersRequest { return &ordersv1.ListOrdersRequest{} },
+		ordersv1.OrdersServiceClient.ListOrders),
+	"UpdateOrderStatus": unary(func() *ordersv1.UpdateOrderStatusRequest { return &ordersv1.UpdateOrderStatusRequest{} },
+		ordersv1.OrdersServiceClient.UpdateOrderStatus),
+}
+
+func (g *GrpcCtx) dial() error {
+	if g.conn != nil {
+		return nil
+	}
+	conn, err := grpc.NewClient(g.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	g.conn, g.client = conn, ordersv1.NewOrdersServiceClient(conn)
+	return nil
+}
+
+func (g *GrpcCtx) Close() {
+	if g.conn != nil {
+		_ = g.conn.Close()
+		g.conn = nil
+	}
+}
+
+// outgoing monta a metadata da chamada: headers dos steps (minúsculos), um
+// x-request-id e a API key padrão, como ApiCtx.applyReqHeaders faz no HTTP.
+func (g *GrpcCtx) outgoing(ctx context.Context) context.Context {
+	md := metadata.MD{}
+	for k, vals := range g.API.ReqHdr {
+		if k == "Content-Type" {
+			continue
+		}
+		md.Append(k, vals...)
+	}
+	if len(md.Get("x-request-id")) == 0 {
+		md.Set("x-request-id", fmt.Sprintf("bdd-%d", time.Now().UnixNano()))
+	}
+	if !g.API.Anonymous && g.API.APIKey != "" &&
+		len(md.Get("authorization")) == 0 && len(md.Get("x-api-key")) == 0 {
+		md.Set("x-api-key", g.API.APIKey)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// Call chama um método unário; erros de status gRPC ficam em LastStatus (não
+// são erro do step), só falhas de transporte/JSON voltam como error.
+func (g *GrpcCtx) Call(method string, body []byte) error {
+	call, ok := grpcCalls[method]
+	if !ok {
+		return fmt.Errorf("unknown gRPC method %q", method)
+	}
+	if err := g.dial(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(g.outgoing(context.Background()), 15*time.Second)
+	defer cancel()
+
+	if g.API.Debug {
+		fmt.Printf("\n=== gRPC → %s\n%s\n", method, body)
+	}
+	var hdr metadata.MD
+	resp, err := call(ctx, g.client, body, grpc.Header(&hdr))
+	g.LastResp, g.LastHeader = resp, hdr
+	g.LastStatus = status.Convert(err)
+	if g.API.Debug {
+		fmt.Printf("=== gRPC ← %s %s\n%s\n", method, g.LastStatus.Code(), g.LastJSON())
+	}
+	if _, isStatus := status.FromError(err); err != nil && !isStatus {
+		return err
+	}
+	return nil
+}
+
+// LastJSON é a última resposta em JSON canônico do proto (camelCase).
+func (g *GrpcCtx) LastJSON() []byte {
+	if g.LastResp == nil {
+		return []byte("{}")
+	}
+	b, _ := protojson.Marshal(g.LastResp)
+	return b
+}
+
+// Watch abre WatchOrders e só volta depois dos headers da resposta, quando a
+// assinatura já está ativa no servidor. Os eventos chegam no canal até o
+// stream acabar ou cancel ser chamado.
+func (g *GrpcCtx) Watch(orderID string, afterID int64) (<-chan *ordersv1.OrderEvent, context.CancelFunc, error) {
+	if err := g.dial(); err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithCancel(g.outgoing(context.Background()))
+	stream, err := g.client.WatchOrders(ctx, &ordersv1.WatchOrdersRequest{OrderId: orderID, AfterEventId: afterID})
+	if err == nil {
+		_, err = stream.Header()
+	}
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	ch := make(chan *ordersv1.OrderEvent, 64)
+	go func() {
+		defer close(ch)
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			select {
+			case ch <- msg.GetEvent():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, cancel, nil
+}
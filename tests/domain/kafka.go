@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,6 +20,38 @@ type Consumed struct {
 	Evt map[string]any
 	Raw []byte
 	Msg kafka.Message
+
+	// W3C trace context extraído do header traceparent (vazio se ausente)
+	TraceID string
+	SpanID  string
+}
+
+// ParseTraceparent valida e quebra um header W3C traceparent
+// (00-<trace-id 32 hex>-<span-id 16 hex>-<flags>).
+func ParseTraceparent(v string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return "", "", false
+	}
+	for _, p := range parts {
+		if _, err := hex.DecodeString(p); err != nil {
+			return "", "", false
+		}
+	}
+	if parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func extractTrace(hdrs []kafka.Header) (traceID, spanID string) {
+	for _, h := range hdrs {
+		if strings.EqualFold(h.Key, "traceparent") {
+			traceID, spanID, _ = ParseTraceparent(string(h.Value))
+			return
+		}
+	}
+	return "", ""
 }
 
 type KafkaCtx struct {
@@ -34,7 +67,7 @@ type KafkaCtx struct {
 	StartAt  string
 }
 
-func formatHeaders(hdrs []kafka.Header) string {
+func FormatHeaders(hdrs []kafka.Header) string {
 	if len(hdrs) == 0 {
 		return "∅"
 	}
@@ -127,7 +160,7 @@ func (k *KafkaCtx) PrintMessage(m kafka.Message, raw []byte) {
 			}
 			return string(m.Key)
 		}(),
-		formatHeaders(m.Headers),
+		FormatHeaders(m.Headers),
 		helpers.PrettyJSON(raw),
 	)
 }
@@ -242,8 +275,10 @@ func (k *KafkaCtx) Start(topic string) error {
 				k.PrintMessage(m, m.Value)
 			}
 
+			traceID, spanID := extractTrace(m.Headers)
+
 			select {
-			case k.Events <- Consumed{Evt: obj, Raw: m.Value, Msg: m}:
+			case k.Events <- Consumed{Evt: obj, Raw: m.Value, Msg: m, TraceID: traceID, SpanID: spanID}:
 			case <-ctx.Done():
 				return
 			}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ScrapeMetrics busca o /metrics da API sem mexer em LastResp/LastBody,
+// para não atrapalhar as asserções do request anterior.
+func (a *ApiCtx) ScrapeMetrics() (string, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	req, err := http.NewRequest(http.MethodGet, a.BaseURL+"/metrics", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET /metrics: %d %s", resp.StatusCode, string(b))
+	}
+	return string(b), a.checkResponse(req, resp, b)
+}
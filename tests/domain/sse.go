@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// SSEEvent é um evento recebido de um endpoint text/event-stream.
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// Stream é uma conexão SSE aberta; os eventos chegam em C até Close.
+type Stream struct {
+	C      chan SSEEvent
+	cancel context.CancelFunc
+}
+
+func (s *Stream) Close() {
+	if s != nil {
+		s.cancel()
+	}
+}
+
+// OpenStream conecta em path (com Last-Event-ID se informado) e passa a ler
+// os eventos em background. Falha se a resposta não for 200 text/event-stream.
+func (a *ApiCtx) OpenStream(path, lastEventID string) (*Stream, error) {
+	url := a.BaseURL + a.ResolvePath(path)
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	a.applyReqHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	a.LogReq(http.MethodGet, url, nil, req.Header)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		cancel()
+		if err := a.checkResponse(req, resp, body); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("stream %s: status %d, content-type %q: %s",
+			path, resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	}
+	if a.Contract != nil {
+		if err := a.Contract.Record(req, resp); err != nil {
+			resp.Body.Close()
+			cancel()
+			return nil, err
+		}
+	}
+
+	s := &Stream{C: make(chan SSEEvent, 64), cancel: cancel}
+	go func() {
+		defer resp.Body.Close()
+		defer close(s.C)
+		var cur SSEEvent
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case line == "":
+				if cur.Data != "" {
+					if a.Debug {
+						fmt.Printf("⇠ sse id=%s event=%s data=%s\n", cur.ID, cur.Event, cur.Data)
+					}
+					s.C <- cur
+				}
+				cur = SSEEvent{}
+			case strings.HasPrefix(line, ":"):
+				// comentário/heartbeat
+			default:
+				field, value, _ := strings.Cut(line, ":")
+				value = strings.TrimPrefix(value, " ")
+				switch field {
+				case "id":
+					cur.ID = value
+				case "event":
+					cur.Event = value
+				case "data":
+					if cur.Data != "" {
+						cur.Data += "\n"
+					}
+					cur.Data += value
+				}
+			}
+		}
+	}()
+	return s, nil
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SinkCtx fala com o webhook-sink (tests/cmd/webhook-sink). BaseURL é como
+// os testes chegam nele; InternalURL é como a API chega (rede do compose).
+type SinkCtx struct {
+	BaseURL     string
+	InternalURL string
+}
+
+// SinkHit é uma entrega registrada pelo sink.
+type SinkHit struct {
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+	Status     int               `json:"status"`
+	ReceivedAt time.Time         `json:"receivedAt"`
+}
+
+// HookURL é a URL que a API deve chamar para o endpoint `name` do sink;
+// fail > 0 faz o sink responder 500 às primeiras `fail` entregas.
+func (s *SinkCtx) HookURL(name string, fail int) string {
+	u := s.InternalURL + "/hooks/" + name
+	if fail > 0 {
+		u += fmt.Sprintf("?fail=%d", fail)
+	}
+	return u
+}
+
+// Hits devolve tudo o que o endpoint `name` recebeu até agora.
+func (s *SinkCtx) Hits(name string) ([]SinkHit, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(s.BaseURL + "/hooks/" + name)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("webhook sink: status %d", resp.StatusCode)
+	}
+	var hits []SinkHit
+	return hits, json.NewDecoder(resp.Body).Decode(&hits)
+}
+
+// VerifySignature confere o X-Webhook-Signature do hit com o secret,
+// do mesmo jeito que um parceiro faria.
+func (h SinkHit) VerifySignature(secret string) error {
+	ts, sig := h.Headers["X-Webhook-Timestamp"], h.Headers["X-Webhook-Signature"]
+	if ts == "" || !strings.HasPrefix(sig, "sha256=") {
+		return fmt.Errorf("missing or malformed signature headers (timestamp=%q signature=%q)", ts, sig)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "." + h.Body))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(sig), []byte(want)) {
+		return fmt.Errorf("signature mismatch: got %s, want %s", sig, want)
+	}
+	return nil
+}
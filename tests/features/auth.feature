@@ -0,0 +1,61 @@
+Feature: Authenticating API callers with API keys and JWTs
+
+  Scenario: 1) Requests without credentials are rejected
+    Given I am not authenticated
+    When I send GET /orders
+    Then the HTTP status should be 401
+    And the response header "Content-Type" should be "application/problem+json"
+
+  Scenario: 2) An unknown API key is rejected
+    Given I set headers:
+      | X-Api-Key | not-a-valid-key |
+    When I send GET /orders
+    Then the HTTP status should be 401
+
+  Scenario: 3) An expired JWT is rejected
+    Given I am authenticated with an expired JWT for subject "alice"
+    When I send GET /orders
+    Then the HTTP status should be 401
+
+  Scenario: 4) The JWT subject is recorded on the order and in the event headers
+    Given the topic "orders.events" is accessible
+    And I am authenticated with a JWT for subject "alice"
+    When I send POST /orders with JSON:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    Then the HTTP status should be 201
+    And I store the "id" from the response body into "order_id"
+    And there must be an event on topic "orders.events" of type "OrderCreated" for "order_id" within 5s
+    And the event should have header "x-subject" equal to "alice"
+    When I send GET /orders/{order_id}
+    Then the HTTP status should be 200
+    And the response body should be:
+      """
+      {
+        "customer": "Acme",
+        "customerId": "$ANY_ULID",
+        "id": "$ANY_ULID",
+        "tenantId": "default",
+        "items": [
+          "x"
+        ],
+        "status": "OPEN",
+        "createdAt": "$ANY_TIMESTAMP",
+        "updatedAt": "$ANY_TIMESTAMP",
+        "createdBy": "alice",
+        "updatedBy": "alice"
+      }
+      """
+
+  Scenario: 5) Probes stay public
+    Given I am not authenticated
+    When I send GET /livez
+    Then the HTTP status should be 200
+    When I send GET /readyz
+    Then the HTTP status should be 200
@@ -0,0 +1,95 @@
+Feature: Authorizing API callers by scope and customer
+
+  Scenario: 1) A read-only caller can list but not create orders
+    Given I am authenticated with a JWT for subject "auditor" with scopes "orders:read"
+    When I send GET /orders
+    Then the HTTP status should be 200
+    When I send POST /orders with JSON:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    Then the HTTP status should be 403
+    And the response header "Content-Type" should be "application/problem+json"
+
+  Scenario: 2) A caller without scopes cannot read orders
+    Given I am authenticated with a JWT for subject "nobody" with scopes ""
+    When I send GET /orders
+    Then the HTTP status should be 403
+
+  Scenario: 3) The admin scope grants every operation
+    Given I am authenticated with a JWT for subject "root" with scopes "orders:admin"
+    When I send POST /orders with JSON:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    Then the HTTP status should be 201
+
+  Scenario: 4) A customer only reads their own orders
+    Given I have an order created via API:
+      """
+      {
+        "customer": "Globex",
+        "items": [
+          "y"
+        ]
+      }
+      """
+    And I store the "id" from the response body into "other_order"
+    And I have an order created via API:
+      """
+      {
+        "customer": "Initech",
+        "items": [
+          "z"
+        ]
+      }
+      """
+    And I store the "id" from the response body into "own_order"
+    And I am authenticated with a JWT for subject "bob" bound to customer "Initech"
+    When I send GET /orders/{own_order}
+    Then the HTTP status should be 200
+    When I send GET /orders/{other_order}
+    Then the HTTP status should be 403
+    And the response header "Content-Type" should be "application/problem+json"
+    When I send GET /orders
+    Then the HTTP status should be 200
+    And every listed order should belong to customer "Initech"
+
+  Scenario: 5) A customer cannot change or create orders of another customer
+    Given I have an order created via API:
+      """
+      {
+        "customer": "Globex",
+        "items": [
+          "y"
+        ]
+      }
+      """
+    And I am authenticated with a JWT for subject "bob" bound to customer "Initech"
+    When I send PUT /orders/{order_id}/status with JSON:
+      """
+      {
+        "status": "DONE"
+      }
+      """
+    Then the HTTP status should be 403
+    When I send POST /orders with JSON:
+      """
+      {
+        "customer": "Globex",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    Then the HTTP status should be 403
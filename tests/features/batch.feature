@@ -0,0 +1,123 @@
+Feature: Batch creation and status changes
+
+  Background:
+    Given the topic "orders.events" is accessible
+
+  Scenario: 1) An atomic batch creates every order and publishes one event each
+    Given I remember the metric orders_created_total
+    When I send POST /orders:batch with JSON:
+      """
+      {
+        "orders": [
+          { "customer": "Acme Corp", "items": ["bolt"] },
+          { "customer": "Globex", "items": ["nut", "washer"] }
+        ]
+      }
+      """
+    Then the HTTP status should be 201
+    And the response field "mode" should be "atomic"
+    And the response field "succeeded" should be "2"
+    And the response field "failed" should be "0"
+    And the response field "results.1.status" should be "201"
+    When I store the "results.0.id" from the response body into "first"
+    And I store the "results.1.id" from the response body into "second"
+    Then there must be an event on topic "orders.events" of type "OrderCreated" for "first" within 5s
+    And there must be an event on topic "orders.events" of type "OrderCreated" for "second" within 5s
+    And the metric orders_created_total should have increased by 2
+
+  Scenario: 2) One invalid entry rolls back an atomic batch
+    Given I use a fresh tenant
+    When I send POST /orders:batch with JSON:
+      """
+      {
+        "mode": "atomic",
+        "orders": [
+          { "customer": "Acme Corp", "items": ["bolt"] },
+          { "customer": "Globex", "items": ["this item is far too long for the limit of the route, so the whole batch has to be rolled back before anything is written to the database or published to kafka; it keeps going just to be sure it is longer than two hundred and fifty six bytes long in total, which is the default"] }
+        ]
+      }
+      """
+    Then the HTTP status should be 422
+    And the response field "failed" should be "2"
+    And the response field "results.0.status" should be "424"
+    And the response field "results.1.status" should be "422"
+    And the response field "results.1.error.title" should be "Invalid order"
+    When I send GET /orders
+    Then the listed customers should be ""
+
+  Scenario: 3) A partial batch keeps the valid entries and reports the others
+    Given I use a fresh tenant
+    And I have the following orders:
+      | customer  | items | as   |
+      | Acme Corp | bolt  | acme |
+      | Globex    | nut   | glob |
+    When I send PUT /orders/status:batch with JSON:
+      """
+      {
+        "mode": "partial",
+        "updates": [
+          { "id": "{acme}", "status": "PAID" },
+          { "id": "does-not-exist", "status": "PAID" },
+          { "id": "{glob}", "status": "" }
+        ]
+      }
+      """
+    Then the HTTP status should be 207
+    And the response field "succeeded" should be "1"
+    And the response field "failed" should be "2"
+    And the response field "results.0.status" should be "200"
+    And the response field "results.1.status" should be "404"
+    And the response field "results.2.status" should be "422"
+    When I send GET /orders?status=PAID
+    Then the listed customers should be "Acme Corp"
+
+  Scenario: 4) Status changes of a batch are published as events
+    Given I have an order created via API:
+      """
+      {
+        "customer": "Initech",
+        "items": ["stapler"]
+      }
+      """
+    When I send PUT /orders/status:batch with JSON:
+      """
+      {
+        "updates": [
+          { "id": "{order_id}", "status": "SHIPPED" }
+        ]
+      }
+      """
+    Then the HTTP status should be 200
+    And the response field "results.0.id" should be "{order_id}"
+    And there must be an event on topic "orders.events" of type "OrderStatusUpdated" for "order_id" within 5s
+
+  Scenario: 5) A customer cannot touch orders of another customer in a batch
+    Given I am authenticated with a JWT for subject "bob" bound to customer "Initech"
+    When I send POST /orders:batch with JSON:
+      """
+      {
+        "mode": "partial",
+        "orders": [
+          { "customer": "Initech", "items": ["stapler"] },
+          { "customer": "Globex", "items": ["nut"] }
+        ]
+      }
+      """
+    Then the HTTP status should be 207
+    And the response field "results.0.status" should be "201"
+    And the response field "results.1.status" should be "403"
+
+  @off-contract
+  Scenario Outline: 6) Malformed batches are rejected before touching the database
+    When I send POST /orders:batch with JSON:
+      """
+      <body>
+      """
+    Then the HTTP status should be <status>
+    And the response header "Content-Type" should be "application/problem+json"
+    And the response field "title" should be "<title>"
+
+    Examples:
+      | body                                                                   | status | title              |
+      | { "orders": [] }                                                       | 422    | Empty batch        |
+      | { "mode": "best-effort", "orders": [{ "customer": "A", "items": [] }] } | 400    | Invalid batch mode |
@@ -0,0 +1,213 @@
+Feature: Customers as a resource referenced by orders
+
+  Background:
+    Given the topic "orders.events" is accessible
+
+  Scenario: 1) A customer is created once per name and published
+    Given I use a fresh tenant
+    When I send POST /customers with JSON:
+      """
+      { "name": "Acme Corp", "email": "billing@acme.test" }
+      """
+    Then the HTTP status should be 201
+    And the response header "Location" should not be empty
+    And the response field "name" should be "Acme Corp"
+    And the response field "email" should be "billing@acme.test"
+    And I store the "id" from the response body into "acme"
+    And there must be an event on topic "orders.events" of type "CustomerCreated" for "acme" within 5s
+    When I send POST /customers with JSON:
+      """
+      { "name": "ACME CORP" }
+      """
+    Then the HTTP status should be 409
+    And the response field "title" should be "Customer already exists"
+    When I send GET /customers/{acme}
+    Then the HTTP status should be 200
+    And the response field "id" should be "{acme}"
+    When I send GET /customers
+    Then the response field "count" should be "1"
+    And the response field "items.0.name" should be "Acme Corp"
+
+  Scenario: 2) Orders reference a customer by id
+    Given I use a fresh tenant
+    When I send POST /customers with JSON:
+      """
+      { "name": "Globex" }
+      """
+    And I store the "id" from the response body into "globex"
+    And I send POST /orders with JSON:
+      """
+      { "customerId": "{globex}", "items": ["nut"] }
+      """
+    Then the HTTP status should be 201
+    And the response field "customer" should be "Globex"
+    And the response field "customerId" should be "{globex}"
+    And I store the "id" from the response body into "order_id"
+    And there must be an event on topic "orders.events" of type "OrderCreated" for "order_id" within 5s
+    When I send GET /customers/{globex}/orders
+    Then the HTTP status should be 200
+    And the response field "count" should be "1"
+    And the response field "items.0.id" should be "{order_id}"
+    When I send POST /orders with JSON:
+      """
+      { "customerId": "01J0000000000000000000000X", "items": ["nut"] }
+      """
+    Then the HTTP status should be 422
+    And the response field "title" should be "Unknown customer"
+    When I send POST /orders with JSON:
+      """
+      { "customer": "Initech", "customerId": "{globex}", "items": ["nut"] }
+      """
+    Then the HTTP status should be 422
+    And the response field "detail" should be "customer does not match the name of customerId"
+
+  Scenario: 3) A bare customer name still works and creates the customer
+    Given I use a fresh tenant
+    When I send POST /orders with JSON:
+      """
+      { "customer": "Umbrella", "items": ["vaccine"] }
+      """
+    Then the HTTP status should be 201
+    And I store the "customerId" from the response body into "umbrella"
+    And there must be an event on topic "orders.events" of type "CustomerCreated" for "umbrella" within 5s
+    When I send POST /orders with JSON:
+      """
+      { "customer": "Umbrella", "items": ["antidote"] }
+      """
+    Then the HTTP status should be 201
+    And the response field "customerId" should be "{umbrella}"
+    When I send GET /customers/{umbrella}/orders
+    Then the response field "count" should be "2"
+    When I send GET /customers
+    Then the response field "count" should be "1"
+
+  Scenario: 3b) Order customer names are normalised like /customers
+    Given I use a fresh tenant
+    When I send POST /orders with JSON:
+      """
+      { "customer": "Stark", "items": ["suit"] }
+      """
+    Then the HTTP status should be 201
+    And I store the "customerId" from the response body into "stark"
+    When I send POST /orders with JSON:
+      """
+      { "customer": "  Stark ", "items": ["helmet"] }
+      """
+    Then the HTTP status should be 201
+    And the response field "customer" should be "Stark"
+    And the response field "customerId" should be "{stark}"
+    When I send GET /customers
+    Then the response field "count" should be "1"
+    When I send POST /orders with JSON:
+      """
+      { "customer": "LLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLLL", "items": ["suit"] }
+      """
+    Then the HTTP status should be 422
+    And the response field "detail" should be "customer: name is longer than 255 bytes"
+
+  Scenario: 4) Renaming a customer renames its orders
+    Given I use a fresh tenant
+    When I send POST /orders with JSON:
+      """
+      { "customer": "Initech", "items": ["stapler"] }
+      """
+    And I store the "id" from the response body into "order_id"
+    And I store the "customerId" from the response body into "initech"
+    And I send PUT /customers/{initech} with JSON:
+      """
+      { "name": "Initrode", "email": "ops@initrode.test" }
+      """
+    Then the HTTP status should be 200
+    And the response field "name" should be "Initrode"
+    And there must be an event on topic "orders.events" of type "CustomerUpdated" for "initech" within 5s
+    When I send GET /orders/{order_id}
+    Then the response field "customer" should be "Initrode"
+    And the response field "customerId" should be "{initech}"
+
+  Scenario: 5) A customer with orders cannot be deleted
+    Given I use a fresh tenant
+    When I send POST /orders with JSON:
+      """
+      { "customer": "Hooli", "items": ["phone"] }
+      """
+    And I store the "customerId" from the response body into "hooli"
+    And I send DELETE /customers/{hooli}
+    Then the HTTP status should be 409
+    And the response field "title" should be "Customer has orders"
+    When I send POST /customers with JSON:
+      """
+      { "name": "Pied Piper" }
+      """
+    And I store the "id" from the response body into "piper"
+    And I send DELETE /customers/{piper}
+    Then the HTTP status should be 204
+    When I send GET /customers/{piper}
+    Then the HTTP status should be 404
+
+  Scenario Outline: 6) Invalid customers are rejected
+    Given I use a fresh tenant
+    When I send POST /customers with JSON:
+      """
+      <body>
+      """
+    Then the HTTP status should be 422
+    And the response field "title" should be "Invalid customer"
+
+    Examples:
+      | body                                        |
+      | { "name": "  " }                            |
+      | { "name": "Soylent", "email": "not-email" } |
+
+  Scenario: 7) A customer-bound client only sees and creates its own customer
+    Given I am authenticated with a JWT for subject "bob" bound to customer "Initech"
+    When I send POST /orders with JSON:
+      """
+      { "customer": "Initech", "items": ["stapler"] }
+      """
+    Then the HTTP status should be 201
+    When I send GET /customers
+    Then the response field "count" should be "1"
+    And the response field "items.0.name" should be "Initech"
+    When I send POST /customers with JSON:
+      """
+      { "name": "Globex" }
+      """
+    Then the HTTP status should be 403
+
+  Scenario: 7b) A customer-bound client matches its customer regardless of case
+    Given I use a fresh tenant
+    When I send POST /orders with JSON:
+      """
+      { "customer": "Initech", "items": ["stapler"] }
+      """
+    And I store the "id" from the response body into "order_id"
+    Given I am authenticated with a JWT for subject "bob" bound to customer "initech"
+    When I send GET /orders/{order_id}
+    Then the HTTP status should be 200
+    And the response field "customer" should be "Initech"
+
+  Scenario: 7c) A client bound by customer id keeps its orders after a rename
+    Given I use a fresh tenant
+    When I send POST /orders with JSON:
+      """
+      { "customer": "Initech", "items": ["stapler"] }
+      """
+    And I store the "id" from the response body into "order_id"
+    And I store the "customerId" from the response body into "initech"
+    Given I am authenticated with a JWT for subject "bob" bound to customer_id "{initech}"
+    When I send PUT /customers/{initech} with JSON:
+      """
+      { "name": "Initrode" }
+      """
+    Then the HTTP status should be 200
+    When I send GET /orders/{order_id}
+    Then the HTTP status should be 200
+    And the response field "customer" should be "Initrode"
+    When I send GET /customers
+    Then the response field "count" should be "1"
+    And the response field "items.0.name" should be "Initrode"
+    When I send POST /customers with JSON:
+      """
+      { "name": "Globex" }
+      """
+    Then the HTTP status should be 403
@@ -0,0 +1,38 @@
+Feature: Exporting orders as NDJSON and CSV
+
+  Background:
+    Given I use a fresh tenant
+    And I have the following orders:
+      | customer  | items      | status | as   |
+      | Acme Corp | bolt,nut   | PAID   | acme |
+      | Globex    | widget     | OPEN   |      |
+      | Initech   | stapler    | PAID   |      |
+
+  Scenario: 1) Without Accept the export is NDJSON, with the list filters and sort
+    When I send GET /orders/export?status=PAID&sort=customer
+    Then the HTTP status should be 200
+    And the response header "Content-Type" should be "application/x-ndjson; charset=utf-8"
+    And the response header "X-Snapshot-At" should not be empty
+    And the exported customers should be "Acme Corp, Initech"
+
+  Scenario: 2) CSV by Accept, compressed with gzip
+    Given I set headers:
+      | Accept          | text/csv |
+      | Accept-Encoding | gzip     |
+    When I send GET /orders/export?sort=-customer
+    Then the HTTP status should be 200
+    And the response header "Content-Type" should be "text/csv; charset=utf-8"
+    And the response header "Content-Encoding" should be "gzip"
+    And the exported customers should be "Initech, Globex, Acme Corp"
+
+  Scenario: 3) Pagination does not apply to the export
+    When I send GET /orders/export?limit=1&offset=1
+    Then the HTTP status should be 200
+    And the exported customers should be "Initech, Globex, Acme Corp"
+
+  Scenario: 4) Formats other than NDJSON and CSV are refused
+    Given I set headers:
+      | Accept | application/xml |
+    When I send GET /orders/export
+    Then the HTTP status should be 406
+    And the response field "title" should be "Not Acceptable"
@@ -0,0 +1,197 @@
+Feature: Managing orders over GraphQL
+
+  Scenario: 1) createOrder publishes the same event as the REST API
+    Given the topic "orders.events" is accessible
+    When I send the GraphQL query:
+      """
+      mutation {
+        createOrder(input: { customer: "Acme", items: ["x", "y"] }) {
+          id tenantId customer status items createdAt createdBy
+        }
+      }
+      """
+    Then the GraphQL response should be:
+      """
+      {
+        "data": {
+          "createOrder": {
+            "id": "$ANY_ULID",
+            "tenantId": "default",
+            "customer": "Acme",
+            "status": "OPEN",
+            "items": [
+              "x",
+              "y"
+            ],
+            "createdAt": "$ANY_TIMESTAMP",
+            "createdBy": "bdd-tests"
+          }
+        }
+      }
+      """
+    And I store the "data.createOrder.id" from the GraphQL response into "order_id"
+    And there must be an event on topic "orders.events" of type "OrderCreated" for "order_id" within 5s
+    When I send GET /orders/{order_id}
+    Then the HTTP status should be 200
+
+  Scenario: 2) An order created over REST can be read and updated over GraphQL
+    Given I have an order created via API:
+      """
+      {
+        "customer": "Umbrella",
+        "items": [
+          "a"
+        ]
+      }
+      """
+    When I send the GraphQL query:
+      """
+      mutation {
+        updateOrderStatus(id: "{order_id}", status: "PAID") { id status updatedBy }
+      }
+      """
+    Then the GraphQL response should have no errors
+    When I send the GraphQL query:
+      """
+      {
+        order(id: "{order_id}") { id customer status updatedBy }
+        missing: order(id: "01HZZZZZZZZZZZZZZZZZZZZZZZ") { id }
+      }
+      """
+    Then the GraphQL response should be:
+      """
+      {
+        "data": {
+          "order": {
+            "id": "{order_id}",
+            "customer": "Umbrella",
+            "status": "PAID",
+            "updatedBy": "bdd-tests"
+          },
+          "missing": null
+        }
+      }
+      """
+
+  Scenario: 3) orders is paginated with cursors, newest first
+    When I send the GraphQL query:
+      """
+      mutation { createOrder(input: { customer: "Paginated Inc", items: ["1"] }) { id } }
+      """
+    And I store the "data.createOrder.id" from the GraphQL response into "first"
+    And I send the GraphQL query:
+      """
+      mutation { createOrder(input: { customer: "Paginated Inc", items: ["2"] }) { id } }
+      """
+    And I store the "data.createOrder.id" from the GraphQL response into "second"
+    And I send the GraphQL query:
+      """
+      mutation { createOrder(input: { customer: "Paginated Inc", items: ["3"] }) { id } }
+      """
+    And I store the "data.createOrder.id" from the GraphQL response into "third"
+    When I send the GraphQL query:
+      """
+      {
+        orders(filter: { customer: "Paginated Inc" }, first: 2) {
+          edges { node { id } }
+          pageInfo { hasNextPage }
+        }
+      }
+      """
+    Then the GraphQL response should be:
+      """
+      {
+        "data": {
+          "orders": {
+            "edges": [
+              { "node": { "id": "{third}" } },
+              { "node": { "id": "{second}" } }
+            ],
+            "pageInfo": { "hasNextPage": true }
+          }
+        }
+      }
+      """
+    When I send the GraphQL query:
+      """
+      { orders(filter: { customer: "Paginated Inc" }, first: 2) { pageInfo { endCursor } } }
+      """
+    And I store the "data.orders.pageInfo.endCursor" from the GraphQL response into "cursor"
+    And I send the GraphQL query:
+      """
+      { orders(filter: { customer: "Paginated Inc" }, first: 1, after: "{cursor}") { edges { node { id } } } }
+      """
+    Then the GraphQL response should be:
+      """
+      {
+        "data": {
+          "orders": {
+            "edges": [
+              { "node": { "id": "{first}" } }
+            ]
+          }
+        }
+      }
+      """
+    When I send the GraphQL query:
+      """
+      { orders(first: 500) { edges { cursor } } }
+      """
+    Then the GraphQL error code should be BAD_USER_INPUT
+
+  Scenario: 4) Mutations require orders:write
+    Given I am authenticated with a JWT for subject "auditor" with scopes "orders:read"
+    When I send the GraphQL query:
+      """
+      { orders(first: 1) { edges { cursor } } }
+      """
+    Then the GraphQL response should have no errors
+    When I send the GraphQL query:
+      """
+      mutation { createOrder(input: { customer: "Acme", items: ["x"] }) { id } }
+      """
+    Then the GraphQL error code should be FORBIDDEN
+    Given I am not authenticated
+    When I send the GraphQL query:
+      """
+      { orders(first: 1) { edges { cursor } } }
+      """
+    Then the HTTP status should be 401
+
+  Scenario: 5) orderEvents pushes the changes of an order over WebSocket
+    Given I have an order created via API:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    And I subscribe over GraphQL to:
+      """
+      subscription { orderEvents(orderId: "{order_id}") { id type orderId payload order { status } } }
+      """
+    When I send the GraphQL query:
+      """
+      mutation { updateOrderStatus(id: "{order_id}", status: "SHIPPED") { id } }
+      """
+    Then the GraphQL response should have no errors
+    And the GraphQL subscription should deliver an "OrderStatusUpdated" event for "order_id" within 5s
+
+  Scenario: 6) Subscribing to another customer's order is forbidden
+    Given I have an order created via API:
+      """
+      {
+        "customer": "Globex",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    And I am authenticated with a JWT for subject "acme-portal" bound to customer "Acme"
+    When I subscribe over GraphQL to:
+      """
+      subscription { orderEvents(orderId: "{order_id}") { id } }
+      """
+    Then the GraphQL subscription should fail with code FORBIDDEN
@@ -0,0 +1,191 @@
+Feature: Managing orders over gRPC
+
+  Scenario: 1) Creating an order over gRPC publishes the same event as HTTP
+    Given the topic "orders.events" is accessible
+    When I call gRPC CreateOrder with:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x",
+          "y"
+        ]
+      }
+      """
+    Then the gRPC status should be OK
+    And the gRPC response should be:
+      """
+      {
+        "order": {
+          "id": "$ANY_ULID",
+          "tenantId": "default",
+          "customer": "Acme",
+          "customerId": "$ANY_ULID",
+          "status": "OPEN",
+          "items": [
+            "x",
+            "y"
+          ],
+          "createdAt": "$ANY_TIMESTAMP",
+          "updatedAt": "$ANY_TIMESTAMP",
+          "createdBy": "bdd-tests",
+          "updatedBy": "bdd-tests"
+        }
+      }
+      """
+    And I store the "order.id" from the gRPC response into "order_id"
+    And there must be an event on topic "orders.events" of type "OrderCreated" for "order_id" within 5s
+    When I send GET /orders/{order_id}
+    Then the HTTP status should be 200
+
+  Scenario: 2) An order created over HTTP can be read and updated over gRPC
+    Given I have an order created via API:
+      """
+      {
+        "customer": "Umbrella",
+        "items": [
+          "a"
+        ]
+      }
+      """
+    When I call gRPC GetOrder with:
+      """
+      {
+        "id": "{order_id}"
+      }
+      """
+    Then the gRPC status should be OK
+    When I call gRPC UpdateOrderStatus with:
+      """
+      {
+        "id": "{order_id}",
+        "status": "DONE"
+      }
+      """
+    Then the gRPC status should be OK
+    And the gRPC response should be:
+      """
+      {
+        "order": {
+          "id": "{order_id}",
+          "tenantId": "default",
+          "customer": "Umbrella",
+          "customerId": "$ANY_ULID",
+          "status": "DONE",
+          "items": [
+            "a"
+          ],
+          "createdAt": "$ANY_TIMESTAMP",
+          "updatedAt": "$ANY_TIMESTAMP",
+          "createdBy": "bdd-tests",
+          "updatedBy": "bdd-tests"
+        }
+      }
+      """
+
+  Scenario: 3) WatchOrders streams the changes of an order
+    Given I have an order created via API:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    And I watch the order "order_id" over gRPC
+    When I call gRPC UpdateOrderStatus with:
+      """
+      {
+        "id": "{order_id}",
+        "status": "DONE"
+      }
+      """
+    Then the gRPC status should be OK
+    And the gRPC watch should deliver an "OrderStatusUpdated" event for "order_id" within 5s
+
+  Scenario: 4) Listing orders over gRPC
+    Given I have an order created via API:
+      """
+      {
+        "customer": "Initech",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    When I call gRPC ListOrders with:
+      """
+      {
+        "customer": "Initech",
+        "limit": 10
+      }
+      """
+    Then the gRPC status should be OK
+
+  Scenario: 5) Unknown orders are NotFound
+    When I call gRPC GetOrder with:
+      """
+      {
+        "id": "01HZZZZZZZZZZZZZZZZZZZZZZZ"
+      }
+      """
+    Then the gRPC status should be NotFound
+
+  Scenario: 6) gRPC applies the same authentication and scopes as HTTP
+    Given I am not authenticated
+    When I call gRPC ListOrders
+    Then the gRPC status should be Unauthenticated
+    Given I am authenticated with a JWT for subject "auditor" with scopes "orders:read"
+    When I call gRPC ListOrders
+    Then the gRPC status should be OK
+    When I call gRPC CreateOrder with:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    Then the gRPC status should be PermissionDenied
+
+  Scenario: 7) Orders reference a customer by id over gRPC
+    Given I use a fresh tenant
+    When I send POST /customers with JSON:
+      """
+      { "name": "Globex" }
+      """
+    Then the HTTP status should be 201
+    And I store the "id" from the response body into "globex"
+    When I call gRPC CreateOrder with:
+      """
+      {
+        "customerId": "{globex}",
+        "items": [
+          "nut"
+        ]
+      }
+      """
+    Then the gRPC status should be OK
+    And the gRPC response field "order.customerId" should be "{globex}"
+    And the gRPC response field "order.customer" should be "Globex"
+    And I store the "order.id" from the gRPC response into "order_id"
+    When I call gRPC GetOrder with:
+      """
+      {
+        "id": "{order_id}"
+      }
+      """
+    Then the gRPC status should be OK
+    And the gRPC response field "order.customerId" should be "{globex}"
+    When I call gRPC CreateOrder with:
+      """
+      {
+        "customerId": "01J0000000000000000000000X",
+        "items": [
+          "nut"
+        ]
+      }
+      """
+    Then the gRPC status should be InvalidArgument
@@ -0,0 +1,93 @@
+Feature: Bulk import of orders from CSV and NDJSON files
+
+  Background:
+    Given the topic "orders.events" is accessible
+
+  Scenario: 1) A CSV import creates the valid rows and reports the others
+    Given I use a fresh tenant
+    And I remember the metric orders_created_total
+    When I upload the file "orders.csv" to /imports:
+      """
+      customer,items
+      Acme Corp,"[""bolt""]"
+      ,"[""nut""]"
+      Globex,"[""nut"",""washer""]"
+      Initech,not-json
+      """
+    Then the HTTP status should be 202
+    And the response header "Location" should not be empty
+    And the response field "status" should be "queued"
+    And the response field "format" should be "csv"
+    And the response field "filename" should be "orders.csv"
+    And the import should finish as "completed" within 15s
+    And the response field "totalRows" should be "4"
+    And the response field "processedRows" should be "4"
+    And the response field "createdRows" should be "2"
+    And the response field "rejectedRows" should be "2"
+    And the response field "rejects.0.line" should be "3"
+    And the response field "rejects.0.error" should be "customer is required"
+    And the response field "rejects.1.line" should be "5"
+    And the response field "rejectsUrl" should be "/imports/{import_id}/rejects"
+    And the metric orders_created_total should have increased by 2
+    When I send GET /orders?sort=customer
+    Then the listed customers should be "Acme Corp, Globex"
+    When I send GET /imports/{import_id}/rejects
+    Then the HTTP status should be 200
+    And the rejected lines should be "3, 5"
+
+  Scenario: 2) Every imported order is published as OrderCreated
+    Given I use a fresh tenant
+    When I send POST /imports with NDJSON:
+      """
+      {"customer": "Umbrella", "items": ["vaccine"]}
+
+      {"customer": "Umbrella", "items": "not a list"}
+      """
+    Then the HTTP status should be 202
+    And the import should finish as "completed" within 15s
+    And the response field "createdRows" should be "1"
+    And the response field "rejects.0.line" should be "3"
+    When I send GET /orders
+    And I store the "items.0.id" from the response body into "umbrella"
+    Then there must be an event on topic "orders.events" of type "OrderCreated" for "umbrella" within 5s
+    When I send GET /imports/{import_id}/rejects
+    Then the response header "Content-Type" should be "application/x-ndjson; charset=utf-8"
+    And the rejected lines should be "3"
+
+  Scenario: 3) A customer-bound client only imports its own orders
+    Given I am authenticated with a JWT for subject "bob" bound to customer "Initech"
+    When I send POST /imports with CSV:
+      """
+      customer,items
+      Initech,"[""stapler""]"
+      Globex,"[""nut""]"
+      """
+    Then the HTTP status should be 202
+    And the import should finish as "completed" within 15s
+    And the response field "createdRows" should be "1"
+    And the response field "rejects.0.line" should be "3"
+    And the response field "rejects.0.error" should be "forbidden: cannot create orders for another customer"
+
+  Scenario Outline: 4) Unreadable files are rejected before being queued
+    When I send POST /imports with <format>:
+      """
+      <body>
+      """
+    Then the HTTP status should be <status>
+    And the response header "Content-Type" should be "application/problem+json"
+    And the response field "title" should be "<title>"
+
+    Examples:
+      | format | body                  | status | title               |
+      | CSV    | name,qty              | 422    | Invalid import file |
+      | CSV    | customer,items        | 422    | Empty import        |
+      | NDJSON |                       | 422    | Empty import        |
+
+  @off-contract
+  Scenario: 5) Only CSV and NDJSON files are accepted
+    When I send POST /imports with JSON:
+      """
+      [{ "customer": "Acme Corp", "items": ["bolt"] }]
+      """
+    Then the HTTP status should be 415
+    And the response field "title" should be "Unsupported Media Type"
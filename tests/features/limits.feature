@@ -0,0 +1,27 @@
+Feature: Protecting the API with rate and size limits
+
+  Scenario: 1) A client flooding order creation is throttled
+    Given I am authenticated with a JWT for subject "runaway-script"
+    When I send 40 concurrent POST /orders with JSON:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    Then at least one of them should be rejected with 429 and a Retry-After header
+
+  Scenario: 2) Oversized bodies are rejected before decoding
+    When I send POST /orders with a JSON body of 70000 bytes
+    Then the HTTP status should be 413
+    And the response header "Content-Type" should be "application/problem+json"
+
+  Scenario: 3) Orders with too many items are rejected
+    When I send POST /orders with 101 items of 1 characters
+    Then the HTTP status should be 422
+
+  Scenario: 4) Orders with oversized items are rejected
+    When I send POST /orders with 1 items of 300 characters
+    Then the HTTP status should be 422
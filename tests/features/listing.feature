@@ -0,0 +1,80 @@
+Feature: Filtering, searching and sorting the order list
+
+  Background:
+    Given I use a fresh tenant
+    And I have the following orders:
+      | customer    | items                | status | as    |
+      | Acme Corp   | Blue Widget,bolt     | OPEN   | acme  |
+      | Acme Labs   | red gadget           | PAID   | labs  |
+      | Globex      | blue widget          | DONE   |       |
+      | Initech     | stapler              | PAID   |       |
+
+  Scenario: 1) Several statuses at once, sorted by customer
+    When I send GET /orders?status=OPEN,PAID&sort=customer
+    Then the HTTP status should be 200
+    And the listed customers should be "Acme Corp, Acme Labs, Initech"
+
+  Scenario: 2) Sorting by status and then by customer descending
+    When I send GET /orders?sort=status,-customer
+    Then the HTTP status should be 200
+    And the listed customers should be "Globex, Acme Corp, Initech, Acme Labs"
+
+  Scenario: 3) The default order is newest first
+    When I send GET /orders
+    Then the HTTP status should be 200
+    And the listed customers should be "Initech, Globex, Acme Labs, Acme Corp"
+
+  Scenario: 4) Orders with an item containing a text, ignoring case
+    When I send GET /orders?item=WIDGET&sort=customer
+    Then the HTTP status should be 200
+    And the listed customers should be "Acme Corp, Globex"
+
+  Scenario: 5) Full-text search on the customer matches every word by prefix
+    When I send GET /orders?q=acm&sort=customer
+    Then the HTTP status should be 200
+    And the listed customers should be "Acme Corp, Acme Labs"
+    When I send GET /orders?q=acme%20lab
+    Then the HTTP status should be 200
+    And the listed customers should be "Acme Labs"
+
+  Scenario: 6) Only orders updated since a point in time
+    When I send PUT /orders/{acme}/status with JSON:
+      """
+      {
+        "status": "SHIPPED"
+      }
+      """
+    Then the HTTP status should be 200
+    When I send GET /orders/{acme}
+    And I store the "updatedAt" from the response body into "acme_updated"
+    And I send GET /orders?updated_since={acme_updated}
+    Then the HTTP status should be 200
+    And the listed customers should be "Acme Corp"
+
+  @off-contract
+  Scenario Outline: 7) Malformed parameters are rejected
+    When I send GET /orders?<query>
+    Then the HTTP status should be 400
+    And the response header "Content-Type" should be "application/problem+json"
+    And the response field "title" should be "Invalid query parameter"
+
+    Examples:
+      | query                              |
+      | status=OPEN,,PAID                  |
+      | sort=price                         |
+      | sort=-status,status                |
+      | since=yesterday                    |
+      | updated_since=2025-13-01T00:00:00Z |
+      | limit=ten                          |
+      | offset=-5                          |
+      | q=%2B%2B                           |
+      | sku=bolt,,nut                      |
+
+  Scenario: 8) Orders with any of the given SKUs, items kept in order
+    When I send GET /orders?sku=bolt,stapler&sort=customer
+    Then the HTTP status should be 200
+    And the listed customers should be "Acme Corp, Initech"
+    And the response field "items.0.items.0" should be "Blue Widget"
+    And the response field "items.0.items.1" should be "bolt"
+    When I send GET /orders/stats?sku=stapler
+    Then the response field "total" should be "1"
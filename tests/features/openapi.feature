@@ -0,0 +1,37 @@
+Feature: Publishing and enforcing the OpenAPI contract
+
+  Scenario: 1) The API publishes its OpenAPI document
+    Given I am not authenticated
+    When I send GET /openapi.json
+    Then the HTTP status should be 200
+    And the response header "Content-Type" should be "application/json"
+    And the response field "openapi" should be "3.1.0"
+    And the response field "info.title" should be "orders-api"
+
+  @off-contract
+  Scenario: 2) Requests outside the contract are rejected in strict mode
+    Given I remember the metric orders_http_contract_violations_total{kind="request",route="/orders"}
+    When I send POST /orders with JSON:
+      """
+      {
+        "customer": "Acme"
+      }
+      """
+    Then the HTTP status should be 400
+    And the response header "Content-Type" should be "application/problem+json"
+    And the response field "title" should be "Request does not match the API contract"
+    And the metric orders_http_contract_violations_total{kind="request",route="/orders"} should have increased by 1
+
+  Scenario: 3) Responses inside the contract pass through untouched
+    Given I remember the metric orders_http_contract_violations_total{kind="response",route="/orders"}
+    When I send POST /orders with JSON:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    Then the HTTP status should be 201
+    And the metric orders_http_contract_violations_total{kind="response",route="/orders"} should have increased by 0
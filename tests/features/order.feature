@@ -19,7 +19,9 @@ Feature: Publishing and consuming service order events
       """
       {
         "customer": "Acme",
+        "customerId": "$ANY_ULID",
         "id": "$ANY_ULID",
+        "tenantId": "default",
         "items": [
           "x",
           "y"
@@ -107,12 +109,107 @@ Feature: Publishing and consuming service order events
       """
       {
         "customer": "Umbrella",
+        "customerId": "$ANY_ULID",
         "id": "$ANY_ULID",
+        "tenantId": "default",
         "items": [
           "a"
         ],
         "status": "DONE",
         "createdAt": "$ANY_TIMESTAMP",
-        "updatedAt": "$ANY_TIMESTAMP"
+        "updatedAt": "$ANY_TIMESTAMP",
+        "createdBy": "bdd-tests",
+        "updatedBy": "bdd-tests"
+      }
+      """
+
+  Scenario: 6) The request id is echoed back and forwarded to the Kafka event
+    Given I set headers:
+      | X-Request-Id | bdd-correlation-6 |
+    When I send POST /orders with JSON:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    Then the HTTP status should be 201
+    And the response header "X-Request-Id" should be "bdd-correlation-6"
+    And I store the "id" from the response body into "order_id"
+    And there must be an event on topic "orders.events" of type "OrderCreated" for "order_id" within 5s
+    And the event should have header "x-request-id" equal to "bdd-correlation-6"
+
+  Scenario: 7) Creating and updating orders is reflected in the metrics
+    Given I remember the metric orders_created_total
+    And I remember the metric orders_status_transitions_total{status="DONE"}
+    And I remember the metric orders_kafka_publish_total{result="success"}
+    And I remember the metric orders_http_requests_total{route="/orders",method="POST",status="201"}
+    And I have an order created via API:
+      """
+      {
+        "customer": "Initech",
+        "items": [
+          "a"
+        ]
+      }
+      """
+    When I send PUT /orders/{order_id}/status with JSON:
+      """
+      {
+        "status": "DONE"
+      }
+      """
+    Then the HTTP status should be 200
+    And the metric orders_created_total should have increased by 1
+    And the metric orders_status_transitions_total{status="DONE"} should have increased by 1
+    And the metric orders_kafka_publish_total{result="success"} should have increased by at least 2
+    And the metric orders_http_requests_total{route="/orders",method="POST",status="201"} should have increased by 1
+    And the metric go_sql_open_connections{db_name="orders"} should be exposed
+
+  Scenario: 8) The trace context of the request is propagated into the Kafka event
+    Given I set headers:
+      | traceparent | 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 |
+    When I send POST /orders with JSON:
+      """
+      {
+        "customer": "Globex",
+        "items": [
+          "z"
+        ]
+      }
+      """
+    Then the HTTP status should be 201
+    And I store the "id" from the response body into "order_id"
+    And there must be an event on topic "orders.events" of type "OrderCreated" for "order_id" within 5s
+    And the event should carry a valid traceparent
+    And the event should belong to trace "4bf92f3577b34da6a3ce929d0e0e4736"
+
+  Scenario: 9) Liveness and readiness probes report each dependency
+    When I send GET /livez
+    Then the HTTP status should be 200
+    And the response body should be:
+      """
+      {
+        "status": "ok"
+      }
+      """
+    When I send GET /readyz
+    Then the HTTP status should be 200
+    And the response body should be:
+      """
+      {
+        "status": "ok",
+        "checks": {
+          "mysql": {
+            "status": "ok",
+            "latencyMs": "$ANY_NUMBER"
+          },
+          "kafka": {
+            "status": "ok",
+            "latencyMs": "$ANY_NUMBER"
+          }
+        }
       }
       """
@@ -0,0 +1,52 @@
+Feature: Aggregated order statistics
+
+  Background:
+    Given I use a fresh tenant
+    And I have the following orders:
+      | customer  | items   | status |
+      | Acme Corp | bolt    | PAID   |
+      | Acme Corp | nut     | OPEN   |
+      | Globex    | widget  | DONE   |
+      | Initech   | stapler | PAID   |
+
+  Scenario: 1) Counts by status, today's bucket and top customers
+    When I send GET /orders/stats?top=2
+    Then the HTTP status should be 200
+    And the response field "total" should be "4"
+    And the response field "byStatus.PAID" should be "2"
+    And the response field "byStatus.OPEN" should be "1"
+    And the response field "interval" should be "day"
+    And the response field "series.-1.count" should be "4"
+    And the response field "topCustomers.0.customer" should be "Acme Corp"
+    And the response field "topCustomers.0.count" should be "2"
+    And the response field "topCustomers.1.customer" should be "Globex"
+
+  Scenario: 2) The list filters apply to every aggregate
+    When I send GET /orders/stats?status=PAID,DONE&interval=hour
+    Then the HTTP status should be 200
+    And the response field "total" should be "3"
+    And the response field "series.-1.count" should be "3"
+    And the response field "topCustomers.0.customer" should be "Acme Corp"
+    And the response field "topCustomers.0.count" should be "1"
+
+  Scenario: 3) Average time in a status comes from the status history
+    When I send GET /orders/stats
+    Then the HTTP status should be 200
+    And the response field "timeInStatus.0.status" should be "OPEN"
+    And the response field "timeInStatus.0.samples" should be "3"
+
+  @off-contract
+  Scenario Outline: 4) Malformed parameters are rejected
+    When I send GET /orders/stats?<query>
+    Then the HTTP status should be 400
+    And the response field "title" should be "Invalid query parameter"
+
+    Examples:
+      | query                                    |
+      | interval=week                            |
+      | top=0                                    |
+      | interval=hour&since=2020-01-01T00:00:00Z |
+      | status=OPEN,,PAID                        |
+      | sort=-createdAt                          |
+      | limit=10                                 |
+      | offset=5                                 |
@@ -0,0 +1,48 @@
+Feature: Streaming order changes over Server-Sent Events
+
+  Scenario: 1) A single order stream pushes its status changes
+    Given I have an order created via API:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    And I open the event stream at /orders/{order_id}/stream
+    When I send PUT /orders/{order_id}/status with JSON:
+      """
+      {
+        "status": "DONE"
+      }
+      """
+    Then the HTTP status should be 200
+    And the stream should deliver an "OrderStatusUpdated" event for "order_id" within 5s
+
+  Scenario: 2) A reconnecting client resumes from Last-Event-ID
+    Given I open the event stream at /orders/stream
+    And I have an order created via API:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    And the stream should deliver an "OrderCreated" event for "order_id" within 5s
+    And I close the event stream
+    When I send PUT /orders/{order_id}/status with JSON:
+      """
+      {
+        "status": "DONE"
+      }
+      """
+    Then the HTTP status should be 200
+    When I reopen the event stream at /orders/{order_id}/stream from the last received event
+    Then the stream should deliver an "OrderStatusUpdated" event for "order_id" within 5s
+
+  Scenario: 3) Streaming an unknown order is a 404
+    When I send GET /orders/01HZZZZZZZZZZZZZZZZZZZZZZZ/stream
+    Then the HTTP status should be 404
@@ -0,0 +1,77 @@
+Feature: Isolating orders per tenant
+
+  Scenario: 1) Orders of one tenant are invisible to another
+    Given I set headers:
+      | X-Tenant-Id | retail |
+    And I have an order created via API:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    When I send GET /orders/{order_id}
+    Then the HTTP status should be 200
+    Given I set headers:
+      | X-Tenant-Id | wholesale |
+    When I send GET /orders/{order_id}
+    Then the HTTP status should be 404
+    When I send PUT /orders/{order_id}/status with JSON:
+      """
+      {
+        "status": "DONE"
+      }
+      """
+    Then the HTTP status should be 404
+    When I send GET /orders
+    Then the HTTP status should be 200
+    And the listed orders should not include "order_id"
+
+  Scenario: 2) Events carry the tenant in key and headers
+    Given the topic "orders.events" is accessible
+    And I set headers:
+      | X-Tenant-Id | retail |
+    When I send POST /orders with JSON:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    Then the HTTP status should be 201
+    And I store the "id" from the response body into "order_id"
+    And there must be an event on topic "orders.events" of type "OrderCreated" for "order_id" within 5s
+    And the event key should be "retail/{order_id}"
+    And the event should have header "x-tenant-id" equal to "retail"
+
+  Scenario: 3) A caller bound to a tenant cannot switch tenants via header
+    Given I am authenticated with a JWT for subject "carol" bound to tenant "retail"
+    And I set headers:
+      | X-Tenant-Id | wholesale |
+    When I send GET /orders
+    Then the HTTP status should be 403
+    And the response header "Content-Type" should be "application/problem+json"
+
+  Scenario: 4) Malformed tenant ids are rejected
+    Given I set headers:
+      | X-Tenant-Id | not a tenant! |
+    When I send GET /orders
+    Then the HTTP status should be 400
+
+  Scenario: 5) A caller without a tenant binding only reaches its allowed tenants
+    Given I am authenticated with a JWT for subject "dave" allowed in tenants "retail"
+    When I send GET /orders
+    Then the HTTP status should be 200
+    Given I set headers:
+      | X-Tenant-Id | retail |
+    When I send GET /orders
+    Then the HTTP status should be 200
+    Given I set headers:
+      | X-Tenant-Id | wholesale |
+    When I send GET /orders
+    Then the HTTP status should be 403
+    And the response header "Content-Type" should be "application/problem+json"
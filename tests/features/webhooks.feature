@@ -0,0 +1,117 @@
+Feature: Delivering order events to webhook subscriptions
+
+  Background:
+    Given I am authenticated with a JWT for subject "ops" with scopes "orders:admin"
+
+  Scenario: 1) A subscribed endpoint receives signed order events
+    Given I register a webhook "partner" for events "OrderCreated,OrderStatusUpdated"
+    And I have an order created via API:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    Then the webhook "partner" should receive an "OrderCreated" event for "order_id" within 5s
+    When I send PUT /orders/{order_id}/status with JSON:
+      """
+      {
+        "status": "DONE"
+      }
+      """
+    Then the HTTP status should be 200
+    And the webhook "partner" should receive an "OrderStatusUpdated" event for "order_id" within 5s
+    And the delivery log of webhook "partner" should show the "OrderCreated" delivery succeeded after 1 attempt
+
+  Scenario: 2) Failed deliveries are retried with backoff
+    Given I register a webhook "flaky" for events "OrderCreated" whose endpoint fails 2 times
+    And I have an order created via API:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    Then the webhook "flaky" should receive an "OrderCreated" event for "order_id" within 10s
+    And the delivery log of webhook "flaky" should show the "OrderCreated" delivery succeeded after 3 attempts
+
+  Scenario: 3) Only subscribed event types are delivered
+    Given I register a webhook "created-only" for events "OrderCreated"
+    And I have an order created via API:
+      """
+      {
+        "customer": "Acme",
+        "items": [
+          "x"
+        ]
+      }
+      """
+    And the webhook "created-only" should receive an "OrderCreated" event for "order_id" within 5s
+    When I send PUT /orders/{order_id}/status with JSON:
+      """
+      {
+        "status": "DONE"
+      }
+      """
+    Then the webhook "created-only" should not receive an "OrderStatusUpdated" event within 2s
+
+  Scenario: 4) Invalid subscriptions are rejected
+    When I send POST /webhooks with JSON:
+      """
+      {
+        "url": "ftp://example.com/hook",
+        "eventTypes": [
+          "OrderCreated"
+        ]
+      }
+      """
+    Then the HTTP status should be 422
+    When I send POST /webhooks with JSON:
+      """
+      {
+        "url": "https://example.com/hook",
+        "eventTypes": [
+          "OrderDeleted"
+        ]
+      }
+      """
+    Then the HTTP status should be 422
+
+  Scenario Outline: 4b) Webhooks cannot point into the internal network
+    When I send POST /webhooks with JSON:
+      """
+      {
+        "url": "<url>",
+        "eventTypes": [
+          "OrderCreated"
+        ]
+      }
+      """
+    Then the HTTP status should be 422
+    And the response field "title" should be "Invalid webhook"
+
+    Examples:
+      | url                                          |
+      | http://127.0.0.1:3000/orders                 |
+      | http://localhost:3000/metrics                |
+      | http://169.254.169.254/latest/meta-data      |
+      | http://10.0.0.5/hook                         |
+      | http://[::1]:8099/hook                       |
+
+  Scenario: 5) A deleted subscription is gone
+    Given I register a webhook "temp" for events "OrderCreated"
+    When I send GET /webhooks/{temp_id}
+    Then the HTTP status should be 200
+    When I send DELETE /webhooks/{temp_id}
+    Then the HTTP status should be 204
+    When I send GET /webhooks/{temp_id}
+    Then the HTTP status should be 404
+
+  Scenario: 6) Managing webhooks requires the admin scope
+    Given I am authenticated with a JWT for subject "writer" with scopes "orders:read orders:write"
+    When I send GET /webhooks
+    Then the HTTP status should be 403
@@ -0,0 +1,69 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// ExportedRows lê o corpo de GET /orders/export (gzip se Content-Encoding
+// disser) como uma lista de linhas campo → valor, em NDJSON ou CSV conforme
+// o Content-Type.
+func ExportedRows(h http.Header, body []byte) ([]map[string]string, error) {
+	if h.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("export: invalid gzip: %w", err)
+		}
+		if body, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("export: invalid gzip: %w", err)
+		}
+	}
+
+	media, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	var rows []map[string]string
+	switch media {
+	case "application/x-ndjson":
+		sc := bufio.NewScanner(bytes.NewReader(body))
+		sc.Buffer(make([]byte, 64<<10), 1<<20)
+		for sc.Scan() {
+			if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+				continue
+			}
+			var obj map[string]any
+			if err := json.Unmarshal(sc.Bytes(), &obj); err != nil {
+				return nil, fmt.Errorf("export: line %d is not JSON: %w", len(rows)+1, err)
+			}
+			row := map[string]string{}
+			for k, v := range obj {
+				row[k] = fmt.Sprint(v)
+			}
+			rows = append(rows, row)
+		}
+		return rows, sc.Err()
+	case "text/csv":
+		recs, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("export: invalid CSV: %w", err)
+		}
+		if len(recs) == 0 {
+			return nil, fmt.Errorf("export: CSV without header")
+		}
+		for _, rec := range recs[1:] {
+			row := map[string]string{}
+			for i, col := range recs[0] {
+				row[col] = rec[i]
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("export: unexpected content type %q", h.Get("Content-Type"))
+	}
+}
@@ -0,0 +1,78 @@
+package helpers
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseMetricSelector quebra `name{a="x",b="y"}` em nome e labels.
+func ParseMetricSelector(sel string) (string, map[string]string, error) {
+	name, rest, hasLabels := strings.Cut(sel, "{")
+	labels := map[string]string{}
+	if !hasLabels {
+		return name, labels, nil
+	}
+	if !strings.HasSuffix(rest, "}") {
+		return "", nil, fmt.Errorf("invalid metric selector %q: missing '}'", sel)
+	}
+	rest = strings.TrimSuffix(rest, "}")
+	for rest != "" {
+		k, after, ok := strings.Cut(rest, "=\"")
+		if !ok {
+			return "", nil, fmt.Errorf("invalid metric selector %q", sel)
+		}
+		v, after, ok := strings.Cut(after, "\"")
+		if !ok {
+			return "", nil, fmt.Errorf("invalid metric selector %q: unterminated value", sel)
+		}
+		labels[strings.TrimSpace(k)] = v
+		rest = strings.TrimPrefix(after, ",")
+	}
+	return name, labels, nil
+}
+
+// SumMetric soma, no texto de exposição do Prometheus, todas as séries com o
+// nome do seletor cujos labels contenham os labels pedidos.
+// Devolve found=false se nenhuma série casar.
+func SumMetric(exposition, selector string) (sum float64, found bool, err error) {
+	wantName, wantLabels, err := ParseMetricSelector(selector)
+	if err != nil {
+		return 0, false, err
+	}
+	sc := bufio.NewScanner(strings.NewReader(exposition))
+	sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.LastIndexByte(line, ' ')
+		if idx < 0 {
+			continue
+		}
+		series, valStr := line[:idx], line[idx+1:]
+		name, labels, err := ParseMetricSelector(series)
+		if err != nil || name != wantName {
+			continue
+		}
+		match := true
+		for k, v := range wantLabels {
+			if labels[k] != v {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		v, err := strconv.ParseFloat(valStr, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("metric %s: invalid value %q", series, valStr)
+		}
+		sum += v
+		found = true
+	}
+	return sum, found, sc.Err()
+}
@@ -13,6 +13,7 @@ var ulidRe = regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
 
 const PlaceholderAnyULID = "$ANY_ULID"
 const PlaceholderAnyTimestamp = "$ANY_TIMESTAMP"
+const PlaceholderAnyNumber = "$ANY_NUMBER"
 
 // Converte qualquer número (float64 do JSON) ou string para ID textual.
 func AnyToStringID(v any) (string, bool) {
@@ -76,6 +77,12 @@ func MatchWithPlaceholders(expected, actual any, path string) error {
 			}
 			return nil
 
+		case PlaceholderAnyNumber:
+			if _, ok := actual.(float64); !ok {
+				return fmt.Errorf("mismatch at %s: expected number, got %T (%v)", path, actual, actual)
+			}
+			return nil
+
 		default:
 			// string normal → comparação literal
 			if !reflect.DeepEqual(s, actual) {
@@ -3,17 +3,24 @@ package steps
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"orders-tests/helpers"
 	"orders-tests/types"
 	"strings"
+	"time"
 
 	"github.com/cucumber/godog"
 )
 
 func (t *TestData) stepPostJSON(path string, body *godog.DocString) error {
-	// parse do DocString para a struct de request
+	// fora de /orders, e nos lotes, o corpo vai como está (ex.: /webhooks)
+	if !strings.HasPrefix(path, "/orders") || strings.HasSuffix(path, ":batch") {
+		return t.postRaw(path, body)
+	}
+
+	// parse do DocString para a struct de request ({acme} vira o id guardado)
 	var req types.OrderRequest
-	if err := json.Unmarshal([]byte(body.Content), &req); err != nil {
+	if err := json.Unmarshal([]byte(t.api.ResolveVars(body.Content)), &req); err != nil {
 		return fmt.Errorf("invalid JSON for Request: %w", err)
 	}
 	t.lastOrderReq = req
@@ -46,7 +53,32 @@ func (t *TestData) stepPutJSON(path string, body *godog.DocString) error {
 		}
 		return nil
 	}
-	return t.api.Put(path, body.Content, nil)
+	// lotes e demais recursos (ex.: /customers/{id}): corpo como está
+	raw, err := t.rawJSON(body)
+	if err != nil {
+		return err
+	}
+	t.lastOrderResp = types.OrderResponse{}
+	return t.api.Put(path, raw, nil)
+}
+
+func (t *TestData) postRaw(path string, body *godog.DocString) error {
+	raw, err := t.rawJSON(body)
+	if err != nil {
+		return err
+	}
+	t.lastOrderResp = types.OrderResponse{} // a resposta não é de pedido
+	return t.api.Post(path, raw, nil)
+}
+
+// rawJSON confere o DocString e troca as variáveis guardadas ({acme}) pelo
+// valor, para corpos que citam ids criados antes no cenário.
+func (t *TestData) rawJSON(body *godog.DocString) (json.RawMessage, error) {
+	raw := json.RawMessage(t.api.ResolveVars(body.Content))
+	if !json.Valid(raw) {
+		return nil, fmt.Errorf("invalid JSON for Request: %s", body.Content)
+	}
+	return raw, nil
 }
 
 func (t *TestData) stepGet(path string) error {
@@ -61,9 +93,14 @@ func (t *TestData) stepGet(path string) error {
 	}
 
 	// Demais GETs: só chama e mantém o corpo bruto em LastBody
+	t.lastOrderResp = types.OrderResponse{}
 	return t.api.Get(path, nil)
 }
 
+func (t *TestData) stepDelete(path string) error {
+	return t.api.Delete(path)
+}
+
 func (t *TestData) stepSetHeaders(table *godog.Table) error {
 	for i, row := range table.Rows {
 		// pula cabeçalho se quiser, mas aqui assumo que não tem
@@ -94,6 +131,16 @@ func (t *TestData) stepAssertStatus(code int) error {
 	return nil
 }
 
+func (t *TestData) stepAssertRespHeader(name, want string) error {
+	if t.api.LastResp == nil {
+		return fmt.Errorf("no HTTP response received")
+	}
+	if got := t.api.LastHdr.Get(name); got != want {
+		return fmt.Errorf("header %s: expected %q, got %q", name, want, got)
+	}
+	return nil
+}
+
 func (t *TestData) stepResponseBodyShouldBe(expectedDoc *godog.DocString) error {
 	if t.api.LastBody == nil {
 		return fmt.Errorf("no HTTP response body available")
@@ -131,11 +178,8 @@ func (t *TestData) stepCaptureID(field, varName string) error {
 		return nil
 	}
 
-	var body map[string]any
-	if err := json.Unmarshal(t.api.LastBody, &body); err != nil {
-		return err
-	}
-	raw, ok := body[field]
+	// caminho com pontos, como em stepResponseFieldShouldBe ("results.0.id")
+	raw, ok := lookupPath(t.api.LastBody, field)
 	if !ok {
 		return fmt.Errorf("field %q not found", field)
 	}
@@ -147,6 +191,20 @@ func (t *TestData) stepCaptureID(field, varName string) error {
 	return nil
 }
 
+// stepResponseFieldShouldBe confere um campo do corpo JSON pelo caminho
+// com pontos ("info.title"); want aceita variáveis ("{order_id}").
+func (t *TestData) stepResponseFieldShouldBe(path, want string) error {
+	want = t.api.ResolveVars(want)
+	v, ok := lookupPath(t.api.LastBody, path)
+	if !ok {
+		return fmt.Errorf("field %q not found in response: %s", path, t.api.LastBody)
+	}
+	if got := fmt.Sprint(v); got != want {
+		return fmt.Errorf("field %q: expected %q, got %q", path, want, got)
+	}
+	return nil
+}
+
 func (t *TestData) stepHaveOrderViaAPI(doc *godog.DocString) error {
 	t.api.ReqHdr.Set("Content-Type", "application/json")
 	if err := t.stepPostJSON("/orders", doc); err != nil {
@@ -157,3 +215,135 @@ func (t *TestData) stepHaveOrderViaAPI(doc *godog.DocString) error {
 	}
 	return t.stepCaptureID("id", "order_id")
 }
+
+func (t *TestData) stepEveryListedOrderHasCustomer(customer string) error {
+	var body struct {
+		Items []types.OrderResponse `json:"items"`
+	}
+	if err := json.Unmarshal(t.api.LastBody, &body); err != nil {
+		return fmt.Errorf("invalid list response: %w", err)
+	}
+	if len(body.Items) == 0 {
+		return fmt.Errorf("expected at least one order, got none")
+	}
+	for _, o := range body.Items {
+		if o.Customer != customer {
+			return fmt.Errorf("order %s belongs to %q, expected only %q", o.ID, o.Customer, customer)
+		}
+	}
+	return nil
+}
+
+func (t *TestData) stepListedOrdersExclude(varName string) error {
+	id, ok := t.api.Vars[varName]
+	if !ok {
+		return fmt.Errorf("variable %q not set", varName)
+	}
+	var body struct {
+		Items []types.OrderResponse `json:"items"`
+	}
+	if err := json.Unmarshal(t.api.LastBody, &body); err != nil {
+		return fmt.Errorf("invalid list response: %w", err)
+	}
+	for _, o := range body.Items {
+		if o.ID == id {
+			return fmt.Errorf("order %s should not be listed", id)
+		}
+	}
+	return nil
+}
+
+// stepFreshTenant isola o cenário num tenant novo, para listagens não verem
+// pedidos de outros cenários.
+func (t *TestData) stepFreshTenant() error {
+	t.api.ReqHdr.Set("X-Tenant-Id", fmt.Sprintf("bdd-%d", time.Now().UnixNano()))
+	return nil
+}
+
+// stepHaveOrders cria um pedido por linha (customer, items separados por
+// vírgula, status opcional, "as" opcional para guardar o id).
+func (t *TestData) stepHaveOrders(table *godog.Table) error {
+	if len(table.Rows) < 2 {
+		return fmt.Errorf("expected a header row and at least one order")
+	}
+	var cols []string
+	for _, c := range table.Rows[0].Cells {
+		cols = append(cols, c.Value)
+	}
+	for _, row := range table.Rows[1:] {
+		cell := map[string]string{}
+		for i, c := range row.Cells {
+			cell[cols[i]] = c.Value
+		}
+		req := types.OrderRequest{Customer: cell["customer"], Items: strings.Split(cell["items"], ",")}
+		var resp types.OrderResponse
+		if err := t.api.Post("/orders", req, &resp); err != nil {
+			return err
+		}
+		if err := t.stepAssertStatus(http.StatusCreated); err != nil {
+			return err
+		}
+		if st := cell["status"]; st != "" && st != "OPEN" {
+			if err := t.api.Put("/orders/"+resp.ID+"/status", map[string]string{"status": st}, nil); err != nil {
+				return err
+			}
+			if err := t.stepAssertStatus(http.StatusOK); err != nil {
+				return err
+			}
+		}
+		if v := cell["as"]; v != "" {
+			t.api.Vars[v] = resp.ID
+		}
+	}
+	return nil
+}
+
+// stepListedCustomers confere os clientes da listagem, na ordem.
+func (t *TestData) stepListedCustomers(want string) error {
+	var body struct {
+		Items []types.OrderResponse `json:"items"`
+	}
+	if err := json.Unmarshal(t.api.LastBody, &body); err != nil {
+		return fmt.Errorf("invalid list response: %w", err)
+	}
+	got := make([]string, len(body.Items))
+	for i, o := range body.Items {
+		got[i] = o.Customer
+	}
+	return expectCustomers("listed", got, want)
+}
+
+// stepExportedCustomers confere os clientes de GET /orders/export, na
+// ordem, seja o corpo NDJSON ou CSV, comprimido ou não.
+func (t *TestData) stepExportedCustomers(want string) error {
+	rows, err := helpers.ExportedRows(t.api.LastHdr, t.api.LastBody)
+	if err != nil {
+		return err
+	}
+	got := make([]string, len(rows))
+	for i, r := range rows {
+		got[i] = r["customer"]
+	}
+	return expectCustomers("exported", got, want)
+}
+
+func (t *TestData) stepHeaderNotEmpty(name string) error {
+	if t.api.LastHdr.Get(name) == "" {
+		return fmt.Errorf("response header %q is missing or empty", name)
+	}
+	return nil
+}
+
+// expectCustomers compara com a lista "A, B" do step (vazia: nenhum).
+func expectCustomers(what string, got []string, want string) error {
+	var expected []string
+	for _, c := range strings.Split(want, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			expected = append(expected, c)
+		}
+	}
+	if strings.Join(got, ", ") != strings.Join(expected, ", ") {
+		return fmt.Errorf("%s customers: expected [%s], got [%s]", what, strings.Join(expected, ", "), strings.Join(got, ", "))
+	}
+	return nil
+}
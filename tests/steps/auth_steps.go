@@ -0,0 +1,58 @@
+package steps
+
+import (
+	"time"
+
+	"orders-tests/domain"
+)
+
+func (t *TestData) stepNotAuthenticated() error {
+	t.api.Anonymous = true
+	t.api.ReqHdr.Del("Authorization")
+	t.api.ReqHdr.Del("X-Api-Key")
+	return nil
+}
+
+// setBearer assina o JWT dos cenários. Sem claim de tenant, o token pode
+// usar qualquer tenant ("tenants": "*"), como a chave dos testes.
+func (t *TestData) setBearer(subject string, ttl time.Duration, extra map[string]any) error {
+	_, bound := extra["tenant"]
+	if _, ok := extra["tenants"]; !ok && !bound {
+		extra["tenants"] = "*"
+	}
+	tok, err := domain.SignJWT(subject, ttl, extra)
+	if err != nil {
+		return err
+	}
+	t.api.ReqHdr.Set("Authorization", "Bearer "+tok)
+	return nil
+}
+
+// defaultScopes é o que um JWT "comum" dos cenários carrega.
+const defaultScopes = "orders:read orders:write"
+
+func (t *TestData) stepAuthJWT(subject string) error {
+	return t.setBearer(subject, 5*time.Minute, map[string]any{"scope": defaultScopes})
+}
+
+func (t *TestData) stepAuthExpiredJWT(subject string) error {
+	return t.setBearer(subject, -5*time.Minute, map[string]any{"scope": defaultScopes})
+}
+
+func (t *TestData) stepAuthJWTScopes(subject, scopes string) error {
+	return t.setBearer(subject, 5*time.Minute, map[string]any{"scope": scopes})
+}
+
+// stepAuthJWTTenants emite um JWT liberado só para os tenants listados
+// (separados por espaço) além do padrão.
+func (t *TestData) stepAuthJWTTenants(subject, tenants string) error {
+	return t.setBearer(subject, 5*time.Minute, map[string]any{"scope": defaultScopes, "tenants": tenants})
+}
+
+// stepAuthJWTBound emite um JWT preso a um cliente ou tenant (claim de
+// mesmo nome).
+func (t *TestData) stepAuthJWTBound(subject, claim, value string) error {
+	// customer_id costuma vir de uma variável ({acme})
+	value = t.resolveVars(value)
+	return t.setBearer(subject, 5*time.Minute, map[string]any{"scope": defaultScopes, claim: value})
+}
@@ -0,0 +1,185 @@
+package steps
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"orders-tests/domain"
+	"orders-tests/helpers"
+
+	"github.com/cucumber/godog"
+)
+
+// lookupPath segue um caminho com pontos (ex.: "data.order.id",
+// "series.-1.count") num JSON.
+func lookupPath(raw []byte, path string) (any, bool) {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, false
+	}
+	for _, part := range strings.Split(path, ".") {
+		switch cur := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = cur[part]; !ok {
+				return nil, false
+			}
+		case []any: // índice numérico; -1 é o último
+			i, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, false
+			}
+			if i < 0 {
+				i += len(cur)
+			}
+			if i < 0 || i >= len(cur) {
+				return nil, false
+			}
+			v = cur[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func (t *TestData) stepGraphQLQuery(doc *godog.DocString) error {
+	return t.api.GraphQL(t.resolveVars(doc.Content), nil)
+}
+
+// stepGraphQLResponseShouldBe compara o corpo (data/errors) com placeholders,
+// depois de trocar {var} pelos valores capturados.
+func (t *TestData) stepGraphQLResponseShouldBe(doc *godog.DocString) error {
+	var expected, actual any
+	if err := json.Unmarshal([]byte(t.resolveVars(doc.Content)), &expected); err != nil {
+		return fmt.Errorf("invalid expected JSON: %w", err)
+	}
+	if err := json.Unmarshal(t.api.LastBody, &actual); err != nil {
+		return fmt.Errorf("invalid GraphQL response JSON: %w", err)
+	}
+	if err := helpers.MatchWithPlaceholders(expected, actual, ""); err != nil {
+		return fmt.Errorf("%w\nactual: %s", err, helpers.PrettyJSON(t.api.LastBody))
+	}
+	return nil
+}
+
+func (t *TestData) stepGraphQLNoErrors() error {
+	if err := t.stepAssertStatus(200); err != nil {
+		return err
+	}
+	if errs, ok := lookupPath(t.api.LastBody, "errors"); ok && errs != nil {
+		return fmt.Errorf("expected no GraphQL errors, got: %s", helpers.PrettyJSON(t.api.LastBody))
+	}
+	return nil
+}
+
+func (t *TestData) stepGraphQLErrorCode(code string) error {
+	if err := t.stepAssertStatus(200); err != nil {
+		return err
+	}
+	return expectErrorCode(t.api.LastBody, "errors", code)
+}
+
+// expectErrorCode confere se algum erro da lista em path traz extensions.code.
+func expectErrorCode(raw []byte, path, code string) error {
+	v, _ := lookupPath(raw, path)
+	errs, _ := v.([]any)
+	for _, e := range errs {
+		if m, ok := e.(map[string]any); ok {
+			if ext, ok := m["extensions"].(map[string]any); ok && ext["code"] == code {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("expected a GraphQL error with code %s, got: %s", code, helpers.PrettyJSON(raw))
+}
+
+func (t *TestData) stepGraphQLCapture(path, varName string) error {
+	v, _ := lookupPath(t.api.LastBody, path)
+	id, ok := helpers.AnyToStringID(v)
+	if !ok || id == "" {
+		return fmt.Errorf("field %q not found in GraphQL response: %s", path, helpers.PrettyJSON(t.api.LastBody))
+	}
+	t.api.Vars[varName] = id
+	return nil
+}
+
+func (t *TestData) stepGraphQLSubscribe(doc *godog.DocString) error {
+	t.gqlSub.Close()
+	sub, err := t.api.SubscribeGraphQL(t.resolveVars(doc.Content), nil)
+	if err != nil {
+		return err
+	}
+	t.gqlSub = sub
+	return nil
+}
+
+// nextGqlMessage espera a próxima mensagem da subscription.
+func (t *TestData) nextGqlMessage(deadline <-chan time.Time) (domain.GqlMessage, error) {
+	if t.gqlSub == nil {
+		return domain.GqlMessage{}, fmt.Errorf("no GraphQL subscription open")
+	}
+	select {
+	case m, ok := <-t.gqlSub.C:
+		if !ok {
+			return domain.GqlMessage{}, fmt.Errorf("GraphQL subscription connection closed")
+		}
+		return m, nil
+	case <-deadline:
+		return domain.GqlMessage{}, fmt.Errorf("timeout")
+	}
+}
+
+func (t *TestData) stepExpectGraphQLEvent(evType, varName string, secs int) error {
+	wantID, ok := t.api.Vars[varName]
+	if !ok {
+		return fmt.Errorf("variable %q not set", varName)
+	}
+	deadline := time.After(time.Duration(secs) * time.Second)
+	for {
+		m, err := t.nextGqlMessage(deadline)
+		if err != nil {
+			return fmt.Errorf("GraphQL subscription: %q for %s not received: %w", evType, wantID, err)
+		}
+		if m.Type != "next" {
+			return fmt.Errorf("GraphQL subscription: unexpected %s %s", m.Type, m.Payload)
+		}
+		var msg struct {
+			Data struct {
+				OrderEvents struct {
+					Type    string `json:"type"`
+					OrderID string `json:"orderId"`
+					Payload string `json:"payload"`
+				} `json:"orderEvents"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(m.Payload, &msg); err != nil {
+			return fmt.Errorf("GraphQL subscription: invalid payload %s: %w", m.Payload, err)
+		}
+		e := msg.Data.OrderEvents
+		if e.Type != evType || e.OrderID != wantID {
+			continue
+		}
+		// payload é o mesmo JSON publicado no Kafka
+		var evt map[string]any
+		if err := json.Unmarshal([]byte(e.Payload), &evt); err != nil || evt["type"] != e.Type || !helpers.MatchID(evt["id"], wantID) {
+			return fmt.Errorf("GraphQL event payload does not match %s/%s: %s", e.Type, wantID, e.Payload)
+		}
+		return nil
+	}
+}
+
+func (t *TestData) stepGraphQLSubscriptionFails(code string) error {
+	m, err := t.nextGqlMessage(time.After(5 * time.Second))
+	if err != nil {
+		return fmt.Errorf("GraphQL subscription: expected an error: %w", err)
+	}
+	if m.Type != "error" {
+		return fmt.Errorf("GraphQL subscription: expected error, got %s %s", m.Type, m.Payload)
+	}
+	wrapped, _ := json.Marshal(map[string]json.RawMessage{"errors": m.Payload})
+	return expectErrorCode(wrapped, "errors", code)
+}
@@ -0,0 +1,141 @@
+package steps
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	ordersv1 "orders-tests/gen/orders/v1"
+	"orders-tests/helpers"
+
+	"github.com/cucumber/godog"
+)
+
+// resolveVars troca {var} pelos valores capturados (ids etc.) no JSON do step.
+func (t *TestData) resolveVars(s string) string {
+	for k, v := range t.api.Vars {
+		s = strings.ReplaceAll(s, "{"+k+"}", v)
+	}
+	return s
+}
+
+func (t *TestData) stepGrpcCall(method string, body *godog.DocString) error {
+	return t.grpc.Call(method, []byte(t.resolveVars(body.Content)))
+}
+
+func (t *TestData) stepGrpcCallEmpty(method string) error {
+	return t.grpc.Call(method, []byte("{}"))
+}
+
+func (t *TestData) stepGrpcStatus(want string) error {
+	if t.grpc.LastStatus == nil {
+		return fmt.Errorf("no gRPC call made yet")
+	}
+	if got := t.grpc.LastStatus.Code().String(); got != want {
+		return fmt.Errorf("gRPC status: expected %s, got %s (%s)", want, got, t.grpc.LastStatus.Message())
+	}
+	return nil
+}
+
+// stepGrpcResponseShouldBe compara a resposta (JSON do proto, camelCase, sem
+// campos vazios) com placeholders como $ANY_ULID.
+func (t *TestData) stepGrpcResponseShouldBe(body *godog.DocString) error {
+	var expected, actual any
+	if err := json.Unmarshal([]byte(t.resolveVars(body.Content)), &expected); err != nil {
+		return fmt.Errorf("invalid expected JSON: %w", err)
+	}
+	raw := t.grpc.LastJSON()
+	if err := json.Unmarshal(raw, &actual); err != nil {
+		return fmt.Errorf("invalid gRPC response JSON: %w", err)
+	}
+	if err := helpers.MatchWithPlaceholders(expected, actual, ""); err != nil {
+		return fmt.Errorf("%w\nactual: %s", err, helpers.PrettyJSON(raw))
+	}
+	return nil
+}
+
+// stepGrpcResponseFieldShouldBe confere um campo da resposta (caminho com
+// pontos, ex.: "order.customerId"); {var} no valor esperado é resolvido.
+func (t *TestData) stepGrpcResponseFieldShouldBe(path, want string) error {
+	want = t.resolveVars(want)
+	raw := t.grpc.LastJSON()
+	v, ok := lookupPath(raw, path)
+	if !ok {
+		return fmt.Errorf("field %q not found in gRPC response: %s", path, raw)
+	}
+	if got := fmt.Sprint(v); got != want {
+		return fmt.Errorf("field %q: expected %q, got %q", path, want, got)
+	}
+	return nil
+}
+
+// stepGrpcCapture guarda um campo da resposta (caminho com pontos, ex.:
+// "order.id") numa variável.
+func (t *TestData) stepGrpcCapture(path, varName string) error {
+	v, _ := lookupPath(t.grpc.LastJSON(), path)
+	id, ok := helpers.AnyToStringID(v)
+	if !ok || id == "" {
+		return fmt.Errorf("field %q not found in gRPC response", path)
+	}
+	t.api.Vars[varName] = id
+	return nil
+}
+
+func (t *TestData) stepGrpcWatchAll() error {
+	return t.openWatch("")
+}
+
+func (t *TestData) stepGrpcWatchOrder(varName string) error {
+	id, ok := t.api.Vars[varName]
+	if !ok {
+		return fmt.Errorf("variable %q not set", varName)
+	}
+	return t.openWatch(id)
+}
+
+func (t *TestData) openWatch(orderID string) error {
+	t.stopWatch()
+	ch, cancel, err := t.grpc.Watch(orderID, 0)
+	if err != nil {
+		return fmt.Errorf("WatchOrders: %w", err)
+	}
+	t.watch, t.stopWatch = ch, cancel
+	return nil
+}
+
+func (t *TestData) stepExpectWatchEvent(evType, varName string, secs int) error {
+	if t.watch == nil {
+		return fmt.Errorf("no gRPC watch open")
+	}
+	wantID, ok := t.api.Vars[varName]
+	if !ok {
+		return fmt.Errorf("variable %q not set", varName)
+	}
+	deadline := time.After(time.Duration(secs) * time.Second)
+	for {
+		select {
+		case e, ok := <-t.watch:
+			if !ok {
+				return fmt.Errorf("gRPC watch ended before %q for %s", evType, wantID)
+			}
+			if e.GetType() == evType && e.GetOrderId() == wantID {
+				return checkWatchPayload(e)
+			}
+		case <-deadline:
+			return fmt.Errorf("gRPC watch: %q for %s not received in %ds", evType, wantID, secs)
+		}
+	}
+}
+
+// checkWatchPayload confere que o payload é o mesmo JSON publicado no Kafka.
+func checkWatchPayload(e *ordersv1.OrderEvent) error {
+	var evt map[string]any
+	if err := json.Unmarshal(e.GetPayload(), &evt); err != nil {
+		return fmt.Errorf("watch payload is not JSON: %w", err)
+	}
+	if evt["type"] != e.GetType() || !helpers.MatchID(evt["id"], e.GetOrderId()) {
+		return fmt.Errorf("watch payload does not match event %s/%s: %s", e.GetType(), e.GetOrderId(), e.GetPayload())
+	}
+	return nil
+}
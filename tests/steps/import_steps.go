@@ -0,0 +1,110 @@
+package steps
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"orders-tests/helpers"
+
+	"github.com/cucumber/godog"
+)
+
+// stepUploadFile envia o DocString como o campo "file" de um
+// multipart/form-data; a API tira o formato da extensão do nome.
+func (t *TestData) stepUploadFile(name, path string, body *godog.DocString) error {
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	fw, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write([]byte(t.api.ResolveVars(body.Content) + "\n")); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	return t.postImport(path, mw.FormDataContentType(), b.Bytes())
+}
+
+// stepPostFile envia o DocString como corpo text/csv ou application/x-ndjson.
+func (t *TestData) stepPostFile(path, format string, body *godog.DocString) error {
+	media := "text/csv"
+	if format == "NDJSON" {
+		media = "application/x-ndjson"
+	}
+	return t.postImport(path, media, []byte(t.api.ResolveVars(body.Content)+"\n"))
+}
+
+// postImport guarda o id do import aceito em {import_id}.
+func (t *TestData) postImport(path, contentType string, body []byte) error {
+	var resp struct {
+		ID string `json:"id"`
+	}
+	if err := t.api.PostAs(path, contentType, body, &resp); err != nil {
+		return err
+	}
+	if t.api.LastResp.StatusCode == http.StatusAccepted {
+		t.api.Vars["import_id"] = resp.ID
+	}
+	return nil
+}
+
+// stepImportFinishes consulta GET /imports/{import_id} até o worker
+// terminar; a última resposta fica para os steps de campo.
+func (t *TestData) stepImportFinishes(want string, secs int) error {
+	id := t.api.Vars["import_id"]
+	if id == "" {
+		return fmt.Errorf("no import was accepted in this scenario")
+	}
+	deadline := time.Now().Add(time.Duration(secs) * time.Second)
+	for {
+		var im struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		}
+		if err := t.api.Get("/imports/"+id, &im); err != nil {
+			return err
+		}
+		if code := t.api.LastResp.StatusCode; code != http.StatusOK {
+			return fmt.Errorf("import %s: expected 200, got %d: %s", id, code, t.api.LastBody)
+		}
+		if im.Status != "queued" && im.Status != "running" {
+			if im.Status != want {
+				return fmt.Errorf("import %s: expected %s, got %s (%s)", id, want, im.Status, im.Error)
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("import %s still %s after %ds: %s", id, im.Status, secs, helpers.PrettyJSON(t.api.LastBody))
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// stepRejectedLines confere as linhas do arquivo de rejeitos (CSV ou
+// NDJSON, coluna/campo "line").
+func (t *TestData) stepRejectedLines(want string) error {
+	rows, err := helpers.ExportedRows(t.api.LastHdr, t.api.LastBody)
+	if err != nil {
+		return err
+	}
+	got := make([]string, len(rows))
+	for i, r := range rows {
+		got[i] = r["line"]
+	}
+	var expected []string
+	for _, l := range strings.Split(want, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			expected = append(expected, l)
+		}
+	}
+	if strings.Join(got, ", ") != strings.Join(expected, ", ") {
+		return fmt.Errorf("rejected lines: expected [%s], got [%s]", strings.Join(expected, ", "), strings.Join(got, ", "))
+	}
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"orders-tests/domain"
 	"orders-tests/helpers"
+	"strings"
 	"time"
 )
 
@@ -81,6 +83,7 @@ func (t *TestData) stepExpectEvent(topic, eventType, varName string, seconds int
 					return fmt.Errorf("payload digest mismatch: got=%s want=%s", got, hdr)
 				}
 			}
+			t.lastEvent = &c
 			return nil
 
 		case <-timeout:
@@ -89,3 +92,53 @@ func (t *TestData) stepExpectEvent(topic, eventType, varName string, seconds int
 		}
 	}
 }
+
+// Header assertions on the event matched by the last expectation
+func (t *TestData) stepEventHeaderEquals(key, want string) error {
+	if t.lastEvent == nil {
+		return fmt.Errorf("no event matched yet; use \"there must be an event ...\" first")
+	}
+	for _, h := range t.lastEvent.Msg.Headers {
+		if h.Key == key {
+			if string(h.Value) != want {
+				return fmt.Errorf("event header %s: expected %q, got %q", key, want, string(h.Value))
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("event header %s not found (headers: %s)", key, domain.FormatHeaders(t.lastEvent.Msg.Headers))
+}
+
+// Trace context propagated by the API into the event headers
+func (t *TestData) stepEventHasTraceparent() error {
+	if t.lastEvent == nil {
+		return fmt.Errorf("no event matched yet; use \"there must be an event ...\" first")
+	}
+	if t.lastEvent.TraceID == "" {
+		return fmt.Errorf("event has no valid traceparent header (headers: %s)", domain.FormatHeaders(t.lastEvent.Msg.Headers))
+	}
+	return nil
+}
+
+func (t *TestData) stepEventBelongsToTrace(traceID string) error {
+	if err := t.stepEventHasTraceparent(); err != nil {
+		return err
+	}
+	if t.lastEvent.TraceID != traceID {
+		return fmt.Errorf("event trace id: expected %s, got %s", traceID, t.lastEvent.TraceID)
+	}
+	return nil
+}
+
+func (t *TestData) stepEventKeyEquals(want string) error {
+	if t.lastEvent == nil {
+		return fmt.Errorf("no event matched yet; use \"there must be an event ...\" first")
+	}
+	for k, v := range t.api.Vars {
+		want = strings.ReplaceAll(want, "{"+k+"}", v)
+	}
+	if got := string(t.lastEvent.Msg.Key); got != want {
+		return fmt.Errorf("event key: expected %q, got %q", want, got)
+	}
+	return nil
+}
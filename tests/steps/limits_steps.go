@@ -0,0 +1,66 @@
+package steps
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cucumber/godog"
+)
+
+func (t *TestData) stepBurstPost(n int, path string, body *godog.DocString) error {
+	res, err := t.api.Burst(http.MethodPost, path, []byte(body.Content), n)
+	if err != nil {
+		return err
+	}
+	t.burst = res
+	return nil
+}
+
+func (t *TestData) stepBurstRateLimited() error {
+	if len(t.burst) == 0 {
+		return fmt.Errorf("no burst sent yet")
+	}
+	statuses := map[int]int{}
+	for _, r := range t.burst {
+		statuses[r.Status]++
+		if r.Status != http.StatusTooManyRequests {
+			continue
+		}
+		if r.Header.Get("Retry-After") == "" {
+			return fmt.Errorf("429 response without Retry-After header")
+		}
+		return nil
+	}
+	return fmt.Errorf("expected at least one 429, got statuses %v", statuses)
+}
+
+// stepPostPadded manda um pedido válido com um campo extra de enchimento até
+// o corpo ter `size` bytes.
+func (t *TestData) stepPostPadded(method, path string, size int) error {
+	base := `{"customer":"Acme","items":["x"],"status":"DONE","padding":""}`
+	pad := size - len(base)
+	if pad < 0 {
+		pad = 0
+	}
+	body := strings.Replace(base, `"padding":""`, `"padding":"`+strings.Repeat("p", pad)+`"`, 1)
+	t.api.ReqHdr.Set("Content-Type", "application/json")
+	if method == http.MethodPut {
+		return t.api.Put(path, body, nil)
+	}
+	return t.api.Post(path, body, nil)
+}
+
+func (t *TestData) stepPostItems(path string, count, length int) error {
+	items := make([]string, count)
+	for i := range items {
+		items[i] = strings.Repeat("i", length)
+	}
+	body, err := json.Marshal(map[string]any{"customer": "Acme", "items": items})
+	if err != nil {
+		return err
+	}
+	t.api.ReqHdr.Set("Content-Type", "application/json")
+	return t.api.Post(path, body, nil)
+}
@@ -0,0 +1,73 @@
+package steps
+
+import (
+	"fmt"
+	"orders-tests/helpers"
+)
+
+func (t *TestData) metricValue(selector string) (float64, error) {
+	text, err := t.api.ScrapeMetrics()
+	if err != nil {
+		return 0, err
+	}
+	v, _, err := helpers.SumMetric(text, selector)
+	// série ainda não criada (nenhum evento) conta como zero
+	return v, err
+}
+
+func (t *TestData) stepRememberMetric(selector string) error {
+	v, err := t.metricValue(selector)
+	if err != nil {
+		return err
+	}
+	if t.metrics == nil {
+		t.metrics = map[string]float64{}
+	}
+	t.metrics[selector] = v
+	return nil
+}
+
+func (t *TestData) stepMetricIncreasedBy(selector string, delta int) error {
+	before, ok := t.metrics[selector]
+	if !ok {
+		return fmt.Errorf("metric %s was not remembered before", selector)
+	}
+	now, err := t.metricValue(selector)
+	if err != nil {
+		return err
+	}
+	if got := now - before; got != float64(delta) {
+		return fmt.Errorf("metric %s: expected increase of %d, got %g (%g → %g)", selector, delta, got, before, now)
+	}
+	return nil
+}
+
+func (t *TestData) stepMetricIncreasedAtLeast(selector string, delta int) error {
+	before, ok := t.metrics[selector]
+	if !ok {
+		return fmt.Errorf("metric %s was not remembered before", selector)
+	}
+	now, err := t.metricValue(selector)
+	if err != nil {
+		return err
+	}
+	if got := now - before; got < float64(delta) {
+		return fmt.Errorf("metric %s: expected increase of at least %d, got %g (%g → %g)", selector, delta, got, before, now)
+	}
+	return nil
+}
+
+func (t *TestData) stepMetricExists(selector string) error {
+	text, err := t.api.ScrapeMetrics()
+	if err != nil {
+		return err
+	}
+	_, found, err := helpers.SumMetric(text, selector)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("metric %s not exposed", selector)
+	}
+	return nil
+}
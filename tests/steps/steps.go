@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"orders-tests/domain"
+	ordersv1 "orders-tests/gen/orders/v1"
 	"orders-tests/types"
 	"os"
 	"strings"
@@ -18,6 +19,35 @@ type TestData struct {
 	kafka         *domain.KafkaCtx
 	lastOrderReq  types.OrderRequest
 	lastOrderResp types.OrderResponse
+	lastEvent     *domain.Consumed
+	metrics       map[string]float64
+	burst         []domain.BurstResult
+	stream        *domain.Stream
+	lastSSE       domain.SSEEvent
+	sink          *domain.SinkCtx
+	hooks         map[string]registeredHook
+	grpc          *domain.GrpcCtx
+	watch         <-chan *ordersv1.OrderEvent
+	stopWatch     func()
+	gqlSub        *domain.GqlSubscription
+}
+
+// contract é o OpenAPI da API, carregado uma vez por suíte quando
+// OPENAPI_VALIDATE=true; o relatório de cobertura sai no fim da suíte.
+var (
+	contract    *domain.Contract
+	contractErr error
+)
+
+func loadContract() {
+	if !strings.EqualFold(os.Getenv("OPENAPI_VALIDATE"), "true") {
+		return
+	}
+	source := os.Getenv("OPENAPI_SPEC") // arquivo ou URL
+	if source == "" {
+		source = newAPI().BaseURL + "/openapi.json"
+	}
+	contract, contractErr = domain.LoadContract(source)
 }
 
 func newAPI() *domain.ApiCtx {
@@ -27,11 +57,17 @@ func newAPI() *domain.ApiCtx {
 	}
 	base = strings.TrimRight(base, "/")
 	debug := strings.EqualFold(os.Getenv("HTTP_DEBUG"), "true")
+	apiKey, ok := os.LookupEnv("API_KEY")
+	if !ok {
+		apiKey = "bdd-secret-key" // mesma chave do docker-compose
+	}
 	return &domain.ApiCtx{
-		BaseURL: base,
-		Vars:    map[string]string{},
-		Debug:   debug,
-		ReqHdr:  http.Header{},
+		BaseURL:  base,
+		Vars:     map[string]string{},
+		Debug:    debug,
+		ReqHdr:   http.Header{},
+		APIKey:   apiKey,
+		Contract: contract,
 	}
 }
 
@@ -52,33 +88,151 @@ func newKafkaCtx() *domain.KafkaCtx {
 	}
 }
 
-func InitializeTestSuite(sc *godog.TestSuiteContext) {}
+func newSinkCtx() *domain.SinkCtx {
+	base := os.Getenv("WEBHOOK_SINK_URL")
+	if base == "" {
+		base = "http://localhost:8099"
+	}
+	internal := os.Getenv("WEBHOOK_SINK_INTERNAL_URL")
+	if internal == "" {
+		internal = "http://webhook-sink:8099" // como a API (no compose) chega no sink
+	}
+	return &domain.SinkCtx{
+		BaseURL:     strings.TrimRight(base, "/"),
+		InternalURL: strings.TrimRight(internal, "/"),
+	}
+}
+
+func newGrpcCtx(api *domain.ApiCtx) *domain.GrpcCtx {
+	addr := os.Getenv("GRPC_ADDR")
+	if addr == "" {
+		addr = "localhost:9090"
+	}
+	return &domain.GrpcCtx{Addr: addr, API: api}
+}
+
+func InitializeTestSuite(sc *godog.TestSuiteContext) {
+	sc.BeforeSuite(loadContract)
+	sc.AfterSuite(func() {
+		if contract != nil {
+			fmt.Print("\n" + contract.Report())
+		}
+	})
+}
 
 func InitializeScenario(s *godog.ScenarioContext) {
+	api := newAPI()
 	t := &TestData{
-		api:   newAPI(),
-		kafka: newKafkaCtx(),
+		api:       api,
+		kafka:     newKafkaCtx(),
+		sink:      newSinkCtx(),
+		hooks:     map[string]registeredHook{},
+		grpc:      newGrpcCtx(api),
+		stopWatch: func() {},
 	}
 
 	s.Step(`^I send POST ([^ ]+) with JSON:$`, t.stepPostJSON)
 	s.Step(`^I send PUT ([^ ]+) with JSON:$`, t.stepPutJSON)
 	s.Step(`^I send GET ([^ ]+)$`, t.stepGet)
+	s.Step(`^I send DELETE ([^ ]+)$`, t.stepDelete)
 	s.Step(`^I set headers:$`, t.stepSetHeaders)
 	s.Step(`^the HTTP status should be (\d+)$`, t.stepAssertStatus)
 	s.Step(`^the response body should be:$`, t.stepResponseBodyShouldBe)
+	s.Step(`^the response header "([^"]+)" should be "([^"]*)"$`, t.stepAssertRespHeader)
 	s.Step(`^I store the "([^"]+)" from the response body into "([^"]+)"$`, t.stepCaptureID)
+	s.Step(`^the response field "([^"]+)" should be "([^"]*)"$`, t.stepResponseFieldShouldBe)
 	s.Step(`^I have an order created via API:$`, t.stepHaveOrderViaAPI)
+	s.Step(`^every listed order should belong to customer "([^"]+)"$`, t.stepEveryListedOrderHasCustomer)
+	s.Step(`^the listed orders should not include "([^"]+)"$`, t.stepListedOrdersExclude)
+	s.Step(`^I use a fresh tenant$`, t.stepFreshTenant)
+	s.Step(`^I have the following orders:$`, t.stepHaveOrders)
+	s.Step(`^the listed customers should be "([^"]*)"$`, t.stepListedCustomers)
+	s.Step(`^the exported customers should be "([^"]*)"$`, t.stepExportedCustomers)
+	s.Step(`^the response header "([^"]+)" should not be empty$`, t.stepHeaderNotEmpty)
+
+	s.Step(`^I upload the file "([^"]+)" to ([^ ]+):$`, t.stepUploadFile)
+	s.Step(`^I send POST ([^ ]+) with (CSV|NDJSON):$`, t.stepPostFile)
+	s.Step(`^the import should finish as "([^"]+)" within (\d+)s$`, t.stepImportFinishes)
+	s.Step(`^the rejected lines should be "([^"]*)"$`, t.stepRejectedLines)
+
+	s.Step(`^I send (\d+) concurrent POST ([^ ]+) with JSON:$`, t.stepBurstPost)
+	s.Step(`^at least one of them should be rejected with 429 and a Retry-After header$`, t.stepBurstRateLimited)
+	s.Step(`^I send (POST|PUT) ([^ ]+) with a JSON body of (\d+) bytes$`, t.stepPostPadded)
+	s.Step(`^I send POST ([^ ]+) with (\d+) items of (\d+) characters$`, t.stepPostItems)
+
+	s.Step(`^I open the event stream at ([^ ]+)$`, t.stepOpenStream)
+	s.Step(`^I reopen the event stream at ([^ ]+) from the last received event$`, t.stepResumeStream)
+	s.Step(`^I close the event stream$`, t.stepCloseStream)
+	s.Step(`^the stream should deliver an? "([^"]+)" event for "([^"]+)" within (\d+)s$`, t.stepExpectStreamEvent)
+
+	s.Step(`^I call gRPC (\w+) with:$`, t.stepGrpcCall)
+	s.Step(`^I call gRPC (\w+)$`, t.stepGrpcCallEmpty)
+	s.Step(`^the gRPC status should be (\w+)$`, t.stepGrpcStatus)
+	s.Step(`^the gRPC response should be:$`, t.stepGrpcResponseShouldBe)
+	s.Step(`^the gRPC response field "([^"]+)" should be "([^"]*)"$`, t.stepGrpcResponseFieldShouldBe)
+	s.Step(`^I store the "([^"]+)" from the gRPC response into "([^"]+)"$`, t.stepGrpcCapture)
+	s.Step(`^I watch all orders over gRPC$`, t.stepGrpcWatchAll)
+	s.Step(`^I watch the order "([^"]+)" over gRPC$`, t.stepGrpcWatchOrder)
+	s.Step(`^the gRPC watch should deliver an? "([^"]+)" event for "([^"]+)" within (\d+)s$`, t.stepExpectWatchEvent)
+
+	s.Step(`^I send the GraphQL query:$`, t.stepGraphQLQuery)
+	s.Step(`^the GraphQL response should be:$`, t.stepGraphQLResponseShouldBe)
+	s.Step(`^the GraphQL response should have no errors$`, t.stepGraphQLNoErrors)
+	s.Step(`^the GraphQL error code should be ([A-Z_]+)$`, t.stepGraphQLErrorCode)
+	s.Step(`^I store the "([^"]+)" from the GraphQL response into "([^"]+)"$`, t.stepGraphQLCapture)
+	s.Step(`^I subscribe over GraphQL to:$`, t.stepGraphQLSubscribe)
+	s.Step(`^the GraphQL subscription should deliver an? "([^"]+)" event for "([^"]+)" within (\d+)s$`, t.stepExpectGraphQLEvent)
+	s.Step(`^the GraphQL subscription should fail with code ([A-Z_]+)$`, t.stepGraphQLSubscriptionFails)
+
+	s.Step(`^I register a webhook "([^"]+)" for events "([^"]+)"$`, t.stepRegisterWebhook)
+	s.Step(`^I register a webhook "([^"]+)" for events "([^"]+)" whose endpoint fails (\d+) times?$`, t.stepRegisterFailingWebhook)
+	s.Step(`^the webhook "([^"]+)" should receive an? "([^"]+)" event for "([^"]+)" within (\d+)s$`, t.stepExpectWebhook)
+	s.Step(`^the webhook "([^"]+)" should not receive an? "([^"]+)" event within (\d+)s$`, t.stepWebhookNotReceived)
+	s.Step(`^the delivery log of webhook "([^"]+)" should show the "([^"]+)" delivery (pending|succeeded|failed) after (\d+) attempts?$`, t.stepDeliveryLog)
+
+	s.Step(`^I am not authenticated$`, t.stepNotAuthenticated)
+	s.Step(`^I am authenticated with a JWT for subject "([^"]+)"$`, t.stepAuthJWT)
+	s.Step(`^I am authenticated with an expired JWT for subject "([^"]+)"$`, t.stepAuthExpiredJWT)
+	s.Step(`^I am authenticated with a JWT for subject "([^"]+)" with scopes "([^"]*)"$`, t.stepAuthJWTScopes)
+	s.Step(`^I am authenticated with a JWT for subject "([^"]+)" allowed in tenants "([^"]*)"$`, t.stepAuthJWTTenants)
+	s.Step(`^I am authenticated with a JWT for subject "([^"]+)" bound to (customer|customer_id|tenant) "([^"]+)"$`, t.stepAuthJWTBound)
+
+	s.Step(`^I remember the metric (\S+)$`, t.stepRememberMetric)
+	s.Step(`^the metric (\S+) should have increased by (\d+)$`, t.stepMetricIncreasedBy)
+	s.Step(`^the metric (\S+) should have increased by at least (\d+)$`, t.stepMetricIncreasedAtLeast)
+	s.Step(`^the metric (\S+) should be exposed$`, t.stepMetricExists)
 
 	s.Step(`^the topic "([^"]+)" is accessible$`, t.stepStartTopic)
 	s.Step(`^the topic "([^"]+)" is accessible from the (beginning|end)$`, t.stepStartTopicFrom)
 	s.Step(`^there must be an event on topic "([^"]+)" of type "([^"]+)" for "([^"]+)" within (\d+)s$`, t.stepExpectEvent)
+	s.Step(`^the event should have header "([^"]+)" equal to "([^"]*)"$`, t.stepEventHeaderEquals)
+	s.Step(`^the event key should be "([^"]+)"$`, t.stepEventKeyEquals)
+	s.Step(`^the event should carry a valid traceparent$`, t.stepEventHasTraceparent)
+	s.Step(`^the event should belong to trace "([0-9a-f]{32})"$`, t.stepEventBelongsToTrace)
 	s.Step(`^I start printing Kafka events$`, t.stepKafkaPrintOn)
 	s.Step(`^I start printing Kafka events matching "([^"]+)"$`, t.stepKafkaPrintOnFilter)
 	s.Step(`^I stop printing Kafka events$`, t.stepKafkaPrintOff)
 	s.Step(`^I clear any pending Kafka events$`, t.stepKafkaDrain)
 
+	s.Before(func(ctx context.Context, sc *godog.Scenario) (context.Context, error) {
+		if contractErr != nil {
+			return ctx, contractErr
+		}
+		for _, tag := range sc.Tags {
+			// cenários que mandam requests fora do contrato de propósito
+			if tag.Name == "@off-contract" {
+				t.api.OffContract = true
+			}
+		}
+		return ctx, nil
+	})
+
 	s.After(func(ctx context.Context, sc *godog.Scenario, err error) (context.Context, error) {
 		t.kafka.Stop()
+		t.stream.Close()
+		t.stopWatch()
+		t.grpc.Close()
+		t.gqlSub.Close()
 		return ctx, nil
 	})
 }
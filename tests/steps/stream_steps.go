@@ -0,0 +1,72 @@
+package steps
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"orders-tests/helpers"
+)
+
+func (t *TestData) stepOpenStream(path string) error {
+	t.stream.Close()
+	s, err := t.api.OpenStream(path, "")
+	if err != nil {
+		return err
+	}
+	t.stream = s
+	return nil
+}
+
+// stepResumeStream reconecta informando o id do último evento recebido.
+func (t *TestData) stepResumeStream(path string) error {
+	if t.lastSSE.ID == "" {
+		return fmt.Errorf("no stream event received yet")
+	}
+	t.stream.Close()
+	s, err := t.api.OpenStream(path, t.lastSSE.ID)
+	if err != nil {
+		return err
+	}
+	t.stream = s
+	return nil
+}
+
+func (t *TestData) stepCloseStream() error {
+	t.stream.Close()
+	t.stream = nil
+	return nil
+}
+
+func (t *TestData) stepExpectStreamEvent(evType, varName string, secs int) error {
+	if t.stream == nil {
+		return fmt.Errorf("no stream open")
+	}
+	wantID, ok := t.api.Vars[varName]
+	if !ok {
+		return fmt.Errorf("variable %q not set", varName)
+	}
+	deadline := time.After(time.Duration(secs) * time.Second)
+	for {
+		select {
+		case <-deadline:
+			return fmt.Errorf("no %s event for %s on the stream within %ds", evType, wantID, secs)
+		case e, ok := <-t.stream.C:
+			if !ok {
+				return fmt.Errorf("stream closed before a %s event for %s arrived", evType, wantID)
+			}
+			if e.Event != evType {
+				continue
+			}
+			var data map[string]any
+			if err := json.Unmarshal([]byte(e.Data), &data); err != nil {
+				return fmt.Errorf("stream event %s: invalid JSON data: %w", e.ID, err)
+			}
+			if !helpers.MatchID(data["id"], wantID) {
+				continue
+			}
+			t.lastSSE = e
+			return nil
+		}
+	}
+}
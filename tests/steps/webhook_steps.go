@@ -0,0 +1,138 @@
+package steps
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"orders-tests/helpers"
+)
+
+// registeredHook é um webhook criado no cenário; o nome no sink leva um
+// sufixo único para execuções repetidas não se misturarem.
+type registeredHook struct {
+	ID       string
+	Secret   string
+	SinkName string
+}
+
+func (t *TestData) stepRegisterWebhook(name, eventTypes string) error {
+	return t.stepRegisterFailingWebhook(name, eventTypes, 0)
+}
+
+func (t *TestData) stepRegisterFailingWebhook(name, eventTypes string, fail int) error {
+	sinkName := fmt.Sprintf("%s-%d", name, time.Now().UnixNano())
+	req := map[string]any{
+		"url":        t.sink.HookURL(sinkName, fail),
+		"eventTypes": strings.Split(eventTypes, ","),
+	}
+	var resp struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+	if err := t.api.Post("/webhooks", req, &resp); err != nil {
+		return err
+	}
+	if code := t.api.LastResp.StatusCode; code != http.StatusCreated {
+		return fmt.Errorf("register webhook: expected 201, got %d: %s", code, t.api.LastBody)
+	}
+	if resp.ID == "" || resp.Secret == "" {
+		return fmt.Errorf("register webhook: response without id/secret: %s", t.api.LastBody)
+	}
+	t.hooks[name] = registeredHook{ID: resp.ID, Secret: resp.Secret, SinkName: sinkName}
+	t.api.Vars[name+"_id"] = resp.ID // para usar em paths: /webhooks/{name_id}
+	return nil
+}
+
+// stepExpectWebhook espera o sink aceitar (2xx) uma entrega do evento para
+// o pedido e confere a assinatura HMAC com o secret devolvido na criação.
+func (t *TestData) stepExpectWebhook(name, evType, varName string, secs int) error {
+	hook, ok := t.hooks[name]
+	if !ok {
+		return fmt.Errorf("webhook %q not registered in this scenario", name)
+	}
+	wantID := t.api.Vars[varName]
+	deadline := time.Now().Add(time.Duration(secs) * time.Second)
+	for {
+		hits, err := t.sink.Hits(hook.SinkName)
+		if err != nil {
+			return err
+		}
+		for _, h := range hits {
+			if h.Status >= 300 || h.Headers["X-Webhook-Event"] != evType {
+				continue
+			}
+			var evt map[string]any
+			if err := json.Unmarshal([]byte(h.Body), &evt); err != nil {
+				return fmt.Errorf("webhook body is not JSON: %w", err)
+			}
+			if !helpers.MatchID(evt["id"], wantID) {
+				continue
+			}
+			if h.Headers["X-Webhook-Id"] != hook.ID {
+				return fmt.Errorf("X-Webhook-Id: expected %s, got %s", hook.ID, h.Headers["X-Webhook-Id"])
+			}
+			if err := h.VerifySignature(hook.Secret); err != nil {
+				return err
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("webhook %q: no accepted %q delivery for id=%s in %ds (%d requests received)",
+				name, evType, wantID, secs, len(hits))
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func (t *TestData) stepWebhookNotReceived(name, evType string, secs int) error {
+	hook, ok := t.hooks[name]
+	if !ok {
+		return fmt.Errorf("webhook %q not registered in this scenario", name)
+	}
+	time.Sleep(time.Duration(secs) * time.Second)
+	hits, err := t.sink.Hits(hook.SinkName)
+	if err != nil {
+		return err
+	}
+	for _, h := range hits {
+		if h.Headers["X-Webhook-Event"] == evType {
+			return fmt.Errorf("webhook %q received an unexpected %q delivery: %s", name, evType, h.Body)
+		}
+	}
+	return nil
+}
+
+// stepDeliveryLog confere no log de entregas da API a entrega mais recente
+// do tipo: status e número de tentativas.
+func (t *TestData) stepDeliveryLog(name, evType, status string, attempts int) error {
+	hook, ok := t.hooks[name]
+	if !ok {
+		return fmt.Errorf("webhook %q not registered in this scenario", name)
+	}
+	var log []struct {
+		EventType      string `json:"eventType"`
+		Status         string `json:"status"`
+		Attempts       int    `json:"attempts"`
+		LastStatusCode int    `json:"lastStatusCode"`
+	}
+	if err := t.api.Get("/webhooks/"+hook.ID+"/deliveries", &log); err != nil {
+		return err
+	}
+	if code := t.api.LastResp.StatusCode; code != http.StatusOK {
+		return fmt.Errorf("delivery log: expected 200, got %d: %s", code, t.api.LastBody)
+	}
+	for _, d := range log {
+		if d.EventType != evType {
+			continue
+		}
+		if d.Status != status || d.Attempts != attempts {
+			return fmt.Errorf("delivery of %s: expected %s after %d attempts, got %s after %d (last status %d)",
+				evType, status, attempts, d.Status, d.Attempts, d.LastStatusCode)
+		}
+		return nil
+	}
+	return fmt.Errorf("no %s delivery in the log: %s", evType, helpers.PrettyJSON(t.api.LastBody))
+}